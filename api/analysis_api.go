@@ -0,0 +1,122 @@
+package api
+
+import (
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type AnalysisAPI interface {
+	GetArtistAnalysis(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalAnalysisAPI{}
+
+type InternalAnalysisAPI struct {
+	lyricsService     internal.LyricsService
+	dictionaryService internal.DictionaryService
+	cfg               *config.Config
+	logger            *log.Entry
+}
+
+func NewAnalysisAPI(cfg *config.Config, lyricsService internal.LyricsService, dictionaryService internal.DictionaryService, logger *log.Entry) *InternalAnalysisAPI {
+	return &InternalAnalysisAPI{cfg: cfg, lyricsService: lyricsService, dictionaryService: dictionaryService, logger: logger}
+}
+
+func (s *InternalAnalysisAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/artists/:artist_name/analysis", s.GetArtistAnalysis)
+	return nil
+}
+
+type apiSongAnalysis struct {
+	Title      string                     `json:"title"`
+	URL        string                     `json:"url"`
+	Languages  []internal.LanguageScore   `json:"languages,omitempty"`
+	Rhymes     internal.RhymeAnalysis     `json:"rhymes"`
+	Flow       internal.FlowAnalysis      `json:"flow"`
+	Sentiment  internal.SentimentAnalysis `json:"sentiment"`
+	Themes     []internal.ThemeScore      `json:"themes"`
+	Repetition internal.Repetition        `json:"repetition"`
+}
+
+// GetArtistAnalysis returns rhymes, flow, sentiment, themes and repetition of every song and summary of the artist with flow of every performer,
+// `?language=pl`, `?mood=upbeat`, `?themes=money,love` and `?lines=true` (sentiment of every line) are supported
+func (s *InternalAnalysisAPI) GetArtistAnalysis(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		SongsCount int                      `json:"songs_count"`
+		Rhymes     internal.RhymeSummary    `json:"rhymes"`
+		Flow       internal.FlowStats       `json:"flow"`
+		Performers []internal.PerformerFlow `json:"performers"`
+		Sentiment  internal.Sentiment       `json:"sentiment"`
+		Moods      map[string]int           `json:"moods"`
+		Themes     []internal.ThemeScore    `json:"themes"`
+		Repetition internal.Repetition      `json:"repetition"`
+		Songs      []apiSongAnalysis        `json:"songs"`
+	}
+
+	mood := string(ctx.QueryArgs().Peek("mood"))
+	if err := internal.ValidateMood(mood); err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+	withLines := ctx.QueryArgs().GetBool("lines")
+
+	themes, err := internal.GetThemes(s.dictionaryService, queryStrings(ctx, "themes"))
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+
+	artistName := ctx.Value("artist_name").(string)
+	songs, err := s.lyricsService.GetSongsByArtist(artistName)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	songs, ok := deduplicated(ctx, songs)
+	if ok == false {
+		return
+	}
+
+	analysis := internal.AnalyseArtist(internal.FilterSongsByLanguage(songs, string(ctx.QueryArgs().Peek("language"))), themes)
+	resp := responseStruct{
+		SongsCount: len(analysis.Songs),
+		Rhymes:     analysis.Rhymes,
+		Flow:       analysis.Flow,
+		Performers: analysis.Performers,
+		Sentiment:  analysis.Sentiment,
+		Moods:      analysis.Moods,
+		Themes:     analysis.Themes,
+		Repetition: analysis.Repetition,
+		Songs:      []apiSongAnalysis{},
+	}
+	for _, song := range internal.FilterByMood(analysis.Songs, mood) {
+		sentiment := song.Sentiment
+		if withLines == false {
+			sentiment = withoutLines(sentiment)
+		}
+		resp.Songs = append(resp.Songs, apiSongAnalysis{
+			Title:      song.Song.Info.Title,
+			URL:        songURL(s.cfg, song.Song.Info.PageEndpoint),
+			Languages:  song.Song.Languages,
+			Rhymes:     song.Rhymes,
+			Flow:       song.Flow,
+			Sentiment:  sentiment,
+			Themes:     song.Themes,
+			Repetition: song.Repetition,
+		})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+// withoutLines drops sentiment of lines, they are the biggest part of the response and rarely needed
+func withoutLines(analysis internal.SentimentAnalysis) internal.SentimentAnalysis {
+	output := internal.SentimentAnalysis{Total: analysis.Total, Stanzas: []internal.StanzaSentiment{}}
+	for _, stanza := range analysis.Stanzas {
+		stanza.Lines = nil
+		output.Stanzas = append(output.Stanzas, stanza)
+	}
+	return output
+}
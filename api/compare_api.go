@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type CompareAPI interface {
+	CompareArtists(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalCompareAPI{}
+
+type InternalCompareAPI struct {
+	lyricsService     internal.LyricsService
+	dictionaryService internal.DictionaryService
+	stopWords         *internal.StopWordsRegistry
+	cfg               *config.Config
+	logger            *log.Entry
+}
+
+func NewCompareAPI(cfg *config.Config, lyricsService internal.LyricsService, dictionaryService internal.DictionaryService, stopWords *internal.StopWordsRegistry, logger *log.Entry) *InternalCompareAPI {
+	return &InternalCompareAPI{cfg: cfg, lyricsService: lyricsService, dictionaryService: dictionaryService, stopWords: stopWords, logger: logger}
+}
+
+func (s *InternalCompareAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/compare", s.CompareArtists)
+	return nil
+}
+
+const defaultCompareLimit = 20
+
+// CompareArtists compares vocabularies of `artists` (comma separated, at least two of them), `dictionary` adds explicitness
+// of every artist, stop words params are the same as in GetArtistWords
+func (s *InternalCompareAPI) CompareArtists(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		internal.ArtistsComparison
+		StopWordsLanguage string                 `json:"stop_words_language"`
+		Dictionary        *apiDictionaryVersion  `json:"dictionary,omitempty"`
+		Dictionaries      []apiDictionaryVersion `json:"dictionaries,omitempty"`
+	}
+
+	names := queryStrings(ctx, "artists")
+	limit, err := queryInt(ctx, "limit", defaultCompareLimit)
+	if err != nil || len(names) < 2 {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	songDictionary, ok := requestedDictionary(ctx, s.dictionaryService, s.logger)
+	if ok == false {
+		return
+	}
+
+	artists, err := internal.GetSongsOfArtists(s.lyricsService, names)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	artists, ok = artistsDeduplicated(ctx, artists)
+	if ok == false {
+		return
+	}
+	artists = artistsDistinctLines(ctx, artists)
+
+	var allSongs []internal.Song
+	for _, name := range names {
+		artists[name] = internal.FilterSongsByLanguage(artists[name], string(ctx.QueryArgs().Peek("language")))
+		allSongs = append(allSongs, artists[name]...)
+	}
+
+	stopWords, language, err := requestedStopWords(ctx, s.stopWords, internal.LanguagesOf(internal.ArtistLanguages(allSongs)), internal.ArtistVocabulary(allSongs))
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	resp := responseStruct{
+		ArtistsComparison: internal.CompareArtists(artists, names, internal.CompareOptions{
+			StopWords:  stopWords,
+			Limit:      limit,
+			Dictionary: songDictionary,
+		}),
+		StopWordsLanguage: language,
+	}
+	resp.Dictionary, resp.Dictionaries = newApiDictionaryVersions(songDictionary)
+	WriteJSON(ctx, 200, New{Data: resp})
+}
@@ -1,33 +1,332 @@
 package api
 
 import (
+	"bytes"
+	"errors"
 	"github.com/buaazp/fasthttprouter"
 	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type DictionaryAPI interface {
 	GetDictsList(ctx *fasthttp.RequestCtx)
+	GetDict(ctx *fasthttp.RequestCtx)
+	PutDict(ctx *fasthttp.RequestCtx)
 }
 
 var _ API = &InternalDictionaryAPI{}
 
 type InternalDictionaryAPI struct {
-	cfg    *config.Config
-	logger *log.Entry
+	dictionaryService internal.DictionaryService
+	cfg               *config.Config
+	logger            *log.Entry
 }
 
 func (s *InternalDictionaryAPI) Register(r *fasthttprouter.Router) error {
 	r.GET("/dictionaries", s.GetDictsList)
 	r.GET("/dicts", s.GetDictsList)
+	r.GET("/dictionaries/*name", s.GetDict)
+	r.GET("/dicts/*name", s.GetDict)
+	r.PUT("/dictionaries/*name", s.PutDict)
+	r.PUT("/dicts/*name", s.PutDict)
 	return nil
 }
 
-func NewDictionaryAPI(cfg *config.Config, logger *log.Entry) *InternalDictionaryAPI {
-	return &InternalDictionaryAPI{cfg: cfg, logger: logger}
+func NewDictionaryAPI(cfg *config.Config, dictionaryService internal.DictionaryService, logger *log.Entry) *InternalDictionaryAPI {
+	return &InternalDictionaryAPI{cfg: cfg, dictionaryService: dictionaryService, logger: logger}
+}
+
+type apiDictionarySummary struct {
+	Name         string `json:"name"`
+	EntriesCount int    `json:"entries_count"`
 }
 
 func (s *InternalDictionaryAPI) GetDictsList(ctx *fasthttp.RequestCtx) {
-	ctx.WriteString("TODO")
+	type responseStruct struct {
+		Dictionaries []apiDictionarySummary `json:"dictionaries"`
+	}
+	resp := responseStruct{Dictionaries: []apiDictionarySummary{}}
+
+	for _, name := range s.dictionaryService.GetDictionariesNames() {
+		dictionary, err := s.dictionaryService.GetDictionary(name)
+		if err != nil {
+			s.logger.WithError(err).Error("error getting dictionary")
+			WriteError(ctx, ErrorByName("internal_error"))
+			return
+		}
+		resp.Dictionaries = append(resp.Dictionaries, apiDictionarySummary{
+			Name:         name,
+			EntriesCount: len(dictionary.Entries),
+		})
+	}
+
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+var dictionaryContentTypes = map[internal.DictionaryFormat]string{
+	internal.DictionaryText: "text/plain; charset=utf-8",
+	internal.DictionaryCSV:  "text/csv; charset=utf-8",
+	internal.DictionaryJSON: "application/json",
+	internal.DictionaryYAML: "application/yaml",
+}
+
+const (
+	showDictionaryAction      = "show"
+	listVersionsAction        = "versions"
+	showVersionAction         = "version"
+	diffDictionaryAction      = "diff"
+	anonymousDictionaryAuthor = "anonymous"
+)
+
+// parseDictionaryPath splits path like "/en/profanity/versions/3" into dictionary name and action,
+// names of dictionaries contain slashes, so the actions are recognised by the last segments
+func parseDictionaryPath(path string) (name string, action string, version int) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	last := segments[len(segments)-1]
+
+	if len(segments) > 2 && segments[len(segments)-2] == "versions" {
+		if v, err := strconv.Atoi(last); err == nil {
+			return strings.Join(segments[:len(segments)-2], "/"), showVersionAction, v
+		}
+	}
+
+	if len(segments) > 1 {
+		switch last {
+		case "versions":
+			return strings.Join(segments[:len(segments)-1], "/"), listVersionsAction, 0
+		case "diff":
+			return strings.Join(segments[:len(segments)-1], "/"), diffDictionaryAction, 0
+		}
+	}
+	return strings.Join(segments, "/"), showDictionaryAction, 0
+}
+
+// GetDict handles all of the GET endpoints of single dictionary:
+// /dictionaries/:name, /dictionaries/:name/versions, /dictionaries/:name/versions/:version and /dictionaries/:name/diff
+func (s *InternalDictionaryAPI) GetDict(ctx *fasthttp.RequestCtx) {
+	name, action, version := parseDictionaryPath(ctx.UserValue("name").(string))
+
+	switch action {
+	case listVersionsAction:
+		s.getDictVersions(ctx, name)
+	case showVersionAction:
+		s.getDictVersion(ctx, name, version)
+	case diffDictionaryAction:
+		s.getDictDiff(ctx, name)
+	default:
+		s.getDict(ctx, name)
+	}
+}
+
+// getDict shows dictionary with expanded composition, `?format=` returns only expanded entries in one of dictionary formats
+// and `?version=` shows one of the previous versions
+func (s *InternalDictionaryAPI) getDict(ctx *fasthttp.RequestCtx, name string) {
+	type responseStruct struct {
+		Version    int                 `json:"version"`
+		Definition internal.Dictionary `json:"definition"`
+		Dictionary internal.Dictionary `json:"dictionary"`
+	}
+
+	version, err := queryInt(ctx, "version", 0)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	dictionaryVersion, err := s.dictionaryService.GetDictionaryVersion(name, version)
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+
+	if ctx.QueryArgs().Has("format") {
+		format, err := internal.ParseDictionaryFormat(string(ctx.QueryArgs().Peek("format")))
+		if err != nil {
+			WriteError(ctx, ErrorByName("invalid_parameter"))
+			return
+		}
+
+		ctx.Response.Header.Set("Content-Type", dictionaryContentTypes[format])
+		if err := internal.WriteDictionary(ctx, dictionaryVersion.Dictionary, format); err != nil {
+			s.logger.WithError(err).Error("error writing dictionary")
+			WriteError(ctx, ErrorByName("internal_error"))
+		}
+		return
+	}
+
+	WriteJSON(ctx, 200, New{Data: responseStruct{
+		Version:    dictionaryVersion.Version,
+		Definition: dictionaryVersion.Definition,
+		Dictionary: dictionaryVersion.Dictionary,
+	}})
+}
+
+type apiDictionaryVersionSummary struct {
+	Version      int       `json:"version"`
+	Author       string    `json:"author"`
+	Cause        string    `json:"cause,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	EntriesCount int       `json:"entries_count"`
+	Added        int       `json:"added"`
+	Removed      int       `json:"removed"`
+	Changed      int       `json:"changed"`
+}
+
+func newApiDictionaryVersionSummary(version internal.DictionaryVersion) apiDictionaryVersionSummary {
+	return apiDictionaryVersionSummary{
+		Version:      version.Version,
+		Author:       version.Author,
+		Cause:        version.Cause,
+		CreatedAt:    version.CreatedAt,
+		EntriesCount: len(version.Dictionary.Entries),
+		Added:        len(version.Diff.Added),
+		Removed:      len(version.Diff.Removed),
+		Changed:      len(version.Diff.Changed),
+	}
+}
+
+func (s *InternalDictionaryAPI) getDictVersions(ctx *fasthttp.RequestCtx, name string) {
+	type responseStruct struct {
+		Name     string                        `json:"name"`
+		Versions []apiDictionaryVersionSummary `json:"versions"`
+	}
+
+	versions, err := s.dictionaryService.GetDictionaryVersions(name)
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+
+	resp := responseStruct{Name: name}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, newApiDictionaryVersionSummary(version))
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+func (s *InternalDictionaryAPI) getDictVersion(ctx *fasthttp.RequestCtx, name string, version int) {
+	dictionaryVersion, err := s.dictionaryService.GetDictionaryVersion(name, version)
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+	WriteJSON(ctx, 200, New{Data: dictionaryVersion})
+}
+
+// getDictDiff compares `?from=` and `?to=` versions, by default the latest version is compared with the previous one
+func (s *InternalDictionaryAPI) getDictDiff(ctx *fasthttp.RequestCtx, name string) {
+	type responseStruct struct {
+		Name string                  `json:"name"`
+		From int                     `json:"from"`
+		To   int                     `json:"to"`
+		Diff internal.DictionaryDiff `json:"diff"`
+	}
+
+	to, err := queryInt(ctx, "to", 0)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	toVersion, err := s.dictionaryService.GetDictionaryVersion(name, to)
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+
+	from, err := queryInt(ctx, "from", toVersion.Version-1)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+	if from < 1 {
+		WriteJSON(ctx, 200, New{Data: responseStruct{Name: name, From: 0, To: toVersion.Version, Diff: toVersion.Diff}})
+		return
+	}
+
+	diff, err := s.dictionaryService.DiffDictionaryVersions(name, from, toVersion.Version)
+	if err != nil {
+		writeDictionaryError(ctx, s.logger, err)
+		return
+	}
+	WriteJSON(ctx, 200, New{Data: responseStruct{Name: name, From: from, To: toVersion.Version, Diff: diff}})
+}
+
+var dictionaryFormatsByContentType = map[string]internal.DictionaryFormat{
+	"text/plain":         internal.DictionaryText,
+	"text/csv":           internal.DictionaryCSV,
+	"application/json":   internal.DictionaryJSON,
+	"application/yaml":   internal.DictionaryYAML,
+	"application/x-yaml": internal.DictionaryYAML,
+	"text/yaml":          internal.DictionaryYAML,
+}
+
+// PutDict registers new dictionary or creates new version of existing one, the body may be written in any dictionary
+// format picked by `?format=` or Content-Type, the author is taken from X-Author header
+func (s *InternalDictionaryAPI) PutDict(ctx *fasthttp.RequestCtx) {
+	name, action, _ := parseDictionaryPath(ctx.UserValue("name").(string))
+	if action != showDictionaryAction {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	format := internal.DictionaryJSON
+	contentType := strings.TrimSpace(strings.Split(string(ctx.Request.Header.ContentType()), ";")[0])
+	if v, ok := dictionaryFormatsByContentType[contentType]; ok {
+		format = v
+	}
+	if ctx.QueryArgs().Has("format") {
+		v, err := internal.ParseDictionaryFormat(string(ctx.QueryArgs().Peek("format")))
+		if err != nil {
+			WriteError(ctx, ErrorByName("invalid_parameter"))
+			return
+		}
+		format = v
+	}
+
+	definition, err := internal.ReadDictionary(bytes.NewReader(ctx.PostBody()), format)
+	if err != nil {
+		s.logger.WithError(err).Debug("invalid dictionary payload")
+		WriteError(ctx, ErrorByName("invalid_payload"))
+		return
+	}
+	definition.Name = name
+
+	author := string(ctx.Request.Header.Peek("X-Author"))
+	if author == "" {
+		author = anonymousDictionaryAuthor
+	}
+
+	version, err := s.dictionaryService.SaveDictionary(definition, author)
+	if err != nil {
+		if internal.IsDictionaryValidationError(err) {
+			s.logger.WithError(err).Debug("invalid dictionary")
+			WriteError(ctx, ErrorByName("invalid_payload"))
+			return
+		}
+		s.logger.WithError(err).Error("error saving dictionary")
+		WriteError(ctx, ErrorByName("internal_error"))
+		return
+	}
+
+	WriteJSON(ctx, 200, New{Data: newApiDictionaryVersionSummary(version)})
+}
+
+func writeDictionaryError(ctx *fasthttp.RequestCtx, logger *log.Entry, err error) {
+	if errors.Is(err, internal.DictionaryNotFoundError) {
+		WriteError(ctx, ErrorByName("dictionary_not_found"))
+		return
+	}
+	if errors.Is(err, internal.DictionaryVersionNotFoundError) {
+		WriteError(ctx, ErrorByName("dictionary_version_not_found"))
+		return
+	}
+
+	logger.WithError(err).Error("error getting dictionary")
+	WriteError(ctx, ErrorByName("internal_error"))
 }
@@ -9,6 +9,16 @@ var errorsList = []*ErrorResponse{
 	{"unknown_error", 500},
 	{"internal_error", 500},
 	{"invalid_payload", 422},
+	{"invalid_parameter", 400},
+	{"invalid_query", 400},
+	{"dictionary_not_found", 404},
+	{"dictionary_version_not_found", 404},
+	{"artist_not_found", 404},
+	{"database_disabled", 501},
+	{"genius_offline", 501},
+	{"job_not_found", 404},
+	{"job_not_done", 409},
+	{"job_finished", 409},
 }
 
 func ErrorByName(name string) ErrorResponse {
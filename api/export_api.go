@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type ExportAPI interface {
+	ExportArtist(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalExportAPI{}
+
+type InternalExportAPI struct {
+	exportService internal.ExportService
+	cfg           *config.Config
+	logger        *log.Entry
+}
+
+func NewExportAPI(cfg *config.Config, exportService internal.ExportService, logger *log.Entry) *InternalExportAPI {
+	return &InternalExportAPI{cfg: cfg, exportService: exportService, logger: logger}
+}
+
+func (s *InternalExportAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/artists/:artist_name/export", s.ExportArtist)
+	return nil
+}
+
+// ExportArtist returns zip archive with songs and manifest.json, `?format=csv`, `?words=true` and `?duplicates=dedupe` are supported
+func (s *InternalExportAPI) ExportArtist(ctx *fasthttp.RequestCtx) {
+	format, err := internal.ParseExportFormat(string(ctx.QueryArgs().Peek("format")))
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+	mode, ok := requestedDuplicates(ctx)
+	if ok == false {
+		return
+	}
+	options := internal.ExportOptions{Format: format, WordCounts: ctx.QueryArgs().GetBool("words"), Deduplicate: mode != ""}
+
+	// the archive is built up front, so errors can still be returned as JSON
+	var archive bytes.Buffer
+	target := internal.NewZipExportTarget(&archive)
+	manifest, err := s.exportService.ExportArtist(ctx.Value("artist_name").(string), options, target)
+	if err == nil {
+		err = target.Close()
+	}
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error exporting artist")
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", "application/zip")
+	ctx.Response.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.zip", manifest.Artist, format)))
+	ctx.SetBody(archive.Bytes())
+}
@@ -1,8 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
-	"fmt"
+	"errors"
 	"github.com/buaazp/fasthttprouter"
 	"github.com/marosiak/WordFinder/config"
 	"github.com/marosiak/WordFinder/internal"
@@ -19,13 +20,15 @@ type GeniusAPI interface {
 var _ API = &InternalGeniusAPI{}
 
 type InternalGeniusAPI struct {
-	lyricsService internal.LyricsService
-	cfg           *config.Config
-	logger        *log.Entry
+	lyricsService     internal.LyricsService
+	dictionaryService internal.DictionaryService
+	stopWords         *internal.StopWordsRegistry
+	cfg               *config.Config
+	logger            *log.Entry
 }
 
-func NewGeniusAPI(cfg *config.Config, lyricsService internal.LyricsService, logger *log.Entry) *InternalGeniusAPI {
-	return &InternalGeniusAPI{cfg: cfg, lyricsService: lyricsService, logger: logger}
+func NewGeniusAPI(cfg *config.Config, lyricsService internal.LyricsService, dictionaryService internal.DictionaryService, stopWords *internal.StopWordsRegistry, logger *log.Entry) *InternalGeniusAPI {
+	return &InternalGeniusAPI{cfg: cfg, lyricsService: lyricsService, dictionaryService: dictionaryService, stopWords: stopWords, logger: logger}
 }
 
 func (s *InternalGeniusAPI) Register(r *fasthttprouter.Router) error {
@@ -38,45 +41,203 @@ type apiSong struct {
 	Title      string                    `json:"title"`
 	URL        string                    `json:"url"`
 	WordsCount internal.WordsOccurrences `json:"words_count,omitempty"`
+	Languages  []internal.LanguageScore  `json:"languages,omitempty"`
+	// StopWordsLanguage is set only when stop words have been excluded from WordsCount
+	StopWordsLanguage string `json:"stop_words_language,omitempty"`
+	// Variant, Similarity and Duplicates are set only with `?duplicates=group`
+	Variant    string    `json:"variant,omitempty"`
+	Similarity float64   `json:"similarity,omitempty"`
+	Duplicates []apiSong `json:"duplicates,omitempty"`
 }
 
+// apiDictionaryVersion tells which version of dictionary has been used to filter songs, so the result can be reproduced
+type apiDictionaryVersion struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// requestedDictionary reads `?dictionary=en/profanity&dictionary_version=3`, the latest version is used when it's not pinned,
+// `?dictionary=profanity` uses "<language>/profanity" dictionaries matching languages of songs
+func requestedDictionary(ctx *fasthttp.RequestCtx, dictionaryService internal.DictionaryService, logger *log.Entry) (*internal.SongDictionary, bool) {
+	name := string(ctx.QueryArgs().Peek("dictionary"))
+	if name == "" {
+		return nil, true
+	}
+
+	version, err := queryInt(ctx, "dictionary_version", 0)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return nil, false
+	}
+
+	songDictionary, err := internal.GetSongDictionary(dictionaryService, name, version)
+	if err != nil {
+		writeDictionaryError(ctx, logger, err)
+		return nil, false
+	}
+	return &songDictionary, true
+}
+
+// newApiDictionaryVersions returns the single dictionary or all of the language dictionaries which have been used
+func newApiDictionaryVersions(songDictionary *internal.SongDictionary) (*apiDictionaryVersion, []apiDictionaryVersion) {
+	if songDictionary == nil {
+		return nil, nil
+	}
+
+	var versions []apiDictionaryVersion
+	for _, version := range songDictionary.Versions {
+		versions = append(versions, apiDictionaryVersion{Name: version.Name, Version: version.Version})
+	}
+	if songDictionary.ByLanguage == nil {
+		return &versions[0], nil
+	}
+	return nil, versions
+}
+
+// requestedFilter joins `banned_words` and `dictionary` into `NOT (@banned_words OR @dictionary)` and adds the `?q=` query
+// to it, nil is returned when songs don't have to be filtered
+func requestedFilter(ctx *fasthttp.RequestCtx, dictionaryService internal.DictionaryService, songDictionary *internal.SongDictionary, logger *log.Entry) (*internal.Query, bool) {
+	var dictionaries []internal.SongDictionary
+	if bannedWords := QueryStringList(ctx, "banned_words"); bannedWords.IsEmpty() == false {
+		var words []string
+		for _, word := range bannedWords {
+			words = append(words, string(word))
+		}
+		dictionaries = append(dictionaries, internal.NewSongDictionary(internal.NewDictionaryFromWords("banned_words", words)))
+	}
+	if songDictionary != nil {
+		dictionaries = append(dictionaries, *songDictionary)
+	}
+
+	query, ok := requestedQuery(ctx, dictionaryService, logger)
+	if ok == false {
+		return nil, false
+	}
+	return internal.AllOf(internal.WithoutDictionaries(dictionaries...), query), true
+}
+
+// requestedQuery parses `?q=`, see internal.ParseQuery for the syntax, wrong query is explained in `message`
+func requestedQuery(ctx *fasthttp.RequestCtx, dictionaryService internal.DictionaryService, logger *log.Entry) (*internal.Query, bool) {
+	text := string(ctx.QueryArgs().Peek("q"))
+	if strings.TrimSpace(text) == "" {
+		return nil, true
+	}
+
+	query, err := internal.ParseQuery(text, dictionaryService)
+	var queryError *internal.QueryError
+	switch {
+	case err == nil:
+		return query, true
+	case errors.As(err, &queryError) && queryError.Err != nil:
+		writeDictionaryError(ctx, logger, queryError.Err)
+	case errors.As(err, &queryError):
+		WriteErrorMessage(ctx, ErrorByName("invalid_query"), queryError.Error())
+	default:
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+	}
+	return nil, false
+}
+
+// queryDictionaryVersions adds dictionaries from `?q=` to the ones of `?dictionary=`
+func queryDictionaryVersions(versions []apiDictionaryVersion, query *internal.Query) []apiDictionaryVersion {
+	if query == nil {
+		return versions
+	}
+	for _, version := range query.DictionaryVersions() {
+		versions = append(versions, apiDictionaryVersion{Name: version.Name, Version: version.Version})
+	}
+	return versions
+}
+
+func (s *InternalGeniusAPI) newApiSong(song internal.Song) apiSong {
+	return apiSong{
+		Title:     song.Info.Title,
+		URL:       songURL(s.cfg, song.Info.PageEndpoint),
+		Languages: song.Languages,
+	}
+}
+
+func (s *InternalGeniusAPI) newApiSongGroup(group internal.SongGroup) apiSong {
+	output := s.newApiSong(group.Song)
+	output.Variant = group.Variant
+	for _, duplicate := range group.Duplicates {
+		apiDuplicate := s.newApiSong(duplicate.Song)
+		apiDuplicate.Variant = duplicate.Variant
+		apiDuplicate.Similarity = duplicate.Similarity
+		output.Duplicates = append(output.Duplicates, apiDuplicate)
+	}
+	return output
+}
+
+// GetSongsByArtist lists songs of the artist, lyrics are downloaded only when songs have to be filtered
+// by `q`, `banned_words`, `dictionary` or `language`, or when `duplicates` have to be found
 func (s *InternalGeniusAPI) GetSongsByArtist(ctx *fasthttp.RequestCtx) {
 	type responseStruct struct {
-		Songs []apiSong `json:"songs"`
+		Songs        []apiSong              `json:"songs"`
+		Dictionary   *apiDictionaryVersion  `json:"dictionary,omitempty"`
+		Dictionaries []apiDictionaryVersion `json:"dictionaries,omitempty"`
 	}
 	resp := responseStruct{}
 
 	artistName := ctx.Value("artist_name").(string)
-	bannedWords := QueryStringList(ctx, "banned_words")
+	language := string(ctx.QueryArgs().Peek("language"))
+	songDictionary, ok := requestedDictionary(ctx, s.dictionaryService, s.logger)
+	if ok == false {
+		return
+	}
+	filter, ok := requestedFilter(ctx, s.dictionaryService, songDictionary, s.logger)
+	if ok == false {
+		return
+	}
+	resp.Dictionary, resp.Dictionaries = newApiDictionaryVersions(songDictionary)
+	resp.Dictionaries = queryDictionaryVersions(resp.Dictionaries, filter)
+	duplicates, ok := requestedDuplicates(ctx)
+	if ok == false {
+		return
+	}
+	request := songsRequest{
+		artistName:    artistName,
+		language:      language,
+		filter:        filter,
+		duplicates:    duplicates,
+		distinctLines: ctx.QueryArgs().GetBool("distinct_lines"),
+		dictionary:    resp.Dictionary,
+		dictionaries:  resp.Dictionaries,
+	}
+	if format := requestedStream(ctx); format != "" {
+		WriteStream(ctx, format, func(streamCtx context.Context, stream *StreamWriter) {
+			s.streamSongs(streamCtx, stream, request)
+		})
+		return
+	}
 
-	if bannedWords.IsEmpty() {
+	if filter == nil && language == "" && duplicates == "" {
 		songs, err := s.lyricsService.GetSongsInfosByArtist(artistName)
 		if err != nil {
-			s.logger.WithError(err).Error("error getting songs infos by artist")
-			WriteError(ctx, ErrorByName("internal_error"))
+			writeLyricsError(ctx, s.logger, err, "error getting songs infos by artist")
 			return
 		}
 
 		for _, song := range songs {
 			resp.Songs = append(resp.Songs, apiSong{
 				Title: song.Title,
-				URL:   fmt.Sprintf("https://%s%s", s.cfg.GeniusHost, song.PageEndpoint),
+				URL:   songURL(s.cfg, song.PageEndpoint),
 			})
 		}
 	} else {
 		songs, err := s.lyricsService.GetSongsByArtist(artistName)
 		if err != nil {
-			s.logger.WithError(err).Error("error getting songs by artist")
-			WriteError(ctx, ErrorByName("internal_error"))
+			writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
 			return
 		}
 
-		for _, song := range songs {
-			if song.Lyrics.FindWords().ContainsOneOfWords(bannedWords.Normalise()) == false {
-				resp.Songs = append(resp.Songs, apiSong{
-					Title: song.Info.Title,
-					URL:   fmt.Sprintf("https://%s%s", s.cfg.GeniusHost, song.Info.PageEndpoint),
-				})
+		if duplicates == internal.DuplicatesGroup {
+			for _, group := range request.prepareGroups(songs) {
+				resp.Songs = append(resp.Songs, s.newApiSongGroup(group))
+			}
+		} else {
+			for _, song := range request.prepare(songs) {
+				resp.Songs = append(resp.Songs, s.newApiSong(song))
 			}
 		}
 	}
@@ -88,16 +249,6 @@ func (s *InternalGeniusAPI) GetSongsByArtist(ctx *fasthttp.RequestCtx) {
 
 type BannedWords []internal.Word
 
-func (b BannedWords) Normalise() BannedWords {
-	var outputWords BannedWords
-	for _, bannedWord := range b {
-		for _, bannedChar := range "ĄĆĘŁŃÓŚŹŻ" {
-			outputWords = append(outputWords, internal.Word(strings.ReplaceAll(string(bannedWord), string(bannedChar), "")))
-			outputWords = append(outputWords, internal.Word(strings.ReplaceAll(string(bannedWord), strings.ToLower(string(bannedChar)), "")))
-		}
-	}
-	return outputWords
-}
 func (b BannedWords) IsEmpty() bool {
 	return len(b) == 0
 }
@@ -114,6 +265,9 @@ func QueryStringList(ctx *fasthttp.RequestCtx, name string) BannedWords {
 	by, _ := base64.StdEncoding.DecodeString(string(ctx.QueryArgs().Peek(name)))
 	var bannedWords BannedWords
 	for _, word := range strings.Split(string(by), ",") {
+		if word == "" {
+			continue
+		}
 		bannedWords = append(bannedWords, internal.Word(word))
 	}
 	return bannedWords
@@ -121,28 +275,234 @@ func QueryStringList(ctx *fasthttp.RequestCtx, name string) BannedWords {
 
 func (s *InternalGeniusAPI) GetSongsWithWordsByArtist(ctx *fasthttp.RequestCtx) {
 	type responseStruct struct {
-		Songs []apiSong
+		Songs        []apiSong
+		Dictionary   *apiDictionaryVersion  `json:"dictionary,omitempty"`
+		Dictionaries []apiDictionaryVersion `json:"dictionaries,omitempty"`
 	}
 
 	artistName := ctx.Value("artist_name").(string)
-	bannedWords := QueryStringList(ctx, "banned_words")
+	songDictionary, ok := requestedDictionary(ctx, s.dictionaryService, s.logger)
+	if ok == false {
+		return
+	}
+	filter, ok := requestedFilter(ctx, s.dictionaryService, songDictionary, s.logger)
+	if ok == false {
+		return
+	}
+
+	resp := responseStruct{}
+	resp.Dictionary, resp.Dictionaries = newApiDictionaryVersions(songDictionary)
+	resp.Dictionaries = queryDictionaryVersions(resp.Dictionaries, filter)
+
+	duplicates, ok := requestedDuplicates(ctx)
+	if ok == false {
+		return
+	}
+	request := songsRequest{
+		artistName:    artistName,
+		language:      string(ctx.QueryArgs().Peek("language")),
+		filter:        filter,
+		duplicates:    duplicates,
+		distinctLines: ctx.QueryArgs().GetBool("distinct_lines"),
+		dictionary:    resp.Dictionary,
+		dictionaries:  resp.Dictionaries,
+		words:         true,
+		stopWords:     requestedStopWordsRequest(ctx),
+	}
+	if format := requestedStream(ctx); format != "" {
+		// unknown language of stop words is told by the status code, before the stream starts
+		if _, _, err := request.stopWords.resolve(s.stopWords, nil, nil); err != nil {
+			WriteError(ctx, ErrorByName("invalid_parameter"))
+			return
+		}
+		WriteStream(ctx, format, func(streamCtx context.Context, stream *StreamWriter) {
+			s.streamSongs(streamCtx, stream, request)
+		})
+		return
+	}
 
 	songs, err := s.lyricsService.GetSongsByArtist(artistName)
 	if err != nil {
-		s.logger.WithError(err).Error("error getting songs infos by artist")
-		WriteError(ctx, ErrorByName("internal_error"))
+		writeLyricsError(ctx, s.logger, err, "error getting songs infos by artist")
 		return
 	}
-
-	resp := responseStruct{}
-	for _, song := range songs {
-		if song.Lyrics.FindWords().ContainsOneOfWords(bannedWords.Normalise()) == false {
-			resp.Songs = append(resp.Songs, apiSong{
-				Title:      song.Info.Title,
-				URL:        fmt.Sprintf("https://%s%s", s.cfg.GeniusHost, song.Info.PageEndpoint),
-				WordsCount: song.Lyrics.FindWords(),
-			})
+	for _, song := range request.prepare(songs) {
+		apiSong, err := s.newApiSongWithWords(song, request.stopWords)
+		if err != nil {
+			WriteError(ctx, ErrorByName("invalid_parameter"))
+			return
 		}
+		resp.Songs = append(resp.Songs, apiSong)
 	}
 	WriteJSON(ctx, 200, New{Data: resp})
 }
+
+// newApiSongWithWords counts words of the song, stop words are picked for each song separately,
+// so mixed catalogues work with "auto"
+func (s *InternalGeniusAPI) newApiSongWithWords(song internal.Song, request stopWordsRequest) (apiSong, error) {
+	words := song.Lyrics.FindWords()
+	stopWords, language, err := request.resolve(s.stopWords, internal.LanguagesOf(song.Languages), words)
+	if err != nil {
+		return apiSong{}, err
+	}
+	if language == internal.StopWordsNone {
+		language = ""
+	}
+
+	output := s.newApiSong(song)
+	output.WordsCount = words.WithoutStopWords(stopWords)
+	output.StopWordsLanguage = language
+	return output, nil
+}
+
+// songsRequest is everything the songs of the artist are prepared by, streamSongs gets it because the request
+// can't be used once the stream has started
+type songsRequest struct {
+	artistName    string
+	language      string
+	filter        *internal.Query
+	duplicates    string
+	distinctLines bool
+	dictionary    *apiDictionaryVersion
+	dictionaries  []apiDictionaryVersion
+	// words counts words of songs like GetSongsWithWordsByArtist, stopWords are used only then
+	words     bool
+	stopWords stopWordsRequest
+}
+
+// prepare drops duplicates among all of the songs before distinct_lines and the filter, in the same order as analysis
+// jobs. Words have nothing to group, so `group` deduplicates them too
+func (r songsRequest) prepare(songs []internal.Song) []internal.Song {
+	if r.duplicates == internal.DuplicatesDedupe || (r.duplicates != "" && r.words) {
+		songs = internal.Deduplicate(songs)
+	}
+	return r.filterSongs(songs)
+}
+
+// prepareGroups groups duplicates among all of the songs, the group is kept when its original song passes
+// distinct_lines and the filter
+func (r songsRequest) prepareGroups(songs []internal.Song) []internal.SongGroup {
+	var groups []internal.SongGroup
+	for _, group := range internal.GroupDuplicates(songs) {
+		filtered := r.filterSongs([]internal.Song{group.Song})
+		if len(filtered) == 0 {
+			continue
+		}
+		group.Song = filtered[0]
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// filterSongs applies distinct_lines and the filter
+func (r songsRequest) filterSongs(songs []internal.Song) []internal.Song {
+	if r.distinctLines {
+		songs = internal.WithDistinctLines(songs)
+	}
+	return internal.FilterSongsByQuery(internal.FilterSongsByLanguage(songs, r.language), r.filter)
+}
+
+// streamSummary is the last event of the stream, Songs is the number of streamed songs
+type streamSummary struct {
+	Songs        int                     `json:"songs"`
+	Progress     internal.ScrapeProgress `json:"progress"`
+	Dictionary   *apiDictionaryVersion   `json:"dictionary,omitempty"`
+	Dictionaries []apiDictionaryVersion  `json:"dictionaries,omitempty"`
+}
+
+// streamSongs sends every song as soon as its lyrics have been downloaded and analysed, so songs come in the order
+// of downloading, `progress` follows every downloaded page. Duplicates can be found only among all of the songs,
+// so with `duplicates` there are no incremental `song` events, all of the songs are sent after the last `progress`.
+// Songs go through the same steps in the same order as in the plain responses
+func (s *InternalGeniusAPI) streamSongs(ctx context.Context, stream *StreamWriter, request songsRequest) {
+	summary := streamSummary{Dictionary: request.dictionary, Dictionaries: request.dictionaries}
+
+	infos, err := s.lyricsService.GetSongsInfosByArtist(request.artistName)
+	if err != nil {
+		stream.Error(lyricsError(err, s.logger, "error getting songs infos by artist"), "")
+		return
+	}
+	summary.Progress.SongsListed = len(infos)
+	if stream.Event("progress", summary.Progress) == false {
+		return
+	}
+
+	if request.words == false && request.filter == nil && request.language == "" && request.duplicates == "" {
+		for _, info := range infos {
+			if stream.Event("song", apiSong{Title: info.Title, URL: songURL(s.cfg, info.PageEndpoint)}) == false {
+				return
+			}
+			summary.Songs++
+		}
+		stream.Event("summary", summary)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// songs are collected by their index, so duplicates are found in the order of listing like in the plain response
+	collected := make([]*internal.Song, len(infos))
+	internal.ScrapeSongs(ctx, s.lyricsService, infos, s.cfg.StreamConcurrentSongs, func(index int, song internal.Song, err error) {
+		summary.Progress.Add(infos[index], err)
+		if err != nil || ctx.Err() != nil {
+			stream.Event("progress", summary.Progress)
+			return
+		}
+
+		if request.duplicates != "" {
+			collected[index] = &song
+			stream.Event("progress", summary.Progress)
+			return
+		}
+
+		for _, filtered := range request.filterSongs([]internal.Song{song}) {
+			if s.streamSong(stream, request, filtered) == false {
+				cancel()
+				return
+			}
+			summary.Songs++
+		}
+		stream.Event("progress", summary.Progress)
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	var songs []internal.Song
+	for _, song := range collected {
+		if song != nil {
+			songs = append(songs, *song)
+		}
+	}
+	switch {
+	case request.duplicates == internal.DuplicatesGroup && request.words == false:
+		for _, group := range request.prepareGroups(songs) {
+			if stream.Event("song", s.newApiSongGroup(group)) == false {
+				return
+			}
+			summary.Songs++
+		}
+	case request.duplicates != "":
+		for _, song := range request.prepare(songs) {
+			if s.streamSong(stream, request, song) == false {
+				return
+			}
+			summary.Songs++
+		}
+	}
+	stream.Event("summary", summary)
+}
+
+// streamSong sends the song, false is returned when the stream has to be stopped
+func (s *InternalGeniusAPI) streamSong(stream *StreamWriter, request songsRequest, song internal.Song) bool {
+	if request.words == false {
+		return stream.Event("song", s.newApiSong(song))
+	}
+
+	output, err := s.newApiSongWithWords(song, request.stopWords)
+	if err != nil {
+		stream.Error(ErrorByName("invalid_parameter"), err.Error())
+		return false
+	}
+	return stream.Event("song", output)
+}
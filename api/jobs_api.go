@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type JobsAPI interface {
+	CreateJob(ctx *fasthttp.RequestCtx)
+	GetJob(ctx *fasthttp.RequestCtx)
+	GetJobResult(ctx *fasthttp.RequestCtx)
+	CancelJob(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalJobsAPI{}
+
+type InternalJobsAPI struct {
+	jobService internal.JobService
+	cfg        *config.Config
+	logger     *log.Entry
+}
+
+func NewJobsAPI(cfg *config.Config, jobService internal.JobService, logger *log.Entry) *InternalJobsAPI {
+	return &InternalJobsAPI{cfg: cfg, jobService: jobService, logger: logger}
+}
+
+func (s *InternalJobsAPI) Register(r *fasthttprouter.Router) error {
+	r.POST("/jobs", s.CreateJob)
+	r.GET("/jobs/:id", s.GetJob)
+	r.GET("/jobs/:id/result", s.GetJobResult)
+	r.POST("/jobs/:id/cancel", s.CancelJob)
+	return nil
+}
+
+type apiJobSong struct {
+	internal.JobSong
+	URL string `json:"url"`
+}
+
+// CreateJob queues analysis described by JSON spec, ex. `{"kind": "words", "artist": "eminem", "q": "NOT @profanity"}`,
+// the job is returned with 202 and its ID
+func (s *InternalJobsAPI) CreateJob(ctx *fasthttp.RequestCtx) {
+	var spec internal.JobSpec
+	if err := json.Unmarshal(ctx.PostBody(), &spec); err != nil {
+		WriteErrorMessage(ctx, ErrorByName("invalid_payload"), err.Error())
+		return
+	}
+
+	job, err := s.jobService.CreateJob(spec)
+	var queryError *internal.QueryError
+	switch {
+	case err == nil:
+		WriteJSON(ctx, 202, New{Data: job})
+	case errors.Is(err, internal.UnknownJobKindError) || errors.Is(err, internal.InvalidJobSpecError):
+		WriteErrorMessage(ctx, ErrorByName("invalid_payload"), err.Error())
+	case errors.As(err, &queryError) && queryError.Err == nil:
+		WriteErrorMessage(ctx, ErrorByName("invalid_query"), queryError.Error())
+	case errors.Is(err, internal.DictionaryNotFoundError) || errors.Is(err, internal.DictionaryVersionNotFoundError):
+		writeDictionaryError(ctx, s.logger, err)
+	default:
+		s.logger.WithError(err).Error("error creating job")
+		WriteError(ctx, ErrorByName("internal_error"))
+	}
+}
+
+// GetJob shows status and progress of the job
+func (s *InternalJobsAPI) GetJob(ctx *fasthttp.RequestCtx) {
+	job, err := s.jobService.GetJob(ctx.Value("id").(string))
+	if err != nil {
+		s.writeJobError(ctx, err)
+		return
+	}
+	WriteJSON(ctx, 200, New{Data: job})
+}
+
+// GetJobResult returns the result of done job, 409 is returned until it's done
+func (s *InternalJobsAPI) GetJobResult(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		Songs        []apiJobSong                    `json:"songs"`
+		Analysis     *internal.JobArtistAnalysis     `json:"analysis,omitempty"`
+		Dictionaries []internal.DictionaryVersionRef `json:"dictionaries,omitempty"`
+	}
+
+	result, err := s.jobService.GetJobResult(ctx.Value("id").(string))
+	if err != nil {
+		s.writeJobError(ctx, err)
+		return
+	}
+
+	resp := responseStruct{Songs: []apiJobSong{}, Analysis: result.Analysis, Dictionaries: result.Dictionaries}
+	for _, song := range result.Songs {
+		resp.Songs = append(resp.Songs, apiJobSong{JobSong: song, URL: songURL(s.cfg, song.PagePath)})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+// CancelJob stops queued or running job, songs which are being downloaded are finished first
+func (s *InternalJobsAPI) CancelJob(ctx *fasthttp.RequestCtx) {
+	job, err := s.jobService.CancelJob(ctx.Value("id").(string))
+	if err != nil {
+		s.writeJobError(ctx, err)
+		return
+	}
+	WriteJSON(ctx, 200, New{Data: job})
+}
+
+func (s *InternalJobsAPI) writeJobError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, internal.JobNotFoundError):
+		WriteError(ctx, ErrorByName("job_not_found"))
+	case errors.Is(err, internal.JobNotDoneError):
+		WriteErrorMessage(ctx, ErrorByName("job_not_done"), err.Error())
+	case errors.Is(err, internal.JobFinishedError):
+		WriteError(ctx, ErrorByName("job_finished"))
+	default:
+		s.logger.WithError(err).Error("error getting job")
+		WriteError(ctx, ErrorByName("internal_error"))
+	}
+}
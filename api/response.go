@@ -2,14 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	_ "github.com/fasthttp/router"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
+	"strings"
 )
 
 type New struct {
 	Data  interface{} `json:"data"`
 	Error *string     `json:"error"`
+	// Message explains the error when the name isn't enough, ex. where the query is wrong
+	Message string `json:"message,omitempty"`
 }
 
 func WriteError(ctx *fasthttp.RequestCtx, error ErrorResponse) {
@@ -27,6 +34,21 @@ func WriteError(ctx *fasthttp.RequestCtx, error ErrorResponse) {
 	}
 }
 
+func WriteErrorMessage(ctx *fasthttp.RequestCtx, error ErrorResponse, message string) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	ctx.Response.SetStatusCode(error.StatusCode)
+
+	by, err := json.Marshal(New{Error: &error.Name, Message: message})
+	if err != nil {
+		log.Error(err)
+	}
+
+	_, err = ctx.Write(by)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
 func WriteJSON(ctx *fasthttp.RequestCtx, code int, object New) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
 	ctx.Response.SetStatusCode(code)
@@ -38,3 +60,90 @@ func WriteJSON(ctx *fasthttp.RequestCtx, code int, object New) {
 
 	ctx.Write(by)
 }
+
+// queryInt reads integer query argument, `def` is returned when the argument is missing
+func queryInt(ctx *fasthttp.RequestCtx, name string, def int) (int, error) {
+	if ctx.QueryArgs().Has(name) == false {
+		return def, nil
+	}
+	return ctx.QueryArgs().GetUint(name)
+}
+
+// queryStrings reads comma separated query argument, empty values are skipped
+func queryStrings(ctx *fasthttp.RequestCtx, name string) []string {
+	var output []string
+	for _, value := range strings.Split(string(ctx.QueryArgs().Peek(name)), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			output = append(output, value)
+		}
+	}
+	return output
+}
+
+// distinctLines counts every line of songs only once with `?distinct_lines=true`, repeated chorus doesn't inflate words then
+func distinctLines(ctx *fasthttp.RequestCtx, songs []internal.Song) []internal.Song {
+	if ctx.QueryArgs().GetBool("distinct_lines") {
+		return internal.WithDistinctLines(songs)
+	}
+	return songs
+}
+
+func artistsDistinctLines(ctx *fasthttp.RequestCtx, artists map[string][]internal.Song) map[string][]internal.Song {
+	if ctx.QueryArgs().GetBool("distinct_lines") {
+		return internal.ArtistsWithDistinctLines(artists)
+	}
+	return artists
+}
+
+// requestedDuplicates reads `?duplicates=group|dedupe`, invalid_parameter is written when the mode is unknown
+func requestedDuplicates(ctx *fasthttp.RequestCtx) (string, bool) {
+	mode := string(ctx.QueryArgs().Peek("duplicates"))
+	if err := internal.ValidateDuplicatesMode(mode); err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return "", false
+	}
+	return mode, true
+}
+
+// deduplicated drops remixes, live versions etc. of songs with `?duplicates=dedupe`, counting endpoints have nothing
+// to group, so `group` deduplicates them too
+func deduplicated(ctx *fasthttp.RequestCtx, songs []internal.Song) ([]internal.Song, bool) {
+	mode, ok := requestedDuplicates(ctx)
+	if ok == false || mode == "" {
+		return songs, ok
+	}
+	return internal.Deduplicate(songs), true
+}
+
+func artistsDeduplicated(ctx *fasthttp.RequestCtx, artists map[string][]internal.Song) (map[string][]internal.Song, bool) {
+	mode, ok := requestedDuplicates(ctx)
+	if ok == false || mode == "" {
+		return artists, ok
+	}
+	return internal.DeduplicateArtists(artists), true
+}
+
+// songURL links song on genius, songs of LYRICS_DIR and songs without GENIUS_HOST (offline) get only the page path,
+// for LYRICS_DIR it's the path of the file relative to it
+func songURL(cfg *config.Config, pageEndpoint string) string {
+	if cfg.LyricsDir != "" || cfg.GeniusHost == "" {
+		return pageEndpoint
+	}
+	return fmt.Sprintf("https://%s%s", cfg.GeniusHost, pageEndpoint)
+}
+
+// lyricsError tells which error describes failure of getting lyrics, unexpected ones are logged
+func lyricsError(err error, logger *log.Entry, message string) ErrorResponse {
+	switch {
+	case errors.Is(err, internal.ArtistNotFoundError):
+		return ErrorByName("artist_not_found")
+	case errors.Is(err, internal.OfflineError):
+		return ErrorByName("genius_offline")
+	}
+	logger.WithError(err).Error(message)
+	return ErrorByName("internal_error")
+}
+
+func writeLyricsError(ctx *fasthttp.RequestCtx, logger *log.Entry, err error, message string) {
+	WriteError(ctx, lyricsError(err, logger, message))
+}
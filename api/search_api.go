@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type SearchAPI interface {
+	SearchLyrics(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalSearchAPI{}
+
+type InternalSearchAPI struct {
+	// searchService is nil without database and LYRICS_DIR
+	searchService internal.SearchService
+	cfg           *config.Config
+	logger        *log.Entry
+}
+
+func NewSearchAPI(cfg *config.Config, searchService internal.SearchService, logger *log.Entry) *InternalSearchAPI {
+	return &InternalSearchAPI{cfg: cfg, searchService: searchService, logger: logger}
+}
+
+func (s *InternalSearchAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/search/lyrics", s.SearchLyrics)
+	return nil
+}
+
+type apiSearchHit struct {
+	GeniusID int      `json:"genius_id"`
+	Title    string   `json:"title"`
+	Artist   string   `json:"artist"`
+	URL      string   `json:"url"`
+	Matches  int      `json:"matches"`
+	Snippets []string `json:"snippets"`
+}
+
+// SearchLyrics finds stored songs of all artists with the word or phrase of `?q=`, `?limit=` is 20 by default
+func (s *InternalSearchAPI) SearchLyrics(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		Query string         `json:"query"`
+		Total int            `json:"total"`
+		Songs []apiSearchHit `json:"songs"`
+	}
+
+	if s.searchService == nil {
+		WriteError(ctx, ErrorByName("database_disabled"))
+		return
+	}
+	limit, err := queryInt(ctx, "limit", 20)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	result, err := s.searchService.SearchLyrics(string(ctx.QueryArgs().Peek("q")), limit)
+	if errors.Is(err, internal.EmptyQueryError) {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("error searching lyrics")
+		WriteError(ctx, ErrorByName("internal_error"))
+		return
+	}
+
+	resp := responseStruct{Query: result.Query, Total: result.Total, Songs: []apiSearchHit{}}
+	for _, hit := range result.Hits {
+		resp.Songs = append(resp.Songs, apiSearchHit{
+			GeniusID: hit.Song.GeniusID,
+			Title:    hit.Song.Title,
+			Artist:   hit.Song.Artist,
+			URL:      songURL(s.cfg, hit.Song.PagePath),
+			Matches:  hit.Matches,
+			Snippets: hit.Snippets,
+		})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
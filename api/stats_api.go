@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type StatsAPI interface {
+	GetArtistStats(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalStatsAPI{}
+
+type InternalStatsAPI struct {
+	lyricsService internal.LyricsService
+	cfg           *config.Config
+	logger        *log.Entry
+}
+
+func NewStatsAPI(cfg *config.Config, lyricsService internal.LyricsService, logger *log.Entry) *InternalStatsAPI {
+	return &InternalStatsAPI{cfg: cfg, lyricsService: lyricsService, logger: logger}
+}
+
+func (s *InternalStatsAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/artists/:artist_name/stats", s.GetArtistStats)
+	return nil
+}
+
+type apiSongStats struct {
+	Title     string                   `json:"title"`
+	URL       string                   `json:"url"`
+	Languages []internal.LanguageScore `json:"languages,omitempty"`
+	Stats     internal.LexicalStats    `json:"stats"`
+}
+
+// GetArtistStats returns lexical stats of every song, of the whole catalogue and averages of songs, `?language=pl` is supported
+func (s *InternalStatsAPI) GetArtistStats(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		SongsCount int                   `json:"songs_count"`
+		Total      internal.LexicalStats `json:"total"`
+		Average    internal.LexicalStats `json:"average"`
+		Songs      []apiSongStats        `json:"songs"`
+	}
+
+	artistName := ctx.Value("artist_name").(string)
+	songs, err := s.lyricsService.GetSongsByArtist(artistName)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	songs, ok := deduplicated(ctx, songs)
+	if ok == false {
+		return
+	}
+
+	stats := internal.NewArtistStats(internal.FilterSongsByLanguage(songs, string(ctx.QueryArgs().Peek("language"))))
+	resp := responseStruct{
+		SongsCount: len(stats.Songs),
+		Total:      stats.Total,
+		Average:    stats.Average,
+		Songs:      []apiSongStats{},
+	}
+	for _, song := range stats.Songs {
+		resp.Songs = append(resp.Songs, apiSongStats{
+			Title:     song.Song.Info.Title,
+			URL:       songURL(s.cfg, song.Song.Info.PageEndpoint),
+			Languages: song.Song.Languages,
+			Stats:     song.Stats,
+		})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
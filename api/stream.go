@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// StreamSSE is picked with `Accept: text/event-stream`, every event is `event: <name>\ndata: <json>\n\n`
+	StreamSSE = "text/event-stream"
+	// StreamNDJSON is picked with `Accept: application/x-ndjson`, every event is `{"event": "<name>", "data": <json>}\n`
+	StreamNDJSON = "application/x-ndjson"
+)
+
+// requestedStream returns the format of streamed response asked by `Accept`, empty string means plain JSON.
+// Media ranges with `q=0` aren't acceptable, the format with the highest `q` is picked when both of them are
+func requestedStream(ctx *fasthttp.RequestCtx) string {
+	format, quality := "", 0.0
+	for _, mediaRange := range strings.Split(string(ctx.Request.Header.Peek("Accept")), ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType != StreamSSE && mediaType != StreamNDJSON {
+			continue
+		}
+		if q := acceptQuality(params[1:]); q > quality {
+			format, quality = mediaType, q
+		}
+	}
+	return format
+}
+
+// acceptQuality reads `q` of the media range, it's 1 by default and 0 when it's invalid
+func acceptQuality(params []string) float64 {
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 || strings.ToLower(strings.TrimSpace(parts[0])) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+type streamEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type streamError struct {
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+}
+
+// StreamWriter writes events of streamed response, every event is flushed to the client right away
+type StreamWriter struct {
+	format string
+	w      *bufio.Writer
+	cancel context.CancelFunc
+	err    error
+}
+
+// Event writes the event, false is returned when the client has gone, the context of the stream is cancelled then
+func (s *StreamWriter) Event(name string, data interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+
+	by, err := json.Marshal(data)
+	if err != nil {
+		log.WithError(err).Error("error encoding streamed event")
+		return true
+	}
+
+	switch s.format {
+	case StreamSSE:
+		_, s.err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, by)
+	default:
+		by, _ = json.Marshal(streamEvent{Event: name, Data: by})
+		_, s.err = fmt.Fprintf(s.w, "%s\n", by)
+	}
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.err != nil {
+		s.cancel()
+		return false
+	}
+	return true
+}
+
+// Error writes the `error` event, the stream has started already, so it can't be told by the status code
+func (s *StreamWriter) Error(error ErrorResponse, message string) bool {
+	return s.Event("error", streamError{Error: error.Name, Message: message})
+}
+
+// WriteStream responds with events written by fn, it runs after the handler has returned, so fn mustn't use the request,
+// everything it needs has to be read before. The context given to fn is cancelled when the client disconnects
+func WriteStream(ctx *fasthttp.RequestCtx, format string, fn func(ctx context.Context, stream *StreamWriter)) {
+	ctx.Response.Header.Set("Content-Type", format)
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	// nginx would buffer the whole response otherwise
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	ctx.Response.SetStatusCode(200)
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		fn(streamCtx, &StreamWriter{format: format, w: w, cancel: cancel})
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type SyncAPI interface {
+	SyncArtist(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalSyncAPI{}
+
+type InternalSyncAPI struct {
+	// syncService is nil when database is disabled
+	syncService internal.SyncService
+	cfg         *config.Config
+	logger      *log.Entry
+}
+
+func NewSyncAPI(cfg *config.Config, syncService internal.SyncService, logger *log.Entry) *InternalSyncAPI {
+	return &InternalSyncAPI{cfg: cfg, syncService: syncService, logger: logger}
+}
+
+func (s *InternalSyncAPI) Register(r *fasthttprouter.Router) error {
+	r.POST("/artists/:artist_name/sync", s.SyncArtist)
+	return nil
+}
+
+// SyncArtist downloads only new songs of the artist and songs which lyrics have been completed since the last sync
+func (s *InternalSyncAPI) SyncArtist(ctx *fasthttp.RequestCtx) {
+	if s.syncService == nil {
+		WriteError(ctx, ErrorByName("database_disabled"))
+		return
+	}
+
+	result, err := s.syncService.SyncArtist(ctx.Value("artist_name").(string))
+	if errors.Is(err, internal.ArtistNotFoundError) {
+		WriteError(ctx, ErrorByName("artist_not_found"))
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("error syncing artist")
+		WriteError(ctx, ErrorByName("internal_error"))
+		return
+	}
+
+	WriteJSON(ctx, 200, New{Data: result})
+}
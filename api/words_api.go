@@ -0,0 +1,309 @@
+package api
+
+import (
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"strings"
+)
+
+type WordsAPI interface {
+	GetWordRanking(ctx *fasthttp.RequestCtx)
+	GetArtistWords(ctx *fasthttp.RequestCtx)
+	GetSongsDistinctiveWords(ctx *fasthttp.RequestCtx)
+	GetArtistsDistinctiveWords(ctx *fasthttp.RequestCtx)
+}
+
+var _ API = &InternalWordsAPI{}
+
+type InternalWordsAPI struct {
+	lyricsService internal.LyricsService
+	stopWords     *internal.StopWordsRegistry
+	cfg           *config.Config
+	logger        *log.Entry
+}
+
+func NewWordsAPI(cfg *config.Config, lyricsService internal.LyricsService, stopWords *internal.StopWordsRegistry, logger *log.Entry) *InternalWordsAPI {
+	return &InternalWordsAPI{cfg: cfg, lyricsService: lyricsService, stopWords: stopWords, logger: logger}
+}
+
+func (s *InternalWordsAPI) Register(r *fasthttprouter.Router) error {
+	r.GET("/artists/:artist_name/words", s.GetArtistWords)
+	r.GET("/artists/:artist_name/words/:word/ranking", s.GetWordRanking)
+	r.GET("/artists/:artist_name/distinctive-words", s.GetSongsDistinctiveWords)
+	r.GET("/distinctive-words", s.GetArtistsDistinctiveWords)
+	return nil
+}
+
+type apiSongWordsRank struct {
+	Title       string                    `json:"title"`
+	URL         string                    `json:"url"`
+	Occurrences internal.WordsOccurrences `json:"occurrences"`
+	Count       int                       `json:"count"`
+	TotalWords  int                       `json:"total_words"`
+	PerThousand float64                   `json:"per_thousand"`
+}
+
+// GetWordRanking orders songs of the artist by occurrences of the word, `:word` may contain many words separated by commas
+func (s *InternalWordsAPI) GetWordRanking(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		Words            []internal.Word           `json:"words"`
+		Totals           internal.WordsOccurrences `json:"totals"`
+		TotalOccurrences int                       `json:"total_occurrences"`
+		TotalWords       int                       `json:"total_words"`
+		PerThousand      float64                   `json:"per_thousand"`
+		SongsCount       int                       `json:"songs_count"`
+		Songs            []apiSongWordsRank        `json:"songs"`
+	}
+
+	artistName := ctx.Value("artist_name").(string)
+	words := internal.NormaliseWords(strings.Split(ctx.Value("word").(string), ","))
+	if len(words) == 0 {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	songs, err := s.lyricsService.GetSongsByArtist(artistName)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	songs, ok := deduplicated(ctx, songs)
+	if ok == false {
+		return
+	}
+	songs = distinctLines(ctx, songs)
+
+	ranking := internal.RankSongsByWords(internal.FilterSongsByLanguage(songs, string(ctx.QueryArgs().Peek("language"))), words)
+	resp := responseStruct{
+		Words:            ranking.Words,
+		Totals:           ranking.Totals,
+		TotalOccurrences: ranking.TotalCount,
+		TotalWords:       ranking.TotalWords,
+		PerThousand:      ranking.PerThousand,
+		SongsCount:       ranking.SongsCount,
+		Songs:            []apiSongWordsRank{},
+	}
+
+	for _, rank := range ranking.Songs {
+		resp.Songs = append(resp.Songs, apiSongWordsRank{
+			Title:       rank.Song.Info.Title,
+			URL:         songURL(s.cfg, rank.Song.Info.PageEndpoint),
+			Occurrences: rank.Occurrences,
+			Count:       rank.Count,
+			TotalWords:  rank.TotalWords,
+			PerThousand: rank.PerThousand,
+		})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+const defaultVocabularyLimit = 100
+
+// stopWordsRequest keeps `stop_words_language` and `stop_words` of the request, so they can be resolved after the handler
+// has returned, ex. for every streamed song
+type stopWordsRequest struct {
+	language string
+	extra    internal.StopWords
+}
+
+// requestedStopWordsRequest reads `stop_words_language` (en, pl, auto or none) and `stop_words` (base64, like banned_words),
+// `exclude_stop_words=true` is the same as `stop_words_language=auto`
+func requestedStopWordsRequest(ctx *fasthttp.RequestCtx) stopWordsRequest {
+	language := string(ctx.QueryArgs().Peek("stop_words_language"))
+	if language == "" && ctx.QueryArgs().GetBool("exclude_stop_words") {
+		language = internal.StopWordsAuto
+	}
+	return stopWordsRequest{language: language, extra: internal.NewStopWords(QueryStringList(ctx, "stop_words"))}
+}
+
+func (r stopWordsRequest) resolve(registry *internal.StopWordsRegistry, languages []string, words internal.WordsOccurrences) (internal.StopWords, string, error) {
+	stopWords, language, err := registry.Resolve(r.language, languages, words)
+	if err != nil {
+		return nil, "", err
+	}
+	return stopWords.Merge(r.extra), language, nil
+}
+
+func requestedStopWords(ctx *fasthttp.RequestCtx, registry *internal.StopWordsRegistry, languages []string, words internal.WordsOccurrences) (internal.StopWords, string, error) {
+	return requestedStopWordsRequest(ctx).resolve(registry, languages, words)
+}
+
+// vocabularyQuery reads `sort`, `min_count`, `limit` and `page`
+func vocabularyQuery(ctx *fasthttp.RequestCtx) (internal.VocabularyQuery, error) {
+	var query internal.VocabularyQuery
+	var err error
+
+	query.Order, err = internal.ParseVocabularyOrder(string(ctx.QueryArgs().Peek("sort")))
+	if err != nil {
+		return query, err
+	}
+	if query.MinCount, err = queryInt(ctx, "min_count", 0); err != nil {
+		return query, err
+	}
+	if query.Limit, err = queryInt(ctx, "limit", defaultVocabularyLimit); err != nil {
+		return query, err
+	}
+	if query.Page, err = queryInt(ctx, "page", 1); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// GetArtistWords returns words used in all of the artist's songs, ordered by `sort` and split into pages
+func (s *InternalWordsAPI) GetArtistWords(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		SongsCount        int                      `json:"songs_count"`
+		Languages         []internal.LanguageScore `json:"languages"`
+		StopWordsLanguage string                   `json:"stop_words_language"`
+		TotalWords        int                      `json:"total_words"`
+		UniqueWords       int                      `json:"unique_words"`
+		MatchingWords     int                      `json:"matching_words"`
+		Page              int                      `json:"page"`
+		Pages             int                      `json:"pages"`
+		Limit             int                      `json:"limit"`
+		Words             []internal.WordCount     `json:"words"`
+	}
+
+	query, err := vocabularyQuery(ctx)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	artistName := ctx.Value("artist_name").(string)
+	songs, err := s.lyricsService.GetSongsByArtist(artistName)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	songs, ok := deduplicated(ctx, songs)
+	if ok == false {
+		return
+	}
+	songs = distinctLines(ctx, songs)
+
+	songs = internal.FilterSongsByLanguage(songs, string(ctx.QueryArgs().Peek("language")))
+	languages := internal.ArtistLanguages(songs)
+	vocabulary := internal.ArtistVocabulary(songs)
+	stopWords, language, err := requestedStopWords(ctx, s.stopWords, internal.LanguagesOf(languages), vocabulary)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+	query.StopWords = stopWords
+
+	page := internal.QueryVocabulary(vocabulary, query)
+	WriteJSON(ctx, 200, New{Data: responseStruct{
+		SongsCount:        len(songs),
+		Languages:         languages,
+		StopWordsLanguage: language,
+		TotalWords:        page.TotalWords,
+		UniqueWords:       page.UniqueWords,
+		MatchingWords:     page.MatchingWords,
+		Page:              page.Page,
+		Pages:             page.Pages,
+		Limit:             page.Limit,
+		Words:             page.Words,
+	}})
+}
+
+const defaultDistinctiveWordsLimit = 10
+
+// GetSongsDistinctiveWords returns words of every song with the best TF-IDF scores, the corpus consists of songs of the artist
+// and songs of `compare_with` artists (comma separated), stop words params are the same as in GetArtistWords
+func (s *InternalWordsAPI) GetSongsDistinctiveWords(ctx *fasthttp.RequestCtx) {
+	type apiSongDistinctiveWords struct {
+		Title string               `json:"title"`
+		URL   string               `json:"url"`
+		Words []internal.WordScore `json:"words"`
+	}
+	type responseStruct struct {
+		CorpusSongs       int                       `json:"corpus_songs"`
+		StopWordsLanguage string                    `json:"stop_words_language"`
+		Songs             []apiSongDistinctiveWords `json:"songs"`
+	}
+
+	limit, err := queryInt(ctx, "limit", defaultDistinctiveWordsLimit)
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	artistName := ctx.Value("artist_name").(string)
+	artists, err := internal.GetSongsOfArtists(s.lyricsService, append([]string{artistName}, queryStrings(ctx, "compare_with")...))
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	artists, ok := artistsDeduplicated(ctx, artists)
+	if ok == false {
+		return
+	}
+	artists = artistsDistinctLines(ctx, artists)
+
+	var corpusSongs []internal.Song
+	for _, songs := range artists {
+		corpusSongs = append(corpusSongs, songs...)
+	}
+	songs := internal.FilterSongsByLanguage(artists[artistName], string(ctx.QueryArgs().Peek("language")))
+
+	stopWords, language, err := requestedStopWords(ctx, s.stopWords, internal.LanguagesOf(internal.ArtistLanguages(corpusSongs)), internal.ArtistVocabulary(corpusSongs))
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	resp := responseStruct{CorpusSongs: len(corpusSongs), StopWordsLanguage: language, Songs: []apiSongDistinctiveWords{}}
+	for _, song := range internal.SongsDistinctiveWords(songs, corpusSongs, stopWords, limit) {
+		resp.Songs = append(resp.Songs, apiSongDistinctiveWords{
+			Title: song.Song.Info.Title,
+			URL:   songURL(s.cfg, song.Song.Info.PageEndpoint),
+			Words: song.Words,
+		})
+	}
+	WriteJSON(ctx, 200, New{Data: resp})
+}
+
+// GetArtistsDistinctiveWords compares whole catalogues of `artists` (comma separated, at least two of them)
+func (s *InternalWordsAPI) GetArtistsDistinctiveWords(ctx *fasthttp.RequestCtx) {
+	type responseStruct struct {
+		StopWordsLanguage string                          `json:"stop_words_language"`
+		Artists           map[string][]internal.WordScore `json:"artists"`
+	}
+
+	names := queryStrings(ctx, "artists")
+	limit, err := queryInt(ctx, "limit", defaultDistinctiveWordsLimit)
+	if err != nil || len(names) < 2 {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	artists, err := internal.GetSongsOfArtists(s.lyricsService, names)
+	if err != nil {
+		writeLyricsError(ctx, s.logger, err, "error getting songs by artist")
+		return
+	}
+	artists, ok := artistsDeduplicated(ctx, artists)
+	if ok == false {
+		return
+	}
+	artists = artistsDistinctLines(ctx, artists)
+
+	var allSongs []internal.Song
+	for _, songs := range artists {
+		allSongs = append(allSongs, songs...)
+	}
+	stopWords, language, err := requestedStopWords(ctx, s.stopWords, internal.LanguagesOf(internal.ArtistLanguages(allSongs)), internal.ArtistVocabulary(allSongs))
+	if err != nil {
+		WriteError(ctx, ErrorByName("invalid_parameter"))
+		return
+	}
+
+	WriteJSON(ctx, 200, New{Data: responseStruct{
+		StopWordsLanguage: language,
+		Artists:           internal.ArtistsDistinctiveWords(artists, stopWords, limit),
+	}})
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/marosiak/WordFinder/api"
 	"github.com/marosiak/WordFinder/config"
 	"github.com/marosiak/WordFinder/internal"
-	"github.com/marosiak/WordFinder/utils"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,13 +22,46 @@ func main() {
 		mainLogger.SetLevel(log.WarnLevel)
 	}
 
-	geniusProvider := internal.NewGeniusProvider(utils.CreateHttpClient(&cfg), &cfg, logger)
-	lyricsService := internal.NewLyricsService(&cfg, geniusProvider, logger)
+	lyricsBackend, err := internal.NewLyricsBackend(&cfg, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot create lyrics service")
+	}
+	defer lyricsBackend.Close()
+	lyricsService, syncService, exportService := lyricsBackend.LyricsService, lyricsBackend.SyncService, lyricsBackend.ExportService
+
+	dictionaryRegistry, err := internal.LoadDictionaryRegistry(cfg.DictionariesDir)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load dictionaries")
+	}
+	dictionaryHistory := internal.NewFileDictionaryHistory(cfg.DictionariesHistoryDir)
+	dictionaryService, err := internal.NewDictionaryService(&cfg, dictionaryRegistry, dictionaryHistory, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load dictionaries history")
+	}
+
+	stopWords, err := internal.LoadStopWordsRegistry(cfg.StopWordsDir)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load stop words")
+	}
+
+	jobService := internal.NewJobService(&cfg, lyricsService, dictionaryService, stopWords, lyricsBackend.JobRepository, logger)
+	if err := jobService.Start(); err != nil {
+		logger.WithError(err).Fatal("cannot start jobs")
+	}
+	defer jobService.Close()
 
 	app, err := api.NewAPI(
 		fmt.Sprintf(":%d", cfg.ServerPort),
-		api.NewGeniusAPI(&cfg, lyricsService, logger),
-		api.NewDictionaryAPI(&cfg, logger),
+		api.NewGeniusAPI(&cfg, lyricsService, dictionaryService, stopWords, logger),
+		api.NewDictionaryAPI(&cfg, dictionaryService, logger),
+		api.NewWordsAPI(&cfg, lyricsService, stopWords, logger),
+		api.NewStatsAPI(&cfg, lyricsService, logger),
+		api.NewCompareAPI(&cfg, lyricsService, dictionaryService, stopWords, logger),
+		api.NewAnalysisAPI(&cfg, lyricsService, dictionaryService, logger),
+		api.NewSyncAPI(&cfg, syncService, logger),
+		api.NewExportAPI(&cfg, exportService, logger),
+		api.NewSearchAPI(&cfg, lyricsBackend.SearchService, logger),
+		api.NewJobsAPI(&cfg, jobService, logger),
 	)
 	if err != nil {
 		logger.WithError(err).Fatal("cannot create API")
@@ -3,7 +3,6 @@ package main
 import (
 	"github.com/marosiak/WordFinder/config"
 	"github.com/marosiak/WordFinder/internal"
-	"github.com/marosiak/WordFinder/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"os"
@@ -23,18 +22,81 @@ func main() {
 		mainLogger.SetLevel(log.WarnLevel)
 	}
 
-	geniusProvider := internal.NewGeniusProvider(utils.CreateHttpClient(&cfg), &cfg, logger)
-	lyricsService := internal.NewLyricsService(&cfg, geniusProvider, logger)
+	lyricsBackend, err := internal.NewLyricsBackend(&cfg, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot create lyrics service")
+	}
+	defer lyricsBackend.Close()
+	lyricsService, syncService := lyricsBackend.LyricsService, lyricsBackend.SyncService
+	exportService, importService, searchService := lyricsBackend.ExportService, lyricsBackend.ImportService, lyricsBackend.SearchService
 
-	cmd := internal.NewCmd(&cfg, lyricsService, logger)
+	dictionaryRegistry, err := internal.LoadDictionaryRegistry(cfg.DictionariesDir)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load dictionaries")
+	}
+	dictionaryHistory := internal.NewFileDictionaryHistory(cfg.DictionariesHistoryDir)
+	dictionaryService, err := internal.NewDictionaryService(&cfg, dictionaryRegistry, dictionaryHistory, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load dictionaries history")
+	}
 
-	flags := []cli.Flag{
+	stopWords, err := internal.LoadStopWordsRegistry(cfg.StopWordsDir)
+	if err != nil {
+		logger.WithError(err).Fatal("cannot load stop words")
+	}
+
+	cmd := internal.NewCmd(&cfg, lyricsService, dictionaryService, syncService, exportService, importService, searchService, stopWords, logger)
+
+	queryFlag := &cli.StringFlag{
+		Name:     "query",
+		Usage:    "--query=\"the_name\"",
+		Aliases:  []string{"q"},
+		Required: true,
+	}
+
+	dictionaryFlag := &cli.StringFlag{
+		Name:    "dictionary",
+		Usage:   "--dictionary=\"en/profanity\" name of dictionary from DICTIONARIES_DIR, \"profanity\" picks \"<language>/profanity\" for every song",
+		Aliases: []string{"dict"},
+	}
+
+	dictionaryVersionFlag := &cli.IntFlag{
+		Name:  "dictionary-version",
+		Usage: "--dictionary-version=3 pins --dictionary to one of its versions, the latest one is used by default",
+	}
+
+	languageFlag := &cli.StringFlag{
+		Name:    "language",
+		Usage:   "--language=\"pl\" uses only songs written (at least partly) in the language",
+		Aliases: []string{"lang"},
+	}
+
+	distinctLinesFlag := &cli.BoolFlag{
+		Name:  "distinct-lines",
+		Usage: "--distinct-lines counts every line of song once, so repeated chorus doesn't inflate words",
+	}
+
+	duplicatesFlag := &cli.StringFlag{
+		Name:  "duplicates",
+		Usage: "--duplicates=\"dedupe\" skips remixes, live versions, translations and skits, \"group\" prints them under the original song",
+	}
+
+	stopWordsFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "exclude-stop-words",
+			Usage: "--exclude-stop-words skips words like \"the\", \"and\", \"się\" of automatically detected language",
+		},
 		&cli.StringFlag{
-			Name:     "query",
-			Usage:    "--query=\"the_name\"",
-			Aliases:  []string{"q"},
-			Required: true,
+			Name:  "stop-words-language",
+			Usage: "--stop-words-language=\"pl\" (en, pl, auto or none), languages from STOP_WORDS_DIR are supported too",
+		},
+		&cli.StringSliceFlag{
+			Name:  "stop-words",
+			Usage: "--stop-words=\"yo,uh\" additional words to skip",
 		},
+	}
+
+	keywordFlags := []cli.Flag{
 		&cli.StringFlag{
 			Name:     "keyword",
 			Usage:    "--keyword=\"the_keyword\"",
@@ -49,7 +111,7 @@ func main() {
 		},
 		&cli.StringFlag{
 			Name:     "keywords-file",
-			Usage:    "--keywords-file=\"keywords.txt\"",
+			Usage:    "--keywords-file=\"keywords.txt\" (txt, csv, json or yaml)",
 			Aliases:  []string{"kwds-f"},
 			Required: false,
 		},
@@ -67,9 +129,249 @@ func main() {
 		Commands: []*cli.Command{
 			{
 				Name:   "songs-by-artist-without-banned-words", // damnn.. I have to find better name
-				Usage:  "Will return list of songs which does not contains any of --keywords or --keyword",
+				Usage:  "Will return list of songs which does not contains any of --keywords or --keyword and match --where",
 				Action: cmd.GetSongsByArtistWithoutBannedWords,
-				Flags:  flags,
+				Flags: append([]cli.Flag{queryFlag, dictionaryFlag, dictionaryVersionFlag, languageFlag, distinctLinesFlag, duplicatesFlag,
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "--where='section:chorus(love) AND NOT (money OR \"dollar bills\" OR @en/profanity)' (AND, OR, NOT, NEAR/5, section:, performer:, @dictionary)",
+					},
+				}, keywordFlags...),
+			},
+			{
+				Name:   "word-rank",
+				Usage:  "Will return songs of the artist ordered by occurrences of --word",
+				Action: cmd.GetWordRanking,
+				Flags: []cli.Flag{
+					queryFlag,
+					languageFlag,
+					distinctLinesFlag,
+					duplicatesFlag,
+					&cli.StringSliceFlag{
+						Name:     "word",
+						Usage:    "--word=\"money\" --word=\"cash\" or --word=\"money,cash\"",
+						Aliases:  []string{"w"},
+						Required: true,
+					},
+				},
+			},
+			{
+				Name:   "words",
+				Usage:  "Will return words used in all songs of the artist, the most common first",
+				Action: cmd.GetArtistWords,
+				Flags: append([]cli.Flag{
+					queryFlag,
+					languageFlag,
+					distinctLinesFlag,
+					duplicatesFlag,
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "--sort=\"count_desc\" (count_desc, count_asc, word_asc or word_desc)",
+						Value: string(internal.OrderCountDesc),
+					},
+					&cli.IntFlag{
+						Name:  "min-count",
+						Usage: "--min-count=3 skips words used less than 3 times",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "--limit=50 words per page, 0 prints all of them",
+						Value: 100,
+					},
+					&cli.IntFlag{
+						Name:  "page",
+						Usage: "--page=2",
+						Value: 1,
+					},
+				}, stopWordsFlags...),
+			},
+			{
+				Name:   "distinctive-words",
+				Usage:  "Will return words with the best TF-IDF scores of every song of the first --artist, or of every artist with --per=artist",
+				Action: cmd.GetDistinctiveWords,
+				Flags: append([]cli.Flag{
+					languageFlag,
+					distinctLinesFlag,
+					duplicatesFlag,
+					&cli.StringSliceFlag{
+						Name:     "artist",
+						Usage:    "--artist=\"eminem\" --artist=\"taco hemingway\", songs of all of the artists are the corpus",
+						Aliases:  []string{"a"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "per",
+						Usage: "--per=\"artist\" (song or artist)",
+						Value: "song",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "--limit=5 words for every song or artist",
+						Value: 10,
+					},
+				}, stopWordsFlags...),
+			},
+			{
+				Name:   "compare",
+				Usage:  "Will compare vocabularies of the artists - shared and exclusive words, jaccard similarity and explicitness with --dictionary",
+				Action: cmd.CompareArtists,
+				Flags: append([]cli.Flag{
+					languageFlag,
+					dictionaryFlag,
+					dictionaryVersionFlag,
+					distinctLinesFlag,
+					duplicatesFlag,
+					&cli.StringSliceFlag{
+						Name:     "artist",
+						Usage:    "--artist=\"eminem\" --artist=\"taco hemingway\"",
+						Aliases:  []string{"a"},
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "--limit=10 top exclusive and shared words",
+						Value: 20,
+					},
+				}, stopWordsFlags...),
+			},
+			{
+				Name:   "stats",
+				Usage:  "Will return vocabulary richness (type-token ratio, MTLD, MATTR, hapax legomena...) of songs of the artist",
+				Action: cmd.GetArtistStats,
+				Flags:  []cli.Flag{queryFlag, languageFlag, duplicatesFlag},
+			},
+			{
+				Name:    "analyse",
+				Aliases: []string{"analyze"},
+				Usage:   "Will return rhymes, flow, sentiment and themes of songs and performers of the artist",
+				Action:  cmd.AnalyseArtist,
+				Flags: []cli.Flag{queryFlag, languageFlag, duplicatesFlag,
+					&cli.BoolFlag{
+						Name:  "schemes",
+						Usage: "Prints rhyme scheme of every stanza, like AABB",
+					},
+					&cli.StringFlag{
+						Name:  "mood",
+						Usage: "Prints only songs of the mood: upbeat, neutral or dark",
+					},
+					&cli.StringSliceFlag{
+						Name:  "theme",
+						Usage: "Themes from \"themes/<theme>\" dictionaries, all of them by default",
+					},
+				},
+			},
+			{
+				Name:   "sync",
+				Usage:  "Will download only new songs of the artist and songs which lyrics have been completed since the last sync, needs DATABASE_DRIVER",
+				Action: cmd.SyncArtist,
+				Flags: []cli.Flag{queryFlag,
+					&cli.BoolFlag{
+						Name:    "verbose",
+						Usage:   "Prints every added, updated, removed and failed song",
+						Aliases: []string{"v"},
+					},
+				},
+			},
+			{
+				Name:   "export",
+				Usage:  "Will write songs of the artist with metadata, raw and normalised lyrics and manifest.json to --output",
+				Action: cmd.ExportArtist,
+				Flags: []cli.Flag{queryFlag, duplicatesFlag,
+					&cli.StringFlag{
+						Name:    "format",
+						Usage:   "--format=\"csv\" (jsonl, csv or txt - tree of text files)",
+						Aliases: []string{"f"},
+						Value:   "jsonl",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Usage:   "--output=\"eminem.zip\", directory or zip archive",
+						Aliases: []string{"o"},
+						Value:   "export",
+					},
+					&cli.BoolFlag{
+						Name:  "words",
+						Usage: "Adds word counts of every song",
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Will store songs from export (directory, zip archive, songs.jsonl or songs.csv), needs DATABASE_DRIVER",
+				ArgsUsage: "eminem.zip",
+				Action:    cmd.Import,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "on-conflict",
+						Usage: "--on-conflict=\"newer\" what to do with songs stored with different lyrics (skip, overwrite or newer)",
+						Value: "skip",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Only validates the dump, nothing is stored",
+					},
+					&cli.BoolFlag{
+						Name:    "verbose",
+						Usage:   "Prints every conflict and invalid song",
+						Aliases: []string{"v"},
+					},
+				},
+			},
+			{
+				Name:      "grep",
+				Usage:     "Will find stored songs of all artists with the word or phrase, needs DATABASE_DRIVER or LYRICS_DIR",
+				ArgsUsage: "\"mom's spaghetti\"",
+				Action:    cmd.Grep,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "--limit=50 songs, 0 prints all of them",
+						Value: 20,
+					},
+				},
+			},
+			{
+				Name:  "dict",
+				Usage: "Manage keywords dictionaries",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "Will print names of dictionaries from DICTIONARIES_DIR",
+						Action: cmd.ListDictionaries,
+					},
+					{
+						Name:      "show",
+						Usage:     "Will print dictionary with expanded extends and compose references",
+						ArgsUsage: "en/profanity",
+						Action:    cmd.ShowDictionary,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "format",
+								Usage:   "--format=\"yaml\" (txt, csv, json or yaml)",
+								Aliases: []string{"f"},
+								Value:   "txt",
+							},
+						},
+					},
+					{
+						Name:   "export",
+						Usage:  "Will write keywords from --keywords, --keywords-file etc. in chosen --format",
+						Action: cmd.ExportDictionary,
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:    "format",
+								Usage:   "--format=\"yaml\" (txt, csv, json or yaml)",
+								Aliases: []string{"f"},
+								Value:   "txt",
+							},
+							&cli.StringFlag{
+								Name:    "output",
+								Usage:   "--output=\"keywords.yaml\", standard output is used when empty",
+								Aliases: []string{"o"},
+							},
+						}, keywordFlags...),
+					},
+				},
 			},
 		},
 	}
@@ -1,26 +1,75 @@
 package config
 
 import (
+	"errors"
 	"github.com/kelseyhightower/envconfig"
 	"time"
 )
 
+var MissingGeniusConfigError = errors.New("GENIUS_RAPID_API_HOST, GENIUS_HOST, GENIUS_RAPID_API_KEY and GENIUS_API_HOST are required unless OFFLINE or LYRICS_DIR is set")
+
 type Config struct {
-	Debug                bool
-	GeniusRapidApiHost   string        `split_words:"true" required:"true"`
-	GeniusHost           string        `split_words:"true" required:"true"`
-	GeniusRapidApiKey    string        `split_words:"true" required:"true"`
-	GeniusApiHost        string        `split_words:"true" required:"true"`
-	UserAgents           []string      `split_words:"true"`
-	RequestTimeout       time.Duration `split_words:"true" default:"5s"`
-	MaxChannelBufferSize int           `split_words:"true" default:"30"`
-	MaxPagesForArtist    int           `split_words:"true" default:"100"`
-	ServerPort           int           `split_words:"true" default:"8080"`
+	Debug bool
+	// Genius settings are required only when lyrics are downloaded, see Validate
+	GeniusRapidApiHost     string        `split_words:"true"`
+	GeniusHost             string        `split_words:"true"`
+	GeniusRapidApiKey      string        `split_words:"true"`
+	GeniusApiHost          string        `split_words:"true"`
+	UserAgents             []string      `split_words:"true"`
+	RequestTimeout         time.Duration `split_words:"true" default:"5s"`
+	MaxChannelBufferSize   int           `split_words:"true" default:"30"`
+	MaxPagesForArtist      int           `split_words:"true" default:"100"`
+	MaxConcurrentRequests  int           `split_words:"true" default:"20"`
+	ServerPort             int           `split_words:"true" default:"8080"`
+	DictionariesDir        string        `split_words:"true" default:"dictionaries"`
+	DictionariesHistoryDir string        `split_words:"true" default:"dictionaries/.history"`
+	StopWordsDir           string        `split_words:"true"`
+	// DatabaseDriver is "sqlite" or "postgres", downloaded lyrics aren't stored when it's empty
+	DatabaseDriver    string        `split_words:"true"`
+	DatabaseDSN       string        `split_words:"true" default:"wordfinder.db"`
+	LyricsMaxAge      time.Duration `split_words:"true"`
+	ArtistSongsMaxAge time.Duration `split_words:"true" default:"24h"`
+	// CacheBackend is "memory", "disk" or "redis", responses of genius aren't cached when it's empty
+	CacheBackend        string        `split_words:"true"`
+	CacheSize           int           `split_words:"true" default:"10000"`
+	CacheDir            string        `split_words:"true" default:".cache"`
+	CacheRedisAddr      string        `split_words:"true" default:"localhost:6379"`
+	CacheRedisPassword  string        `split_words:"true"`
+	CacheSearchTTL      time.Duration `split_words:"true" default:"1h"`
+	CacheArtistSongsTTL time.Duration `split_words:"true" default:"1h"`
+	CacheLyricsTTL      time.Duration `split_words:"true" default:"168h"`
+	CacheStaleTTL       time.Duration `split_words:"true" default:"24h"`
+	// Offline uses only lyrics stored in the database, LyricsDir uses files instead, nothing is downloaded with both of them
+	Offline   bool
+	LyricsDir string `split_words:"true"`
+	// JobsWorkers is the number of analysis jobs running at the same time, every job downloads JobsConcurrentSongs songs at once
+	JobsWorkers         int `split_words:"true" default:"2"`
+	JobsConcurrentSongs int `split_words:"true" default:"4"`
+	// StreamConcurrentSongs is the number of songs downloaded at once for every streamed response
+	StreamConcurrentSongs int `split_words:"true" default:"4"`
 }
 
 func NewConfig() (Config, error) {
 	var cfg Config
 	err := envconfig.Process("", &cfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// IsOffline tells if lyrics are never downloaded from genius
+func (c Config) IsOffline() bool {
+	return c.Offline || c.LyricsDir != ""
+}
 
-	return cfg, err
+func (c Config) Validate() error {
+	if c.IsOffline() {
+		return nil
+	}
+	if c.GeniusRapidApiHost == "" || c.GeniusHost == "" || c.GeniusRapidApiKey == "" || c.GeniusApiHost == "" {
+		return MissingGeniusConfigError
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package internal
+
+type SongAnalysis struct {
+	Song       Song
+	Rhymes     RhymeAnalysis
+	Flow       FlowAnalysis
+	Sentiment  SentimentAnalysis
+	Themes     []ThemeScore
+	Repetition Repetition
+}
+
+type ArtistAnalysis struct {
+	Songs  []SongAnalysis
+	Rhymes RhymeSummary
+	Flow   FlowStats
+	// Performers include guests of features, stanzas without performer in header belong to the song author
+	Performers []PerformerFlow
+	Sentiment  Sentiment
+	// Moods count songs of every mood
+	Moods  map[string]int
+	Themes []ThemeScore
+	// Repetition has shares and scores averaged by songs
+	Repetition Repetition
+}
+
+// AnalyseSong analyses rhymes, flow, sentiment and repetition of the song, themes are counted only for the given theme dictionaries
+func AnalyseSong(song Song, themes []SongDictionary) SongAnalysis {
+	return SongAnalysis{
+		Song:       song,
+		Rhymes:     AnalyseRhymes(song.Lyrics, song.Language()),
+		Flow:       AnalyseFlow(song.Lyrics, song.Language(), song.Info.AuthorName),
+		Sentiment:  AnalyseSentiment(song.Lyrics, song.Language()),
+		Themes:     SongsThemes([]Song{song}, themes),
+		Repetition: AnalyseRepetition(song.Lyrics),
+	}
+}
+
+func AnalyseArtist(songs []Song, themes []SongDictionary) ArtistAnalysis {
+	analysis := ArtistAnalysis{
+		Sentiment: newSentiment().finish(),
+		Moods:     map[string]int{MoodUpbeat: 0, MoodNeutral: 0, MoodDark: 0},
+	}
+	var rhymes []RhymeAnalysis
+	var stanzas []StanzaFlow
+	var repetitions []Repetition
+	for _, song := range songs {
+		songAnalysis := AnalyseSong(song, themes)
+		analysis.Songs = append(analysis.Songs, songAnalysis)
+		rhymes = append(rhymes, songAnalysis.Rhymes)
+		stanzas = append(stanzas, songAnalysis.Flow.Stanzas...)
+		analysis.Sentiment = analysis.Sentiment.Add(songAnalysis.Sentiment.Total)
+		analysis.Moods[songAnalysis.Sentiment.Total.Mood]++
+		repetitions = append(repetitions, songAnalysis.Repetition)
+	}
+	analysis.Rhymes = NewRhymeSummary(rhymes)
+	analysis.Flow = NewFlowStats(stanzas)
+	analysis.Performers = PerformersFlow(stanzas)
+	analysis.Themes = SongsThemes(songs, themes)
+	analysis.Repetition = AverageRepetition(repetitions)
+	return analysis
+}
+
+// FilterByMood keeps analyses of songs with the mood, empty mood keeps all of them
+func FilterByMood(analyses []SongAnalysis, mood string) []SongAnalysis {
+	if mood == "" {
+		return analyses
+	}
+	var output []SongAnalysis
+	for _, analysis := range analyses {
+		if analysis.Sentiment.Total.Mood == mood {
+			output = append(output, analysis)
+		}
+	}
+	return output
+}
@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	CacheMemory = "memory"
+	CacheDisk   = "disk"
+	CacheRedis  = "redis"
+
+	// cacheEntryHeaderSize is size of times of storing and expiration written before value
+	cacheEntryHeaderSize = 16
+)
+
+var (
+	CacheMissError           = errors.New("cache miss")
+	UnknownCacheBackendError = errors.New("unknown cache backend")
+	InvalidCacheEntryError   = errors.New("invalid cache entry")
+)
+
+type CacheEntry struct {
+	Value    []byte
+	StoredAt time.Time
+}
+
+// Age is how long ago the entry has been stored
+func (e CacheEntry) Age() time.Duration {
+	return time.Since(e.StoredAt)
+}
+
+// Cache keeps bytes under keys, Get returns CacheMissError when there is no entry or it has expired.
+// Entries are kept after their TTL until expiration, so stale values can be served while they are refreshed
+type Cache interface {
+	Get(key string) (CacheEntry, error)
+	// Set stores the value, zero expiration means that the entry is kept until it's evicted
+	Set(key string, value []byte, expiration time.Duration) error
+	Delete(key string) error
+}
+
+// NewCache creates backend picked by CACHE_BACKEND, nil is returned when cache is disabled
+func NewCache(cfg *config.Config) (Cache, error) {
+	switch cfg.CacheBackend {
+	case "":
+		return nil, nil
+	case CacheMemory:
+		return NewLRUCache(cfg.CacheSize), nil
+	case CacheDisk:
+		return NewDiskCache(cfg.CacheDir)
+	case CacheRedis:
+		return NewRESPCache(cfg.CacheRedisAddr, cfg.CacheRedisPassword, cfg.RequestTimeout), nil
+	}
+	return nil, fmt.Errorf("%w: %q", UnknownCacheBackendError, cfg.CacheBackend)
+}
+
+func expiresAt(now time.Time, expiration time.Duration) time.Time {
+	if expiration == 0 {
+		return time.Time{}
+	}
+	return now.Add(expiration)
+}
+
+func isExpired(expires time.Time) bool {
+	return expires.IsZero() == false && time.Now().After(expires)
+}
+
+// encodeCacheEntry prefixes value with times of storing and expiration, so backends which keep only bytes know them
+func encodeCacheEntry(value []byte, storedAt time.Time, expires time.Time) []byte {
+	output := make([]byte, cacheEntryHeaderSize+len(value))
+	binary.BigEndian.PutUint64(output[0:8], uint64(storedAt.UnixNano()))
+	var expiresNano int64
+	if expires.IsZero() == false {
+		expiresNano = expires.UnixNano()
+	}
+	binary.BigEndian.PutUint64(output[8:16], uint64(expiresNano))
+	copy(output[cacheEntryHeaderSize:], value)
+	return output
+}
+
+func decodeCacheEntry(data []byte) (CacheEntry, time.Time, error) {
+	if len(data) < cacheEntryHeaderSize {
+		return CacheEntry{}, time.Time{}, InvalidCacheEntryError
+	}
+	entry := CacheEntry{
+		Value:    data[cacheEntryHeaderSize:],
+		StoredAt: time.Unix(0, int64(binary.BigEndian.Uint64(data[0:8]))),
+	}
+	var expires time.Time
+	if nano := int64(binary.BigEndian.Uint64(data[8:16])); nano != 0 {
+		expires = time.Unix(0, nano)
+	}
+	return entry, expires, nil
+}
+
+type lruItem struct {
+	key     string
+	entry   CacheEntry
+	expires time.Time
+}
+
+var _ Cache = &LRUCache{}
+
+// LRUCache keeps limited number of entries in memory, the least recently used one is evicted first
+type LRUCache struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{capacity: capacity, items: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *LRUCache) Get(key string) (CacheEntry, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.items[key]
+	if ok == false {
+		return CacheEntry{}, CacheMissError
+	}
+	item := element.Value.(*lruItem)
+	if isExpired(item.expires) {
+		c.order.Remove(element)
+		delete(c.items, key)
+		return CacheEntry{}, CacheMissError
+	}
+	c.order.MoveToFront(element)
+	return item.entry, nil
+}
+
+func (c *LRUCache) Set(key string, value []byte, expiration time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	item := &lruItem{key: key, entry: CacheEntry{Value: value, StoredAt: now}, expires: expiresAt(now, expiration)}
+	if element, ok := c.items[key]; ok {
+		element.Value = item
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(item)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+func (c *LRUCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+	return nil
+}
+
+func (c *LRUCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+var _ Cache = &DiskCache{}
+
+// DiskCache keeps every entry in a file named by hash of the key, expired files are removed when they are read
+type DiskCache struct {
+	dir string
+}
+
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:]))
+}
+
+func (c *DiskCache) Get(key string) (CacheEntry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return CacheEntry{}, CacheMissError
+	}
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	entry, expires, err := decodeCacheEntry(data)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	if isExpired(expires) {
+		os.Remove(c.path(key))
+		return CacheEntry{}, CacheMissError
+	}
+	return entry, nil
+}
+
+// Set writes to temporary file which is renamed, so readers never see half written entry
+func (c *DiskCache) Set(key string, value []byte, expiration time.Duration) error {
+	now := time.Now()
+	file, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(encodeCacheEntry(value, now, expiresAt(now, expiration)))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), c.path(key))
+}
+
+func (c *DiskCache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
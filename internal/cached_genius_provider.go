@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+var _ GeniusProvider = &CachedGeniusProvider{}
+
+// CachedGeniusProvider keeps responses of genius in the cache. Entry older than its TTL is still returned
+// for CACHE_STALE_TTL, it's refreshed in the background then (stale-while-revalidate), so repeated requests
+// are instant. Errors aren't cached
+type CachedGeniusProvider struct {
+	provider   GeniusProvider
+	cache      Cache
+	cfg        *config.Config
+	logger     *log.Entry
+	refreshing map[string]struct{}
+	mutex      sync.Mutex
+}
+
+func NewCachedGeniusProvider(cfg *config.Config, provider GeniusProvider, cache Cache, logger *log.Entry) *CachedGeniusProvider {
+	return &CachedGeniusProvider{provider: provider, cache: cache, cfg: cfg, logger: logger, refreshing: make(map[string]struct{})}
+}
+
+// cached reads the key into value, fetch is called when there is nothing fresh enough in the cache
+func (c *CachedGeniusProvider) cached(key string, ttl time.Duration, value interface{}, fetch func() (interface{}, error)) error {
+	entry, err := c.cache.Get(key)
+	if err != nil && errors.Is(err, CacheMissError) == false {
+		c.logger.WithError(err).WithField("key", key).Error("cannot read cache")
+	}
+	if err == nil && json.Unmarshal(entry.Value, value) == nil {
+		if entry.Age() < ttl {
+			return nil
+		}
+		c.refresh(key, ttl, fetch)
+		return nil
+	}
+
+	data, err := c.fetch(key, ttl, fetch)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// uncached is returned by fetch when the value mustn't be cached, ex. artist which hasn't been found,
+// it could be added to genius a minute later
+type uncached struct {
+	value interface{}
+}
+
+// uncachedIfZeroID wraps values which have zero ID, genius returns them when nothing has been found
+func uncachedIfZeroID(value interface{}, id int) interface{} {
+	if id == 0 {
+		return uncached{value: value}
+	}
+	return value
+}
+
+func (c *CachedGeniusProvider) fetch(key string, ttl time.Duration, fetch func() (interface{}, error)) ([]byte, error) {
+	fetched, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := fetched.(uncached); ok {
+		return json.Marshal(value.value)
+	}
+	data, err := json.Marshal(fetched)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cache.Set(key, data, ttl+c.cfg.CacheStaleTTL); err != nil {
+		c.logger.WithError(err).WithField("key", key).Error("cannot write cache")
+	}
+	return data, nil
+}
+
+// refresh fetches the key in the background, only once at the same time
+func (c *CachedGeniusProvider) refresh(key string, ttl time.Duration, fetch func() (interface{}, error)) {
+	c.mutex.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mutex.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			c.mutex.Lock()
+			delete(c.refreshing, key)
+			c.mutex.Unlock()
+		}()
+		if _, err := c.fetch(key, ttl, fetch); err != nil {
+			c.logger.WithError(err).WithField("key", key).Error("cannot refresh cache")
+		}
+	}()
+}
+
+// Refreshing tells how many keys are being refreshed in the background right now
+func (c *CachedGeniusProvider) Refreshing() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.refreshing)
+}
+
+func (c *CachedGeniusProvider) Search(query string) ([]GeniusSearchResult, error) {
+	var results []GeniusSearchResult
+	err := c.cached("search:"+query, c.cfg.CacheSearchTTL, &results, func() (interface{}, error) {
+		results, err := c.provider.Search(query)
+		return results, err
+	})
+	return results, err
+}
+
+func (c *CachedGeniusProvider) GetArtist(artistName string) (GeniusArtist, error) {
+	var artist GeniusArtist
+	err := c.cached("artist:"+artistName, c.cfg.CacheSearchTTL, &artist, func() (interface{}, error) {
+		artist, err := c.provider.GetArtist(artistName)
+		return uncachedIfZeroID(artist, artist.ID), err
+	})
+	return artist, err
+}
+
+func (c *CachedGeniusProvider) GetSongInfoByID(id int) (GeniusSongInfo, error) {
+	var songInfo GeniusSongInfo
+	err := c.cached(fmt.Sprintf("song:%d", id), c.cfg.CacheArtistSongsTTL, &songInfo, func() (interface{}, error) {
+		songInfo, err := c.provider.GetSongInfoByID(id)
+		return uncachedIfZeroID(songInfo, songInfo.ID), err
+	})
+	return songInfo, err
+}
+
+func (c *CachedGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusSongInfo, error) {
+	var songInfos []GeniusSongInfo
+	err := c.cached(fmt.Sprintf("artist-songs:%d", artistID), c.cfg.CacheArtistSongsTTL, &songInfos, func() (interface{}, error) {
+		songInfos, err := c.provider.GetSongInfosByArtistID(artistID)
+		return songInfos, err
+	})
+	return songInfos, err
+}
+
+func (c *CachedGeniusProvider) GetLyrics(songInfo GeniusSongInfo) (Lyrics, error) {
+	var lyrics Lyrics
+	err := c.cached(fmt.Sprintf("lyrics:%d", songInfo.ID), c.cfg.CacheLyricsTTL, &lyrics, func() (interface{}, error) {
+		lyrics, err := c.provider.GetLyrics(songInfo)
+		return lyrics, err
+	})
+	return lyrics, err
+}
+
+func (c *CachedGeniusProvider) GetSongByName(name string) (GeniusSong, error) {
+	var song GeniusSong
+	err := c.cached("song-by-name:"+name, c.cfg.CacheLyricsTTL, &song, func() (interface{}, error) {
+		song, err := c.provider.GetSongByName(name)
+		return uncachedIfZeroID(song, song.Info.ID), err
+	})
+	return song, err
+}
+
+func (c *CachedGeniusProvider) GetSongByID(id int) (GeniusSong, error) {
+	return getSongByID(c, id)
+}
+
+func (c *CachedGeniusProvider) GetSongsByIDs(ids []int) ([]GeniusSong, error) {
+	return getSongsByIDs(c, ids, c.logger), nil
+}
+
+// GetSongsByArtistID is built from the cached list of songs and cached lyrics, so only missing lyrics are downloaded
+func (c *CachedGeniusProvider) GetSongsByArtistID(artistID int) ([]GeniusSong, error) {
+	return getSongsByArtistID(c, artistID)
+}
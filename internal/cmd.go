@@ -1,134 +1,719 @@
 package internal
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"github.com/marosiak/WordFinder/config"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 type Cmd interface {
 	GetSongsByArtistWithoutBannedWords(ctx *cli.Context) error
+	ExportDictionary(ctx *cli.Context) error
+	ListDictionaries(ctx *cli.Context) error
+	ShowDictionary(ctx *cli.Context) error
+	GetWordRanking(ctx *cli.Context) error
+	GetArtistWords(ctx *cli.Context) error
+	GetArtistStats(ctx *cli.Context) error
+	GetDistinctiveWords(ctx *cli.Context) error
+	CompareArtists(ctx *cli.Context) error
+	AnalyseArtist(ctx *cli.Context) error
+	SyncArtist(ctx *cli.Context) error
+	ExportArtist(ctx *cli.Context) error
+	Import(ctx *cli.Context) error
+	Grep(ctx *cli.Context) error
 }
 
 var _ Cmd = &InternalCmd{}
 
 type InternalCmd struct {
-	lyricsService LyricsService
+	lyricsService     LyricsService
+	dictionaryService DictionaryService
+	// syncService is nil when database is disabled
+	syncService   SyncService
+	exportService ExportService
+	// importService is nil when database is disabled
+	importService ImportService
+	// searchService is nil without database and LYRICS_DIR
+	searchService SearchService
+	stopWords     *StopWordsRegistry
 	logger        *log.Entry
 	cfg           *config.Config
 }
 
-func NewCmd(cfg *config.Config, lyricsService LyricsService, logger *log.Entry) *InternalCmd {
-	return &InternalCmd{logger: logger, lyricsService: lyricsService, cfg: cfg}
+func NewCmd(cfg *config.Config, lyricsService LyricsService, dictionaryService DictionaryService, syncService SyncService, exportService ExportService, importService ImportService, searchService SearchService, stopWords *StopWordsRegistry, logger *log.Entry) *InternalCmd {
+	return &InternalCmd{logger: logger, lyricsService: lyricsService, dictionaryService: dictionaryService, syncService: syncService, exportService: exportService, importService: importService, searchService: searchService, stopWords: stopWords, cfg: cfg}
 }
 
-func getKeywordsFromFile(fileName string) (output []string) {
-	f, err := os.OpenFile(fileName, os.O_RDONLY, os.ModePerm)
+// getDictionary merges keywords from all of the keyword flags into one dictionary, files may be in any supported format
+func getDictionary(ctx *cli.Context) (Dictionary, error) {
+	var keywords []string
+	for _, keyword := range ctx.StringSlice("keywords") {
+		keywords = append(keywords, strings.Split(keyword, ",")...)
+	}
+	if keyword := ctx.String("keyword"); keyword != "" {
+		keywords = append(keywords, keyword)
+	}
+
+	var fileNames []string
+	for _, files := range ctx.StringSlice("keywords-files") {
+		fileNames = append(fileNames, strings.Split(files, ",")...)
+	}
+	if fileName := ctx.String("keywords-file"); fileName != "" {
+		fileNames = append(fileNames, fileName)
+	}
+
+	dictionary := NewDictionaryFromWords("keywords", keywords)
+	for _, fileName := range fileNames {
+		fileDictionary, err := LoadDictionaryFile(fileName)
+		if err != nil {
+			return Dictionary{}, err
+		}
+		dictionary = dictionary.Merge(fileDictionary)
+	}
+
+	return dictionary, nil
+}
+
+func (s *InternalCmd) GetSongsByArtistWithoutBannedWords(ctx *cli.Context) error {
+	query := ctx.String("query")
+	dictionary, err := getDictionary(ctx)
 	if err != nil {
+		fmt.Printf("Error while reading keywords: %v\n", err)
+		return err
+	}
+
+	var dictionaries []SongDictionary
+	if len(dictionary.Entries) > 0 {
+		dictionaries = append(dictionaries, NewSongDictionary(dictionary))
+	}
+	if name := ctx.String("dictionary"); name != "" {
+		found, err := GetSongDictionary(s.dictionaryService, name, ctx.Int("dictionary-version"))
+		if err != nil {
+			fmt.Printf("Error while getting dictionary: %v\n", err)
+			return err
+		}
+		dictionaries = append(dictionaries, found)
+	}
+
+	// keywords and dictionaries are just `NOT (@keywords OR @dictionary)` joined with --where
+	var where *Query
+	if text := ctx.String("where"); text != "" {
+		where, err = ParseQuery(text, s.dictionaryService)
+		if err != nil {
+			printQueryError(err)
+			return err
+		}
+	}
+	filter := AllOf(WithoutDictionaries(dictionaries...), where)
+	if filter != nil {
+		for _, dictionaryVersion := range filter.DictionaryVersions() {
+			fmt.Printf("%s dictionary %s version %d\n", commentPrefix, dictionaryVersion.Name, dictionaryVersion.Version)
+		}
+	}
+
+	songs, err := s.lyricsService.GetSongsByArtist(query)
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+	duplicates := ctx.String("duplicates")
+	if err := ValidateDuplicatesMode(duplicates); err != nil {
+		fmt.Printf("Error while reading duplicates mode: %v\n", err)
+		return err
+	}
+	if duplicates == DuplicatesDedupe {
+		songs = Deduplicate(songs)
+	}
+	if ctx.Bool("distinct-lines") {
+		songs = WithDistinctLines(songs)
+	}
+	songs = FilterSongsByLanguage(songs, ctx.String("language"))
+
+	allowedSongs := FilterSongsByQuery(songs, filter)
+	if duplicates == DuplicatesGroup {
+		printSongGroups(GroupDuplicates(allowedSongs))
+		return nil
+	}
+
+	songsWithoutBannedWords := make(map[string]struct{})
+	for _, song := range allowedSongs {
+		songsWithoutBannedWords[song.Info.Title] = struct{}{}
+	}
+	for k, _ := range songsWithoutBannedWords {
+		fmt.Println(k)
+	}
+	return nil
+}
+
+// printQueryError shows where the query is wrong
+func printQueryError(err error) {
+	var queryError *QueryError
+	if errors.As(err, &queryError) {
+		fmt.Printf("Invalid --where, %v\n%s\n", queryError, queryError.Pointer())
 		return
 	}
-	defer f.Close()
-
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		text := sc.Text() // GET the line string
-		splittedSpace := strings.Split(text, " ")
-		for _, word := range splittedSpace {
-			splittedComma := strings.Split(word, ",")
-			for _, word := range splittedComma {
-				if word != "" {
-					output = append(output, strings.ToLower(word))
-				}
+	fmt.Printf("Invalid --where: %v\n", err)
+}
+
+// printSongGroups prints original songs with their remixes, live versions etc. indented below
+func printSongGroups(groups []SongGroup) {
+	for _, group := range groups {
+		fmt.Println(group.Song.Info.Title)
+		for _, duplicate := range group.Duplicates {
+			variant := duplicate.Variant
+			if variant == "" {
+				variant = "similar lyrics"
 			}
+			fmt.Printf("  %s (%s, similarity %.2f)\n", duplicate.Song.Info.Title, variant, duplicate.Similarity)
 		}
 	}
-	if err := sc.Err(); err != nil {
-		log.Fatalf("scan file error: %v", err)
-		return
+}
+
+// deduplicateSongs applies --duplicates to songs which words are counted, there is nothing to group there,
+// so group mode deduplicates them too
+func deduplicateSongs(ctx *cli.Context, songs []Song) ([]Song, error) {
+	mode := ctx.String("duplicates")
+	if err := ValidateDuplicatesMode(mode); err != nil {
+		fmt.Printf("Error while reading duplicates mode: %v\n", err)
+		return nil, err
 	}
-	return output
+	if mode == "" {
+		return songs, nil
+	}
+	return Deduplicate(songs), nil
+}
+
+func deduplicateArtists(ctx *cli.Context, artists map[string][]Song) (map[string][]Song, error) {
+	mode := ctx.String("duplicates")
+	if err := ValidateDuplicatesMode(mode); err != nil {
+		fmt.Printf("Error while reading duplicates mode: %v\n", err)
+		return nil, err
+	}
+	if mode == "" {
+		return artists, nil
+	}
+	return DeduplicateArtists(artists), nil
 }
 
-var CannotOpenFileError = errors.New("Cannot open \"%s\" file\n")
+func (s *InternalCmd) ExportDictionary(ctx *cli.Context) error {
+	dictionary, err := getDictionary(ctx)
+	if err != nil {
+		fmt.Printf("Error while reading keywords: %v\n", err)
+		return err
+	}
+
+	format, err := ParseDictionaryFormat(ctx.String("format"))
+	if err != nil {
+		fmt.Printf("Error while reading format: %v\n", err)
+		return err
+	}
+
+	output := ctx.String("output")
+	if output == "" {
+		return WriteDictionary(os.Stdout, dictionary, format)
+	}
+	return SaveDictionaryFile(output, dictionary, format)
+}
 
-func getKeywords(ctx *cli.Context) (output []string) {
-	keywords := ctx.StringSlice("keywords")
-	for _, keyword := range keywords {
-		splitted := strings.Split(keyword, ",")
-		for _, v := range splitted {
-			output = append(output, v)
+func (s *InternalCmd) ListDictionaries(ctx *cli.Context) error {
+	for _, name := range s.dictionaryService.GetDictionariesNames() {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func (s *InternalCmd) ShowDictionary(ctx *cli.Context) error {
+	name := ctx.Args().First()
+	if name == "" {
+		return errors.New("dictionary name is required, ex. genius-cli dict show en/profanity")
+	}
+
+	dictionary, err := s.dictionaryService.GetDictionary(name)
+	if err != nil {
+		fmt.Printf("Error while getting dictionary: %v\n", err)
+		return err
+	}
+
+	format, err := ParseDictionaryFormat(ctx.String("format"))
+	if err != nil {
+		fmt.Printf("Error while reading format: %v\n", err)
+		return err
+	}
+
+	definition, err := s.dictionaryService.GetDictionaryDefinition(name)
+	if err == nil && definition.IsComposed() && format == DictionaryText {
+		for _, line := range directiveLines(definition) {
+			fmt.Printf("%s %s\n", commentPrefix, line)
 		}
 	}
+	return WriteDictionary(os.Stdout, dictionary, format)
+}
 
-	keywordsFiles := ctx.StringSlice("keywords-files")
-	keywordsFromFiles := []string{}
-	for _, keyword := range keywordsFiles {
-		splitted := strings.Split(keyword, ",")
-		for _, fileName := range splitted {
-			keywordsFromFiles = append(keywordsFromFiles, fileName)
+func (s *InternalCmd) GetWordRanking(ctx *cli.Context) error {
+	query := ctx.String("query")
+	words := NormaliseWords(ctx.StringSlice("word"))
 
-			kw := getKeywordsFromFile(fileName)
-			if len(kw) == 0 {
-				fmt.Printf(CannotOpenFileError.Error(), fileName)
-				continue
-			}
-			output = append(output, kw...)
+	songs, err := s.lyricsService.GetSongsByArtist(query)
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+	if songs, err = deduplicateSongs(ctx, songs); err != nil {
+		return err
+	}
+	if ctx.Bool("distinct-lines") {
+		songs = WithDistinctLines(songs)
+	}
+
+	ranking := RankSongsByWords(FilterSongsByLanguage(songs, ctx.String("language")), words)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COUNT\tPER 1000\tTITLE")
+	for _, rank := range ranking.Songs {
+		fmt.Fprintf(w, "%d\t%.2f\t%s\n", rank.Count, rank.PerThousand, rank.Song.Info.Title)
+	}
+	fmt.Fprintf(w, "%d\t%.2f\tTOTAL (%d of %d songs)\n", ranking.TotalCount, ranking.PerThousand, len(ranking.Songs), ranking.SongsCount)
+	return w.Flush()
+}
+
+// getStopWords reads --stop-words-language, --exclude-stop-words and --stop-words, "auto" uses languages of the songs
+func (s *InternalCmd) getStopWords(ctx *cli.Context, songs []Song) (StopWords, string, error) {
+	language := ctx.String("stop-words-language")
+	if language == "" && ctx.Bool("exclude-stop-words") {
+		language = StopWordsAuto
+	}
+
+	stopWords, language, err := s.stopWords.Resolve(language, LanguagesOf(ArtistLanguages(songs)), ArtistVocabulary(songs))
+	if err != nil {
+		return nil, "", err
+	}
+	return stopWords.Merge(NewStopWords(NormaliseWords(ctx.StringSlice("stop-words")))), language, nil
+}
+
+func (s *InternalCmd) GetArtistWords(ctx *cli.Context) error {
+	order, err := ParseVocabularyOrder(ctx.String("sort"))
+	if err != nil {
+		fmt.Printf("Error while reading sort order: %v\n", err)
+		return err
+	}
+
+	songs, err := s.lyricsService.GetSongsByArtist(ctx.String("query"))
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+	if songs, err = deduplicateSongs(ctx, songs); err != nil {
+		return err
+	}
+	if ctx.Bool("distinct-lines") {
+		songs = WithDistinctLines(songs)
+	}
+	songs = FilterSongsByLanguage(songs, ctx.String("language"))
+	vocabulary := ArtistVocabulary(songs)
+
+	stopWords, language, err := s.getStopWords(ctx, songs)
+	if err != nil {
+		fmt.Printf("Error while getting stop words: %v\n", err)
+		return err
+	}
+
+	page := QueryVocabulary(vocabulary, VocabularyQuery{
+		Order:     order,
+		MinCount:  ctx.Int("min-count"),
+		StopWords: stopWords,
+		Limit:     ctx.Int("limit"),
+		Page:      ctx.Int("page"),
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COUNT\tWORD")
+	for _, word := range page.Words {
+		fmt.Fprintf(w, "%d\t%s\n", word.Count, word.Word)
+	}
+	fmt.Fprintf(w, "%s page %d of %d, %d of %d unique words, %d words in %d songs, stop words: %s\n", commentPrefix,
+		page.Page, page.Pages, page.MatchingWords, page.UniqueWords, page.TotalWords, len(songs), language)
+	return w.Flush()
+}
+
+func (s *InternalCmd) GetArtistStats(ctx *cli.Context) error {
+	songs, err := s.lyricsService.GetSongsByArtist(ctx.String("query"))
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+
+	if songs, err = deduplicateSongs(ctx, songs); err != nil {
+		return err
+	}
+
+	stats := NewArtistStats(FilterSongsByLanguage(songs, ctx.String("language")))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORDS\tUNIQUE\tTTR\tMTLD\tMATTR\tHAPAX\tWORD LENGTH\tWORDS/LINE\tTITLE")
+	printStats := func(stats LexicalStats, title string) {
+		fmt.Fprintf(w, "%d\t%d\t%.3f\t%.1f\t%.3f\t%d\t%.2f\t%.2f\t%s\n", stats.Words, stats.UniqueWords, stats.TypeTokenRatio,
+			stats.MTLD, stats.MATTR, stats.HapaxLegomena, stats.AverageWordLength, stats.WordsPerLine, title)
+	}
+	for _, song := range stats.Songs {
+		printStats(song.Stats, song.Song.Info.Title)
+	}
+	printStats(stats.Average, fmt.Sprintf("AVERAGE (%d songs)", len(stats.Songs)))
+	printStats(stats.Total, "TOTAL")
+	return w.Flush()
+}
+
+// GetDistinctiveWords prints words with the best TF-IDF scores of every song of the first --artist, or with --per=artist
+// words of every artist compared with the other ones
+func (s *InternalCmd) GetDistinctiveWords(ctx *cli.Context) error {
+	names := ctx.StringSlice("artist")
+	per := ctx.String("per")
+	if per != "song" && per != "artist" {
+		return fmt.Errorf("--per has to be \"song\" or \"artist\", got %q", per)
+	}
+	if per == "artist" && len(names) < 2 {
+		return errors.New("at least two --artist are required to compare artists")
+	}
+
+	artists, err := GetSongsOfArtists(s.lyricsService, names)
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+	if artists, err = deduplicateArtists(ctx, artists); err != nil {
+		return err
+	}
+	if ctx.Bool("distinct-lines") {
+		artists = ArtistsWithDistinctLines(artists)
+	}
+
+	var allSongs []Song
+	for _, name := range names {
+		allSongs = append(allSongs, artists[name]...)
+	}
+
+	stopWords, _, err := s.getStopWords(ctx, allSongs)
+	if err != nil {
+		fmt.Printf("Error while getting stop words: %v\n", err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDISTINCTIVE WORDS")
+	formatScores := func(scores []WordScore) string {
+		var words []string
+		for _, score := range scores {
+			words = append(words, fmt.Sprintf("%s (%d)", score.Word, score.Count))
 		}
+		return strings.Join(words, ", ")
 	}
 
-	filePath := ctx.String("keywords-file")
-	if filePath != "" {
-		kw := getKeywordsFromFile(filePath)
-		if len(kw) == 0 {
-			fmt.Printf(CannotOpenFileError.Error(), filePath)
+	if per == "artist" {
+		scores := ArtistsDistinctiveWords(artists, stopWords, ctx.Int("limit"))
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, formatScores(scores[name]))
 		}
-		output = append(output, kw...)
+		return w.Flush()
+	}
+
+	songs := FilterSongsByLanguage(artists[names[0]], ctx.String("language"))
+	for _, song := range SongsDistinctiveWords(songs, allSongs, stopWords, ctx.Int("limit")) {
+		fmt.Fprintf(w, "%s\t%s\n", song.Song.Info.Title, formatScores(song.Words))
+	}
+	return w.Flush()
+}
+
+func (s *InternalCmd) CompareArtists(ctx *cli.Context) error {
+	names := ctx.StringSlice("artist")
+	if len(names) < 2 {
+		return errors.New("at least two --artist are required")
 	}
 
-	keyword := ctx.String("keyword")
-	if keyword != "" {
-		output = append(output, keyword)
+	var songDictionary *SongDictionary
+	if name := ctx.String("dictionary"); name != "" {
+		found, err := GetSongDictionary(s.dictionaryService, name, ctx.Int("dictionary-version"))
+		if err != nil {
+			fmt.Printf("Error while getting dictionary: %v\n", err)
+			return err
+		}
+		songDictionary = &found
 	}
 
-	return output
+	artists, err := GetSongsOfArtists(s.lyricsService, names)
+	if err != nil {
+		fmt.Printf("Error while getting songs list: %v", err)
+		return err
+	}
+	if artists, err = deduplicateArtists(ctx, artists); err != nil {
+		return err
+	}
+	if ctx.Bool("distinct-lines") {
+		artists = ArtistsWithDistinctLines(artists)
+	}
+
+	var allSongs []Song
+	for _, name := range names {
+		artists[name] = FilterSongsByLanguage(artists[name], ctx.String("language"))
+		allSongs = append(allSongs, artists[name]...)
+	}
+
+	stopWords, _, err := s.getStopWords(ctx, allSongs)
+	if err != nil {
+		fmt.Printf("Error while getting stop words: %v\n", err)
+		return err
+	}
+
+	comparison := CompareArtists(artists, names, CompareOptions{StopWords: stopWords, Limit: ctx.Int("limit"), Dictionary: songDictionary})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARTIST\tSONGS\tWORDS\tUNIQUE\tEXCLUSIVE\tEXPLICITNESS\tTOP EXCLUSIVE WORDS")
+	for _, artist := range comparison.Artists {
+		explicitness := "-"
+		if artist.Explicitness != nil {
+			explicitness = fmt.Sprintf("%.2f (x%.2f)", artist.Explicitness.Score, artist.Explicitness.Relative)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\n", artist.Name, artist.SongsCount, artist.TotalWords, artist.UniqueWords,
+			artist.ExclusiveWords, explicitness, formatWordCounts(artist.TopExclusiveWords))
+	}
+	fmt.Fprintln(w)
+	for _, pair := range comparison.Pairs {
+		fmt.Fprintf(w, "%s\tjaccard %.3f\t%d shared words\n", strings.Join(pair.Artists, " & "), pair.Jaccard, pair.Shared)
+	}
+	fmt.Fprintf(w, "ALL\tjaccard %.3f\t%d shared words: %s\n", comparison.Jaccard, comparison.SharedCount, formatWordCounts(comparison.SharedWords))
+	return w.Flush()
 }
 
-func (s *InternalCmd) GetSongsByArtistWithoutBannedWords(ctx *cli.Context) error {
-	query := ctx.String("query")
-	keywords := getKeywords(ctx)
+func formatWordCounts(words []WordCount) string {
+	var output []string
+	for _, word := range words {
+		output = append(output, fmt.Sprintf("%s (%d)", word.Word, word.Count))
+	}
+	return strings.Join(output, ", ")
+}
 
-	songs, err := s.lyricsService.GetSongsByArtist(query)
+// AnalyseArtist prints rhymes, flow, sentiment and repetition of every song, flow of every performer and themes of the artist,
+// with --schemes rhyme schemes of stanzas are printed too, --mood prints only songs of the mood
+func (s *InternalCmd) AnalyseArtist(ctx *cli.Context) error {
+	mood := ctx.String("mood")
+	if err := ValidateMood(mood); err != nil {
+		fmt.Printf("Error while reading mood: %v\n", err)
+		return err
+	}
+
+	themes, err := GetThemes(s.dictionaryService, ctx.StringSlice("theme"))
+	if err != nil {
+		fmt.Printf("Error while getting themes: %v\n", err)
+		return err
+	}
+
+	songs, err := s.lyricsService.GetSongsByArtist(ctx.String("query"))
 	if err != nil {
 		fmt.Printf("Error while getting songs list: %v", err)
 		return err
 	}
 
-	songToWordsMap := make(map[Song]WordsOccurrences)
-	for _, song := range songs {
-		songToWordsMap[song] = song.Lyrics.FindWords()
+	if songs, err = deduplicateSongs(ctx, songs); err != nil {
+		return err
 	}
 
-	songsWithoutBannedWords := make(map[string]struct{})
-	for song, occurrence := range songToWordsMap {
-		keywordExists := false
-		for _, keyword := range keywords {
-			keyword = strings.ReplaceAll(keyword, " ", "")
-			if occurrence[Word(keyword)] > 0 {
-				keywordExists = true
+	analysis := AnalyseArtist(FilterSongsByLanguage(songs, ctx.String("language")), themes)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DENSITY\tSYLLABLES\tEND\tMULTI\tINTERNAL\tSYL/LINE\tVARIANCE\tVALENCE\tMOOD\tEMOTION\tDUPLICATES\tCHORUS\tREPETITIVE\tTITLE")
+	for _, song := range FilterByMood(analysis.Songs, mood) {
+		rhymes, flow, sentiment, repetition := song.Rhymes, song.Flow.Total, song.Sentiment.Total, song.Repetition
+		fmt.Fprintf(w, "%.3f\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t%s\t%s\t%.3f\t%.3f\t%.3f\t%s\n", rhymes.Density, rhymes.Syllables,
+			rhymes.EndRhymes, rhymes.MultisyllabicRhymes, rhymes.InternalRhymes, flow.SyllablesPerLine, flow.Variance, sentiment.Valence,
+			sentiment.Mood, sentiment.DominantEmotion, repetition.DuplicateShare, repetition.ChorusShare, repetition.Repetitiveness,
+			song.Song.Info.Title)
+		if ctx.Bool("schemes") {
+			for _, stanza := range rhymes.Stanzas {
+				fmt.Fprintf(w, "\t\t\t\t\t\t\t\t\t\t\t\t\t  %s %s\n", stanza.Scheme, stanza.Section)
 			}
 		}
-		if keywordExists == false {
-			songsWithoutBannedWords[song.Info.Title] = struct{}{}
+	}
+	summary := analysis.Rhymes
+	fmt.Fprintf(w, "%.3f\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t%s\t%s\t%.3f\t%.3f\t%.3f\tTOTAL (%d songs, average density %.3f)\n",
+		summary.Density, summary.Syllables, summary.EndRhymes, summary.MultisyllabicRhymes, summary.InternalRhymes,
+		analysis.Flow.SyllablesPerLine, analysis.Flow.Variance, analysis.Sentiment.Valence, analysis.Sentiment.Mood,
+		analysis.Sentiment.DominantEmotion, analysis.Repetition.DuplicateShare, analysis.Repetition.ChorusShare,
+		analysis.Repetition.Repetitiveness, summary.Songs, summary.AverageDensity)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nMoods: %d upbeat, %d neutral, %d dark\n", analysis.Moods[MoodUpbeat], analysis.Moods[MoodNeutral], analysis.Moods[MoodDark])
+	if len(summary.CommonSchemes) > 0 {
+		fmt.Println("\nMost common schemes:")
+		for _, scheme := range summary.CommonSchemes {
+			fmt.Printf("%s (%d)\n", scheme.Scheme, scheme.Count)
 		}
 	}
 
-	for k, _ := range songsWithoutBannedWords {
-		fmt.Println(k)
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINES\tSYL/LINE\tVARIANCE\tSYL/VERSE\tPERFORMER")
+	for _, performer := range analysis.Performers {
+		flow := performer.Flow
+		fmt.Fprintf(w, "%d\t%.2f\t%.2f\t%.2f\t%s\n", flow.Lines, flow.SyllablesPerLine, flow.Variance, flow.SyllablesPerVerse, performer.Performer)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(analysis.Themes) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MATCHES\tPER 1000 WORDS\tPROPORTION\tTHEME")
+		for _, theme := range analysis.Themes {
+			fmt.Fprintf(w, "%d\t%.2f\t%.3f\t%s\n", theme.Matches, theme.Share, theme.Proportion, theme.Theme)
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// SyncArtist downloads only new songs of the artist and the ones completed since the last sync, it needs DATABASE_DRIVER
+func (s *InternalCmd) SyncArtist(ctx *cli.Context) error {
+	if s.syncService == nil {
+		return errors.New("sync needs database and network, set DATABASE_DRIVER without OFFLINE and LYRICS_DIR")
+	}
+
+	result, err := s.syncService.SyncArtist(ctx.String("query"))
+	if err != nil {
+		fmt.Printf("Error while syncing artist: %v\n", err)
+		return err
+	}
+
+	if ctx.Bool("verbose") {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANGE\tID\tTITLE")
+		for _, change := range []struct {
+			name  string
+			songs []SyncedSong
+		}{{"added", result.Added}, {"updated", result.Updated}, {"removed", result.Removed}, {"restored", result.Restored}, {"failed", result.Failed}} {
+			for _, song := range change.songs {
+				fmt.Fprintf(w, "%s\t%d\t%s\n", change.name, song.GeniusID, song.Title)
+			}
+		}
+		w.Flush()
+	}
+
+	fmt.Printf("%s synced in %s: %d added, %d updated, %d removed, %d restored, %d unchanged, %d failed\n",
+		result.Artist.Name, result.Duration.Round(time.Millisecond), len(result.Added), len(result.Updated), len(result.Removed),
+		len(result.Restored), result.Unchanged, len(result.Failed))
+	return nil
+}
+
+// ExportArtist dumps songs of the artist with manifest.json to --output directory, or zip archive when it ends with .zip
+func (s *InternalCmd) ExportArtist(ctx *cli.Context) error {
+	format, err := ParseExportFormat(ctx.String("format"))
+	if err != nil {
+		fmt.Printf("Error while reading format: %v\n", err)
+		return err
+	}
+	mode := ctx.String("duplicates")
+	if err := ValidateDuplicatesMode(mode); err != nil {
+		fmt.Printf("Error while reading duplicates mode: %v\n", err)
+		return err
+	}
+	options := ExportOptions{Format: format, WordCounts: ctx.Bool("words"), Deduplicate: mode != ""}
+
+	output := ctx.String("output")
+	var manifest ExportManifest
+	if strings.EqualFold(filepath.Ext(output), ".zip") {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		target := NewZipExportTarget(f)
+		manifest, err = s.exportService.ExportArtist(ctx.String("query"), options, target)
+		if err == nil {
+			err = target.Close()
+		}
+	} else {
+		manifest, err = s.exportService.ExportArtist(ctx.String("query"), options, NewDirExportTarget(output))
+	}
+	if err != nil {
+		fmt.Printf("Error while exporting artist: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("%d songs of %s exported to %s\n", manifest.SongsCount, manifest.Artist, output)
+	return nil
+}
+
+// Import stores songs from export of this or another database, conflicts and invalid songs are printed with --verbose
+func (s *InternalCmd) Import(ctx *cli.Context) error {
+	if s.importService == nil {
+		return errors.New("import needs database, set DATABASE_DRIVER")
+	}
+	path := ctx.Args().First()
+	if path == "" {
+		return errors.New("path of the dump is required, ex. genius-cli import eminem.zip")
+	}
+	policy, err := ParseConflictPolicy(ctx.String("on-conflict"))
+	if err != nil {
+		fmt.Printf("Error while reading conflict policy: %v\n", err)
+		return err
+	}
+
+	result, err := s.importService.Import(path, ImportOptions{OnConflict: policy, DryRun: ctx.Bool("dry-run")})
+	if err != nil {
+		fmt.Printf("Error while importing: %v\n", err)
+		return err
+	}
+
+	if ctx.Bool("verbose") {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROBLEM\tSOURCE\tDETAILS")
+		for _, problem := range result.Invalid {
+			fmt.Fprintf(w, "invalid\t%s\t%s\n", problem.Source, problem.Reason)
+		}
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(w, "conflict\t%s\t%d %s differs from %s, %s\n", conflict.Source, conflict.GeniusID, conflict.Title, conflict.With, conflict.Resolution)
+		}
+		w.Flush()
+	}
+
+	verb := "imported"
+	if ctx.Bool("dry-run") {
+		verb = "would be imported"
+	}
+	fmt.Printf("%d songs %s: %d unchanged, %d duplicates, %d conflicts, %d invalid\n", result.Imported, verb,
+		result.Unchanged, result.Duplicates, len(result.Conflicts), len(result.Invalid))
+	return nil
+}
+
+// Grep finds stored songs of all artists with the word or phrase, every snippet is printed in its own line
+func (s *InternalCmd) Grep(ctx *cli.Context) error {
+	if s.searchService == nil {
+		return errors.New("grep searches stored lyrics, set DATABASE_DRIVER or LYRICS_DIR")
+	}
+	query := strings.Join(ctx.Args().Slice(), " ")
+	if query == "" {
+		return errors.New("word or phrase is required, ex. genius-cli grep \"mom's spaghetti\"")
+	}
+
+	result, err := s.searchService.SearchLyrics(query, ctx.Int("limit"))
+	if err != nil {
+		fmt.Printf("Error while searching lyrics: %v\n", err)
+		return err
+	}
+
+	for _, hit := range result.Hits {
+		for _, snippet := range hit.Snippets {
+			fmt.Printf("%s - %s: %s\n", hit.Song.Artist, hit.Song.Title, snippet)
+		}
 	}
+	fmt.Printf("%d songs found\n", result.Total)
 	return nil
 }
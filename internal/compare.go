@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"sort"
+	"sync"
+)
+
+// GetSongsOfArtists fetches songs of all of the artists at the same time, the number of requests is still limited
+// by the http client shared by the service, the first error is returned
+func GetSongsOfArtists(lyricsService LyricsService, names []string) (map[string][]Song, error) {
+	type artistSongs struct {
+		name  string
+		songs []Song
+		err   error
+	}
+
+	ch := make(chan artistSongs, len(names))
+	wg := sync.WaitGroup{}
+	for _, name := range names {
+		wg.Add(1)
+		name := name
+		go func() {
+			defer wg.Done()
+			songs, err := lyricsService.GetSongsByArtist(name)
+			ch <- artistSongs{name: name, songs: songs, err: err}
+		}()
+	}
+	wg.Wait()
+	close(ch)
+
+	output := make(map[string][]Song)
+	for result := range ch {
+		if result.err != nil {
+			return nil, result.err
+		}
+		output[result.name] = result.songs
+	}
+	return output, nil
+}
+
+type Explicitness struct {
+	// Score is sum of weights of matched words per 1000 words
+	Score            float64 `json:"score"`
+	Matches          int     `json:"matches"`
+	SongsWithMatches int     `json:"songs_with_matches"`
+	// Relative compares the score with the average of compared artists, 1 is average, 2 is twice as explicit
+	Relative float64 `json:"relative"`
+}
+
+type ArtistVocabularyComparison struct {
+	Name              string        `json:"name"`
+	SongsCount        int           `json:"songs_count"`
+	TotalWords        int           `json:"total_words"`
+	UniqueWords       int           `json:"unique_words"`
+	ExclusiveWords    int           `json:"exclusive_words"`
+	TopExclusiveWords []WordCount   `json:"top_exclusive_words"`
+	Explicitness      *Explicitness `json:"explicitness,omitempty"`
+}
+
+type ArtistsSimilarity struct {
+	Artists []string `json:"artists"`
+	Shared  int      `json:"shared"`
+	Jaccard float64  `json:"jaccard"`
+}
+
+type ArtistsComparison struct {
+	Artists []ArtistVocabularyComparison `json:"artists"`
+	// SharedWords are used by all of the artists, the count is the sum of occurrences
+	SharedWords []WordCount         `json:"shared_words"`
+	SharedCount int                 `json:"shared_count"`
+	Jaccard     float64             `json:"jaccard"`
+	Pairs       []ArtistsSimilarity `json:"pairs"`
+}
+
+type CompareOptions struct {
+	StopWords StopWords
+	// Limit of top exclusive and shared words, 0 means all of them
+	Limit      int
+	Dictionary *SongDictionary
+}
+
+// CompareArtists compares vocabularies of the artists, `names` keep the order of artists in the output
+func CompareArtists(artists map[string][]Song, names []string, options CompareOptions) ArtistsComparison {
+	vocabularies := make(map[string]WordsOccurrences)
+	usage := make(map[Word]int)
+	for _, name := range names {
+		vocabularies[name] = ArtistVocabulary(artists[name]).WithoutStopWords(options.StopWords)
+		for word := range vocabularies[name] {
+			usage[word]++
+		}
+	}
+
+	output := ArtistsComparison{Artists: []ArtistVocabularyComparison{}, Pairs: []ArtistsSimilarity{}}
+
+	shared := make(WordsOccurrences)
+	for word, artistsCount := range usage {
+		if artistsCount != len(names) {
+			continue
+		}
+		for _, name := range names {
+			shared[word] += vocabularies[name][word]
+		}
+	}
+	output.SharedCount = len(shared)
+	output.SharedWords = limitWords(shared.Sorted(OrderCountDesc), options.Limit)
+	if len(usage) > 0 {
+		output.Jaccard = float64(len(shared)) / float64(len(usage))
+	}
+
+	for _, name := range names {
+		exclusive := make(WordsOccurrences)
+		for word, count := range vocabularies[name] {
+			if usage[word] == 1 {
+				exclusive[word] = count
+			}
+		}
+
+		comparison := ArtistVocabularyComparison{
+			Name:              name,
+			SongsCount:        len(artists[name]),
+			TotalWords:        vocabularies[name].Total(),
+			UniqueWords:       len(vocabularies[name]),
+			ExclusiveWords:    len(exclusive),
+			TopExclusiveWords: limitWords(exclusive.Sorted(OrderCountDesc), options.Limit),
+		}
+		if options.Dictionary != nil {
+			comparison.Explicitness = explicitness(artists[name], *options.Dictionary)
+		}
+		output.Artists = append(output.Artists, comparison)
+	}
+	relativeExplicitness(output.Artists)
+
+	for i := 0; i < len(names); i++ {
+		for j := i + 1; j < len(names); j++ {
+			output.Pairs = append(output.Pairs, similarity(names[i], names[j], vocabularies[names[i]], vocabularies[names[j]]))
+		}
+	}
+	sort.SliceStable(output.Pairs, func(i, j int) bool {
+		return output.Pairs[i].Jaccard > output.Pairs[j].Jaccard
+	})
+	return output
+}
+
+func limitWords(words []WordCount, limit int) []WordCount {
+	if limit > 0 && len(words) > limit {
+		return words[:limit]
+	}
+	return words
+}
+
+func similarity(firstName, secondName string, first, second WordsOccurrences) ArtistsSimilarity {
+	output := ArtistsSimilarity{Artists: []string{firstName, secondName}}
+	for word := range first {
+		if _, ok := second[word]; ok {
+			output.Shared++
+		}
+	}
+	if union := len(first) + len(second) - output.Shared; union > 0 {
+		output.Jaccard = float64(output.Shared) / float64(union)
+	}
+	return output
+}
+
+// explicitness counts words matching the dictionary of language of each song, all of the words are counted,
+// not only the ones left after removing stop words
+func explicitness(songs []Song, songDictionary SongDictionary) *Explicitness {
+	output := &Explicitness{}
+	weights := 0.0
+	total := 0
+
+	for _, song := range songs {
+		words := song.Lyrics.FindWords()
+		total += words.Total()
+
+		matches := songDictionary.Dictionary(song).FindMatchesIn(words, LanguagesOf(song.Languages))
+		if len(matches) > 0 {
+			output.SongsWithMatches++
+		}
+		for _, match := range matches {
+			output.Matches += match.Occurrences
+			weights += match.Entry.Weight * float64(match.Occurrences)
+		}
+	}
+
+	if total > 0 {
+		output.Score = weights * 1000 / float64(total)
+	}
+	return output
+}
+
+func relativeExplicitness(artists []ArtistVocabularyComparison) {
+	sum := 0.0
+	for _, artist := range artists {
+		if artist.Explicitness == nil {
+			return
+		}
+		sum += artist.Explicitness.Score
+	}
+	if sum == 0 {
+		return
+	}
+
+	average := sum / float64(len(artists))
+	for _, artist := range artists {
+		artist.Explicitness.Relative = artist.Explicitness.Score / average
+	}
+}
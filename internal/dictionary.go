@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"strings"
+)
+
+type MatchMode string
+
+const (
+	MatchExact    MatchMode = "exact"
+	MatchPrefix   MatchMode = "prefix"
+	MatchSuffix   MatchMode = "suffix"
+	MatchContains MatchMode = "contains"
+	// MatchStem compares stems of words, the stemmer is picked by the language of the song
+	MatchStem MatchMode = "stem"
+)
+
+var matchModes = []MatchMode{MatchExact, MatchPrefix, MatchSuffix, MatchContains, MatchStem}
+
+func ParseMatchMode(s string) (MatchMode, error) {
+	if s == "" {
+		return MatchExact, nil
+	}
+
+	for _, mode := range matchModes {
+		if string(mode) == strings.ToLower(s) {
+			return mode, nil
+		}
+	}
+	return "", UnknownMatchModeError
+}
+
+const defaultEntryWeight = 1
+
+type DictionaryEntry struct {
+	Word     Word      `json:"word" yaml:"word"`
+	Weight   float64   `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Category string    `json:"category,omitempty" yaml:"category,omitempty"`
+	Match    MatchMode `json:"match,omitempty" yaml:"match,omitempty"`
+}
+
+func normaliseWord(word Word) string {
+	return strings.ToLower(string(word.TrimSpecials()))
+}
+
+// Matches compares the entry with a single word from lyrics, the polish special characters are ignored
+// on both sides, so it behaves the same as WordsOccurrences.ContainsOneOfWords
+func (e DictionaryEntry) Matches(word Word) bool {
+	return e.MatchesIn(word, nil)
+}
+
+// MatchesIn compares the entry with a word of lyrics written in one of the languages, the languages matter only
+// for MatchStem, when they are empty all of the stemmers are tried
+func (e DictionaryEntry) MatchesIn(word Word, languages []string) bool {
+	entryWord := normaliseWord(e.Word)
+	lyricsWord := normaliseWord(word)
+
+	switch e.Match {
+	case MatchStem:
+		if len(languages) == 0 {
+			languages = StemLanguages()
+		}
+		for _, language := range languages {
+			if normaliseWord(Stem(word, language)) == normaliseWord(Stem(e.Word, language)) {
+				return true
+			}
+		}
+		return false
+	case MatchPrefix:
+		return strings.HasPrefix(lyricsWord, entryWord)
+	case MatchSuffix:
+		return strings.HasSuffix(lyricsWord, entryWord)
+	case MatchContains:
+		return strings.Contains(lyricsWord, entryWord)
+	default:
+		return lyricsWord == entryWord
+	}
+}
+
+// Dictionary may be defined in terms of other dictionaries, Extends lists base dictionaries which entries are
+// overridden by own Entries, Compose is set expression ex. "en/profanity ∪ en/drugs − allow/biblical"
+type Dictionary struct {
+	Name    string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Extends []string          `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Compose string            `json:"compose,omitempty" yaml:"compose,omitempty"`
+	Entries []DictionaryEntry `json:"entries" yaml:"entries"`
+}
+
+func NewDictionaryFromWords(name string, words []string) Dictionary {
+	dictionary := Dictionary{Name: name}
+	for _, word := range words {
+		word = strings.ReplaceAll(word, " ", "")
+		if word == "" {
+			continue
+		}
+		dictionary.Entries = append(dictionary.Entries, DictionaryEntry{
+			Word:   Word(strings.ToLower(word)),
+			Weight: defaultEntryWeight,
+			Match:  MatchExact,
+		})
+	}
+	return dictionary
+}
+
+func (d Dictionary) IsComposed() bool {
+	return len(d.Extends) > 0 || d.Compose != ""
+}
+
+func (d Dictionary) IsEmpty() bool {
+	return len(d.Entries) == 0
+}
+
+func (d Dictionary) Words() []Word {
+	var words []Word
+	for _, entry := range d.Entries {
+		words = append(words, entry.Word)
+	}
+	return words
+}
+
+// Merge returns dictionary with entries of both dictionaries, entries from `other` replace the ones with the same word
+func (d Dictionary) Merge(other Dictionary) Dictionary {
+	output := Dictionary{Name: d.Name}
+	positions := make(map[Word]int)
+
+	for _, entry := range append(append([]DictionaryEntry{}, d.Entries...), other.Entries...) {
+		if i, ok := positions[entry.Word]; ok {
+			output.Entries[i] = entry
+			continue
+		}
+		positions[entry.Word] = len(output.Entries)
+		output.Entries = append(output.Entries, entry)
+	}
+	return output
+}
+
+func (d Dictionary) Contains(word Word) bool {
+	for _, entry := range d.Entries {
+		if entry.Word == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Difference returns entries which words aren't present in `other`
+func (d Dictionary) Difference(other Dictionary) Dictionary {
+	output := Dictionary{Name: d.Name}
+	for _, entry := range d.Entries {
+		if other.Contains(entry.Word) == false {
+			output.Entries = append(output.Entries, entry)
+		}
+	}
+	return output
+}
+
+func (d Dictionary) Intersection(other Dictionary) Dictionary {
+	output := Dictionary{Name: d.Name}
+	for _, entry := range d.Entries {
+		if other.Contains(entry.Word) {
+			output.Entries = append(output.Entries, entry)
+		}
+	}
+	return output
+}
+
+type DictionaryMatch struct {
+	Entry       DictionaryEntry `json:"entry"`
+	Word        Word            `json:"word"`
+	Occurrences int             `json:"occurrences"`
+}
+
+func (d Dictionary) FindMatches(occurrences WordsOccurrences) []DictionaryMatch {
+	return d.FindMatchesIn(occurrences, nil)
+}
+
+// FindMatchesIn is FindMatches for lyrics written in the languages, see DictionaryEntry.MatchesIn
+func (d Dictionary) FindMatchesIn(occurrences WordsOccurrences, languages []string) []DictionaryMatch {
+	var matches []DictionaryMatch
+	for word, count := range occurrences {
+		if count <= 0 {
+			continue
+		}
+		for _, entry := range d.Entries {
+			if entry.MatchesIn(word, languages) {
+				matches = append(matches, DictionaryMatch{Entry: entry, Word: word, Occurrences: count})
+			}
+		}
+	}
+	return matches
+}
+
+func (d Dictionary) MatchesAny(occurrences WordsOccurrences) bool {
+	return d.MatchesAnyIn(occurrences, nil)
+}
+
+func (d Dictionary) MatchesAnyIn(occurrences WordsOccurrences, languages []string) bool {
+	for word, count := range occurrences {
+		if count <= 0 {
+			continue
+		}
+		for _, entry := range d.Entries {
+			if entry.MatchesIn(word, languages) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,537 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type DictionaryFormat string
+
+const (
+	DictionaryText DictionaryFormat = "txt"
+	DictionaryCSV  DictionaryFormat = "csv"
+	DictionaryJSON DictionaryFormat = "json"
+	DictionaryYAML DictionaryFormat = "yaml"
+)
+
+var (
+	UnknownDictionaryFormatError = errors.New("unknown dictionary format")
+	UnknownMatchModeError        = errors.New("unknown match mode")
+	InvalidWeightError           = errors.New("invalid weight")
+	EmptyWordError               = errors.New("empty word")
+	UnknownDirectiveError        = errors.New("unknown directive")
+)
+
+const (
+	commentPrefix   = "#"
+	directivePrefix = "@"
+)
+
+// applyDirective reads lines such as "@extends en/base" or "@compose a ∪ b" used by plain text and CSV formats
+func applyDirective(dictionary *Dictionary, line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, directivePrefix))
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: %q", UnknownDirectiveError, line)
+	}
+
+	arguments := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, directivePrefix), fields[0]))
+	switch strings.ToLower(fields[0]) {
+	case "extends":
+		for _, name := range strings.FieldsFunc(arguments, func(r rune) bool { return r == ',' || r == ' ' }) {
+			dictionary.Extends = append(dictionary.Extends, name)
+		}
+	case "compose":
+		dictionary.Compose = arguments
+	default:
+		return fmt.Errorf("%w: %q", UnknownDirectiveError, fields[0])
+	}
+	return nil
+}
+
+func directiveLines(dictionary Dictionary) []string {
+	var lines []string
+	if len(dictionary.Extends) > 0 {
+		lines = append(lines, fmt.Sprintf("%sextends %s", directivePrefix, strings.Join(dictionary.Extends, " ")))
+	}
+	if dictionary.Compose != "" {
+		lines = append(lines, fmt.Sprintf("%scompose %s", directivePrefix, dictionary.Compose))
+	}
+	return lines
+}
+
+func ParseDictionaryFormat(s string) (DictionaryFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), ".") {
+	case "", "txt", "text":
+		return DictionaryText, nil
+	case "csv":
+		return DictionaryCSV, nil
+	case "json":
+		return DictionaryJSON, nil
+	case "yaml", "yml":
+		return DictionaryYAML, nil
+	}
+	return "", fmt.Errorf("%w: %q", UnknownDictionaryFormatError, s)
+}
+
+// DictionaryFormatFromPath guesses format by the file extension, unknown extensions are treated as plain text
+func DictionaryFormatFromPath(path string) DictionaryFormat {
+	format, err := ParseDictionaryFormat(filepath.Ext(path))
+	if err != nil {
+		return DictionaryText
+	}
+	return format
+}
+
+// DictionaryError points to the place in dictionary file which could not be read, Line is 0 when it's unknown
+type DictionaryError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *DictionaryError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "dictionary"
+	}
+
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", path, e.Err)
+}
+
+func (e *DictionaryError) Unwrap() error {
+	return e.Err
+}
+
+func LoadDictionaryFile(path string) (Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Dictionary{}, &DictionaryError{Path: path, Err: err}
+	}
+	defer f.Close()
+
+	dictionary, err := ReadDictionary(f, DictionaryFormatFromPath(path))
+	if err != nil {
+		var dictErr *DictionaryError
+		if errors.As(err, &dictErr) {
+			dictErr.Path = path
+			return Dictionary{}, dictErr
+		}
+		return Dictionary{}, &DictionaryError{Path: path, Err: err}
+	}
+
+	if dictionary.Name == "" {
+		dictionary.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return dictionary, nil
+}
+
+func SaveDictionaryFile(path string, dictionary Dictionary, format DictionaryFormat) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteDictionary(f, dictionary, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func ReadDictionary(r io.Reader, format DictionaryFormat) (Dictionary, error) {
+	switch format {
+	case DictionaryText:
+		return readTextDictionary(r)
+	case DictionaryCSV:
+		return readCSVDictionary(r)
+	case DictionaryJSON:
+		return readJSONDictionary(r)
+	case DictionaryYAML:
+		return readYAMLDictionary(r)
+	}
+	return Dictionary{}, fmt.Errorf("%w: %q", UnknownDictionaryFormatError, format)
+}
+
+func WriteDictionary(w io.Writer, dictionary Dictionary, format DictionaryFormat) error {
+	switch format {
+	case DictionaryText:
+		return writeTextDictionary(w, dictionary)
+	case DictionaryCSV:
+		return writeCSVDictionary(w, dictionary)
+	case DictionaryJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(dictionary)
+	case DictionaryYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(dictionary); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("%w: %q", UnknownDictionaryFormatError, format)
+}
+
+// normaliseEntry validates the entry and fills defaults, so every format produces the same entries
+func normaliseEntry(entry DictionaryEntry) (DictionaryEntry, error) {
+	entry.Word = Word(strings.ToLower(strings.TrimSpace(string(entry.Word))))
+	if entry.Word == "" {
+		return entry, EmptyWordError
+	}
+
+	mode, err := ParseMatchMode(string(entry.Match))
+	if err != nil {
+		return entry, fmt.Errorf("%w: %q", err, entry.Match)
+	}
+	entry.Match = mode
+
+	if entry.Weight < 0 {
+		return entry, fmt.Errorf("%w: %v", InvalidWeightError, entry.Weight)
+	}
+	if entry.Weight == 0 {
+		entry.Weight = defaultEntryWeight
+	}
+	return entry, nil
+}
+
+// parseTextEntry reads entry written with wildcards, "word*" is prefix, "*word" is suffix and "*word*" means contains,
+// "~word" matches all forms of the word
+func parseTextEntry(token string) DictionaryEntry {
+	if strings.HasPrefix(token, "~") && len(token) > 1 {
+		return DictionaryEntry{Word: Word(strings.TrimPrefix(token, "~")), Match: MatchStem}
+	}
+
+	startsWithWildcard := strings.HasPrefix(token, "*")
+	endsWithWildcard := strings.HasSuffix(token, "*") && len(token) > 1
+
+	mode := MatchExact
+	switch {
+	case startsWithWildcard && endsWithWildcard:
+		mode = MatchContains
+	case endsWithWildcard:
+		mode = MatchPrefix
+	case startsWithWildcard:
+		mode = MatchSuffix
+	}
+
+	return DictionaryEntry{Word: Word(strings.Trim(token, "*")), Match: mode}
+}
+
+func formatTextEntry(entry DictionaryEntry) string {
+	switch entry.Match {
+	case MatchPrefix:
+		return string(entry.Word) + "*"
+	case MatchSuffix:
+		return "*" + string(entry.Word)
+	case MatchContains:
+		return "*" + string(entry.Word) + "*"
+	case MatchStem:
+		return "~" + string(entry.Word)
+	}
+	return string(entry.Word)
+}
+
+func readTextDictionary(r io.Reader) (Dictionary, error) {
+	var dictionary Dictionary
+
+	sc := bufio.NewScanner(r)
+	lineNumber := 0
+	for sc.Scan() {
+		lineNumber++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, commentPrefix) {
+			continue
+		}
+
+		if strings.HasPrefix(text, directivePrefix) {
+			if err := applyDirective(&dictionary, text); err != nil {
+				return Dictionary{}, &DictionaryError{Line: lineNumber, Err: err}
+			}
+			continue
+		}
+
+	WORDS:
+		for _, token := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if strings.HasPrefix(token, commentPrefix) {
+				break WORDS
+			}
+
+			entry, err := normaliseEntry(parseTextEntry(token))
+			if err != nil {
+				return Dictionary{}, &DictionaryError{Line: lineNumber, Err: err}
+			}
+			dictionary.Entries = append(dictionary.Entries, entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Dictionary{}, &DictionaryError{Line: lineNumber, Err: err}
+	}
+	return dictionary, nil
+}
+
+func writeTextDictionary(w io.Writer, dictionary Dictionary) error {
+	buf := bufio.NewWriter(w)
+	if dictionary.Name != "" {
+		fmt.Fprintf(buf, "%s %s\n", commentPrefix, dictionary.Name)
+	}
+	for _, line := range directiveLines(dictionary) {
+		fmt.Fprintln(buf, line)
+	}
+	for _, entry := range dictionary.Entries {
+		fmt.Fprintln(buf, formatTextEntry(entry))
+	}
+	return buf.Flush()
+}
+
+var csvColumns = []string{"word", "weight", "category", "match"}
+
+func readCSVDictionary(r io.Reader) (Dictionary, error) {
+	var dictionary Dictionary
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := csvColumns
+	firstRecord := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return Dictionary{}, &DictionaryError{Line: parseErr.Line, Err: parseErr.Err}
+			}
+			return Dictionary{}, &DictionaryError{Err: err}
+		}
+		line, _ := reader.FieldPos(0)
+
+		if strings.HasPrefix(strings.TrimSpace(record[0]), directivePrefix) {
+			if err := applyDirective(&dictionary, strings.Join(record, ",")); err != nil {
+				return Dictionary{}, &DictionaryError{Line: line, Err: err}
+			}
+			continue
+		}
+
+		if firstRecord {
+			firstRecord = false
+			if strings.EqualFold(strings.TrimSpace(record[0]), "word") {
+				columns = nil
+				for _, column := range record {
+					columns = append(columns, strings.ToLower(strings.TrimSpace(column)))
+				}
+				continue
+			}
+		}
+
+		entry := DictionaryEntry{}
+		for i, value := range record {
+			if i >= len(columns) {
+				return Dictionary{}, &DictionaryError{Line: line, Err: fmt.Errorf("too many fields, expected at most %d", len(columns))}
+			}
+
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "word":
+				entry.Word = Word(value)
+			case "weight":
+				if value == "" {
+					continue
+				}
+				weight, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return Dictionary{}, &DictionaryError{Line: line, Err: fmt.Errorf("%w: %q", InvalidWeightError, value)}
+				}
+				entry.Weight = weight
+			case "category":
+				entry.Category = value
+			case "match":
+				entry.Match = MatchMode(value)
+			default:
+				return Dictionary{}, &DictionaryError{Line: line, Err: fmt.Errorf("unknown column %q", columns[i])}
+			}
+		}
+
+		entry, err = normaliseEntry(entry)
+		if err != nil {
+			return Dictionary{}, &DictionaryError{Line: line, Err: err}
+		}
+		dictionary.Entries = append(dictionary.Entries, entry)
+	}
+	return dictionary, nil
+}
+
+func writeCSVDictionary(w io.Writer, dictionary Dictionary) error {
+	writer := csv.NewWriter(w)
+	for _, line := range directiveLines(dictionary) {
+		if err := writer.Write([]string{line}); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	for _, entry := range dictionary.Entries {
+		err := writer.Write([]string{
+			string(entry.Word),
+			strconv.FormatFloat(entry.Weight, 'f', -1, 64),
+			entry.Category,
+			string(entry.Match),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// lineAt converts byte offset into 1-based line number, whitespace and commas are skipped so the offset points to a value
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// jsonEntriesOffsets returns offsets of every element of top-level "entries" array
+func jsonEntriesOffsets(data []byte) []int64 {
+	var offsets []int64
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return offsets
+		}
+
+		if key != "entries" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return offsets
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return offsets
+		}
+		for decoder.More() {
+			offsets = append(offsets, decoder.InputOffset())
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return offsets
+			}
+		}
+		return offsets
+	}
+	return offsets
+}
+
+func (e *DictionaryEntry) UnmarshalJSON(data []byte) error {
+	var word string
+	if err := json.Unmarshal(data, &word); err == nil {
+		*e = parseTextEntry(word)
+		return nil
+	}
+
+	type plainEntry DictionaryEntry
+	return json.Unmarshal(data, (*plainEntry)(e))
+}
+
+func readJSONDictionary(r io.Reader) (Dictionary, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Dictionary{}, &DictionaryError{Err: err}
+	}
+
+	var dictionary Dictionary
+	if err := json.Unmarshal(data, &dictionary); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return Dictionary{}, &DictionaryError{Line: lineAt(data, syntaxErr.Offset-1), Err: err}
+		case errors.As(err, &typeErr):
+			return Dictionary{}, &DictionaryError{Line: lineAt(data, typeErr.Offset-1), Err: err}
+		}
+		return Dictionary{}, &DictionaryError{Err: err}
+	}
+
+	for i, entry := range dictionary.Entries {
+		entry, err := normaliseEntry(entry)
+		if err != nil {
+			dictErr := &DictionaryError{Err: fmt.Errorf("entries[%d]: %w", i, err)}
+			if offsets := jsonEntriesOffsets(data); i < len(offsets) {
+				dictErr.Line = lineAt(data, offsets[i])
+			}
+			return Dictionary{}, dictErr
+		}
+		dictionary.Entries[i] = entry
+	}
+	return dictionary, nil
+}
+
+func (e *DictionaryEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*e = parseTextEntry(node.Value)
+		return nil
+	}
+
+	type plainEntry DictionaryEntry
+	return node.Decode((*plainEntry)(e))
+}
+
+func readYAMLDictionary(r io.Reader) (Dictionary, error) {
+	var document struct {
+		Name    string      `yaml:"name"`
+		Extends []string    `yaml:"extends"`
+		Compose string      `yaml:"compose"`
+		Entries []yaml.Node `yaml:"entries"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&document); err != nil && err != io.EOF {
+		return Dictionary{}, &DictionaryError{Err: err}
+	}
+
+	dictionary := Dictionary{Name: document.Name, Extends: document.Extends, Compose: document.Compose}
+	for i := range document.Entries {
+		node := &document.Entries[i]
+
+		var entry DictionaryEntry
+		err := node.Decode(&entry)
+		if err == nil {
+			entry, err = normaliseEntry(entry)
+		}
+		if err != nil {
+			return Dictionary{}, &DictionaryError{Line: node.Line, Err: err}
+		}
+		dictionary.Entries = append(dictionary.Entries, entry)
+	}
+	return dictionary, nil
+}
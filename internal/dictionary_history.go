@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var DictionaryVersionNotFoundError = errors.New("dictionary version not found")
+
+// DictionaryVersion is immutable snapshot of a dictionary, Dictionary keeps expanded entries,
+// so filter decisions can be reproduced even when referenced dictionaries changed later
+type DictionaryVersion struct {
+	Name       string         `json:"name"`
+	Version    int            `json:"version"`
+	Author     string         `json:"author"`
+	Cause      string         `json:"cause,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Definition Dictionary     `json:"definition"`
+	Dictionary Dictionary     `json:"dictionary"`
+	Diff       DictionaryDiff `json:"diff"`
+}
+
+type DictionaryEntryChange struct {
+	Before DictionaryEntry `json:"before"`
+	After  DictionaryEntry `json:"after"`
+}
+
+type DictionaryDiff struct {
+	Added   []DictionaryEntry       `json:"added"`
+	Removed []DictionaryEntry       `json:"removed"`
+	Changed []DictionaryEntryChange `json:"changed"`
+}
+
+func (d DictionaryDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func DiffDictionaries(before, after Dictionary) DictionaryDiff {
+	diff := DictionaryDiff{
+		Added:   []DictionaryEntry{},
+		Removed: []DictionaryEntry{},
+		Changed: []DictionaryEntryChange{},
+	}
+
+	beforeEntries := make(map[Word]DictionaryEntry)
+	for _, entry := range before.Entries {
+		beforeEntries[entry.Word] = entry
+	}
+
+	afterEntries := make(map[Word]DictionaryEntry)
+	for _, entry := range after.Entries {
+		afterEntries[entry.Word] = entry
+
+		previous, ok := beforeEntries[entry.Word]
+		if ok == false {
+			diff.Added = append(diff.Added, entry)
+		} else if previous != entry {
+			diff.Changed = append(diff.Changed, DictionaryEntryChange{Before: previous, After: entry})
+		}
+	}
+
+	for _, entry := range before.Entries {
+		if _, ok := afterEntries[entry.Word]; ok == false {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+	return diff
+}
+
+func sameDictionaries(a, b Dictionary) bool {
+	first, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	second, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(first) == string(second)
+}
+
+// DictionaryHistory stores versions of dictionaries, versions are never modified after Append
+type DictionaryHistory interface {
+	Names() ([]string, error)
+	Versions(name string) ([]DictionaryVersion, error)
+	// Append records all of the versions or none of them
+	Append(versions ...DictionaryVersion) error
+}
+
+var _ DictionaryHistory = &MemoryDictionaryHistory{}
+
+type MemoryDictionaryHistory struct {
+	mu       sync.RWMutex
+	versions map[string][]DictionaryVersion
+}
+
+func NewMemoryDictionaryHistory() *MemoryDictionaryHistory {
+	return &MemoryDictionaryHistory{versions: make(map[string][]DictionaryVersion)}
+}
+
+func (h *MemoryDictionaryHistory) Names() ([]string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var names []string
+	for name := range h.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (h *MemoryDictionaryHistory) Versions(name string) ([]DictionaryVersion, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]DictionaryVersion{}, h.versions[name]...), nil
+}
+
+func (h *MemoryDictionaryHistory) Append(versions ...DictionaryVersion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, version := range versions {
+		h.versions[version.Name] = append(h.versions[version.Name], version)
+	}
+	return nil
+}
+
+var _ DictionaryHistory = &FileDictionaryHistory{}
+
+// FileDictionaryHistory keeps all versions of one dictionary in "<dir>/<name>.json" file
+type FileDictionaryHistory struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileDictionaryHistory(dir string) *FileDictionaryHistory {
+	return &FileDictionaryHistory{dir: dir}
+}
+
+func (h *FileDictionaryHistory) path(name string) string {
+	return filepath.Join(h.dir, filepath.FromSlash(name)+".json")
+}
+
+func (h *FileDictionaryHistory) Names() ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var names []string
+	err := filepath.WalkDir(h.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(h.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(strings.TrimSuffix(relativePath, ".json")))
+		return nil
+	})
+	if err != nil && errors.Is(err, os.ErrNotExist) == false {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (h *FileDictionaryHistory) Versions(name string) ([]DictionaryVersion, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.read(name)
+}
+
+func (h *FileDictionaryHistory) read(name string) ([]DictionaryVersion, error) {
+	by, err := os.ReadFile(h.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []DictionaryVersion
+	if err := json.Unmarshal(by, &versions); err != nil {
+		return nil, fmt.Errorf("reading history of %q: %w", name, err)
+	}
+	return versions, nil
+}
+
+func (h *FileDictionaryHistory) Append(versions ...DictionaryVersion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	byName := make(map[string][]DictionaryVersion)
+	var names []string
+	for _, version := range versions {
+		if _, ok := byName[version.Name]; ok == false {
+			stored, err := h.read(version.Name)
+			if err != nil {
+				return err
+			}
+			byName[version.Name] = stored
+			names = append(names, version.Name)
+		}
+		byName[version.Name] = append(byName[version.Name], version)
+	}
+
+	// All of the files are written aside first and replaced only when every one of them is ready, the files which have
+	// been replaced already are restored when replacing of the next one fails
+	var tmpPaths []string
+	removeTmp := func() {
+		for _, tmpPath := range tmpPaths {
+			os.Remove(tmpPath)
+		}
+	}
+	for _, name := range names {
+		by, err := json.MarshalIndent(byName[name], "", "  ")
+		if err != nil {
+			removeTmp()
+			return err
+		}
+
+		path := h.path(name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			removeTmp()
+			return err
+		}
+		tmpPaths = append(tmpPaths, path+".tmp")
+		if err := os.WriteFile(path+".tmp", by, 0644); err != nil {
+			removeTmp()
+			return err
+		}
+	}
+
+	previous := make([][]byte, len(names))
+	for i, name := range names {
+		by, err := os.ReadFile(h.path(name))
+		if err != nil && errors.Is(err, os.ErrNotExist) == false {
+			removeTmp()
+			return err
+		}
+		previous[i] = by
+	}
+
+	for i, name := range names {
+		if err := os.Rename(tmpPaths[i], h.path(name)); err != nil {
+			removeTmp()
+			h.restore(names[:i], previous)
+			return err
+		}
+	}
+	return nil
+}
+
+// restore brings back the previous files of histories, nil means that the history hasn't existed
+func (h *FileDictionaryHistory) restore(names []string, previous [][]byte) {
+	for i, name := range names {
+		path := h.path(name)
+		if previous[i] == nil {
+			os.Remove(path)
+			continue
+		}
+		if err := os.WriteFile(path+".tmp", previous[i], 0644); err == nil {
+			os.Rename(path+".tmp", path)
+		}
+	}
+}
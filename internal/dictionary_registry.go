@@ -0,0 +1,352 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	DictionaryNotFoundError    = errors.New("dictionary not found")
+	DuplicatedDictionaryError  = errors.New("dictionary defined more than once")
+	InvalidComposeError        = errors.New("invalid compose expression")
+	dictionaryFilesExtensions  = []string{".txt", ".csv", ".json", ".yaml", ".yml"}
+	composeUnionOperators      = []string{"∪", "+", "|"}
+	composeDifferenceOperators = []string{"−", "-", "\\"}
+	composeIntersectOperators  = []string{"∩", "&"}
+)
+
+type DictionaryCycleError struct {
+	Cycle []string
+}
+
+func (e *DictionaryCycleError) Error() string {
+	return fmt.Sprintf("dictionary cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// DictionaryRegistry keeps dictionaries by names, every composed dictionary is resolved once while registry is created,
+// so cycles and missing references are reported at load time instead of while filtering songs
+type DictionaryRegistry struct {
+	definitions map[string]Dictionary
+	resolved    map[string]Dictionary
+}
+
+func NewDictionaryRegistry(definitions ...Dictionary) (*DictionaryRegistry, error) {
+	r := &DictionaryRegistry{
+		definitions: make(map[string]Dictionary),
+		resolved:    make(map[string]Dictionary),
+	}
+
+	for _, definition := range definitions {
+		if _, ok := r.definitions[definition.Name]; ok {
+			return nil, fmt.Errorf("%w: %q", DuplicatedDictionaryError, definition.Name)
+		}
+		r.definitions[definition.Name] = definition
+	}
+
+	for _, name := range r.Names() {
+		if _, err := r.resolve(name, nil); err != nil {
+			return nil, fmt.Errorf("resolving %q: %w", name, err)
+		}
+	}
+	return r, nil
+}
+
+// LoadDictionaryRegistry reads every dictionary file from the directory, the name of dictionary is its path
+// relative to the directory without extension, ex. "en/profanity" for "dir/en/profanity.yaml"
+func LoadDictionaryRegistry(dir string) (*DictionaryRegistry, error) {
+	var definitions []Dictionary
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || isDictionaryFile(path) == false {
+			return nil
+		}
+
+		definition, err := LoadDictionaryFile(path)
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		definition.Name = filepath.ToSlash(strings.TrimSuffix(relativePath, filepath.Ext(relativePath)))
+
+		definitions = append(definitions, definition)
+		return nil
+	})
+	if err != nil && errors.Is(err, os.ErrNotExist) == false {
+		return nil, err
+	}
+
+	return NewDictionaryRegistry(definitions...)
+}
+
+func isDictionaryFile(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	for _, v := range dictionaryFilesExtensions {
+		if v == extension {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *DictionaryRegistry) Names() []string {
+	var names []string
+	for name := range r.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *DictionaryRegistry) Definitions() []Dictionary {
+	var definitions []Dictionary
+	for _, name := range r.Names() {
+		definitions = append(definitions, r.definitions[name])
+	}
+	return definitions
+}
+
+// Get returns dictionary with all of the references expanded
+func (r *DictionaryRegistry) Get(name string) (Dictionary, error) {
+	dictionary, ok := r.resolved[name]
+	if ok == false {
+		return Dictionary{}, fmt.Errorf("%w: %q", DictionaryNotFoundError, name)
+	}
+	return dictionary, nil
+}
+
+// Definition returns dictionary the way it was written, without resolving references
+func (r *DictionaryRegistry) Definition(name string) (Dictionary, error) {
+	dictionary, ok := r.definitions[name]
+	if ok == false {
+		return Dictionary{}, fmt.Errorf("%w: %q", DictionaryNotFoundError, name)
+	}
+	return dictionary, nil
+}
+
+func (r *DictionaryRegistry) resolve(name string, path []string) (Dictionary, error) {
+	if dictionary, ok := r.resolved[name]; ok {
+		return dictionary, nil
+	}
+
+	for i, visited := range path {
+		if visited == name {
+			return Dictionary{}, &DictionaryCycleError{Cycle: append(append([]string{}, path[i:]...), name)}
+		}
+	}
+	path = append(path, name)
+
+	definition, ok := r.definitions[name]
+	if ok == false {
+		return Dictionary{}, fmt.Errorf("%w: %q", DictionaryNotFoundError, name)
+	}
+
+	output := Dictionary{Name: name}
+	for _, base := range definition.Extends {
+		dictionary, err := r.resolve(base, path)
+		if err != nil {
+			return Dictionary{}, err
+		}
+		output = output.Merge(dictionary)
+	}
+
+	if definition.Compose != "" {
+		expression, err := parseComposeExpression(definition.Compose)
+		if err != nil {
+			return Dictionary{}, fmt.Errorf("%s: %w", name, err)
+		}
+
+		dictionary, err := expression.evaluate(func(reference string) (Dictionary, error) {
+			return r.resolve(reference, path)
+		})
+		if err != nil {
+			return Dictionary{}, err
+		}
+		output = output.Merge(dictionary)
+	}
+
+	output = output.Merge(Dictionary{Entries: definition.Entries})
+	output.Name = name
+
+	r.resolved[name] = output
+	return output, nil
+}
+
+type composeExpression interface {
+	evaluate(resolve func(name string) (Dictionary, error)) (Dictionary, error)
+}
+
+type composeReference string
+
+func (e composeReference) evaluate(resolve func(name string) (Dictionary, error)) (Dictionary, error) {
+	return resolve(string(e))
+}
+
+type composeOperation struct {
+	operator    string
+	left, right composeExpression
+}
+
+func (e composeOperation) evaluate(resolve func(name string) (Dictionary, error)) (Dictionary, error) {
+	left, err := e.left.evaluate(resolve)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	right, err := e.right.evaluate(resolve)
+	if err != nil {
+		return Dictionary{}, err
+	}
+
+	switch {
+	case isOneOf(e.operator, composeUnionOperators):
+		return left.Merge(right), nil
+	case isOneOf(e.operator, composeDifferenceOperators):
+		return left.Difference(right), nil
+	}
+	return left.Intersection(right), nil
+}
+
+func isOneOf(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeCompose splits expression into names, operators and parentheses, the ascii operators have to be
+// surrounded by spaces, so names like "en/hate-speech" are still valid
+func tokenizeCompose(expression string) []string {
+	var tokens []string
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, char := range expression {
+		switch {
+		case unicode.IsSpace(char):
+			flush()
+		case strings.ContainsRune("∪−∩()", char):
+			flush()
+			tokens = append(tokens, string(char))
+		default:
+			current.WriteRune(char)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type composeParser struct {
+	tokens   []string
+	position int
+}
+
+func parseComposeExpression(expression string) (composeExpression, error) {
+	p := &composeParser{tokens: tokenizeCompose(expression)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", InvalidComposeError)
+	}
+
+	output, err := p.parseUnion()
+	if err != nil {
+		return nil, err
+	}
+	if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", InvalidComposeError, p.tokens[p.position])
+	}
+	return output, nil
+}
+
+func (p *composeParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *composeParser) parseUnion() (composeExpression, error) {
+	left, err := p.parseIntersection()
+	if err != nil {
+		return nil, err
+	}
+
+	for isOneOf(p.peek(), composeUnionOperators) || isOneOf(p.peek(), composeDifferenceOperators) {
+		operator := p.peek()
+		p.position++
+
+		right, err := p.parseIntersection()
+		if err != nil {
+			return nil, err
+		}
+		left = composeOperation{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *composeParser) parseIntersection() (composeExpression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for isOneOf(p.peek(), composeIntersectOperators) {
+		operator := p.peek()
+		p.position++
+
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = composeOperation{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *composeParser) parseOperand() (composeExpression, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", InvalidComposeError)
+	case token == "(":
+		p.position++
+		output, err := p.parseUnion()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing \")\"", InvalidComposeError)
+		}
+		p.position++
+		return output, nil
+	case token == ")" || isOneOf(token, composeUnionOperators) || isOneOf(token, composeDifferenceOperators) || isOneOf(token, composeIntersectOperators):
+		return nil, fmt.Errorf("%w: unexpected %q", InvalidComposeError, token)
+	}
+
+	p.position++
+	return composeReference(token), nil
+}
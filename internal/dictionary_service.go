@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileDictionaryAuthor is the author of versions created because a file in DICTIONARIES_DIR has been changed
+const FileDictionaryAuthor = "filesystem"
+
+var (
+	InvalidDictionaryNameError = errors.New("invalid dictionary name")
+	dictionaryNameRegexp       = regexp.MustCompile(`^[\p{L}0-9_.\-]+(/[\p{L}0-9_.\-]+)*$`)
+	reservedDictionaryNames    = []string{"versions", "diff"}
+)
+
+type DictionaryService interface {
+	GetDictionariesNames() []string
+	GetDictionary(name string) (Dictionary, error)
+	GetDictionaryDefinition(name string) (Dictionary, error)
+	GetDictionaryVersions(name string) ([]DictionaryVersion, error)
+	GetDictionaryVersion(name string, version int) (DictionaryVersion, error)
+	DiffDictionaryVersions(name string, from int, to int) (DictionaryDiff, error)
+	SaveDictionary(definition Dictionary, author string) (DictionaryVersion, error)
+}
+
+var _ DictionaryService = &InternalDictionaryService{}
+
+type InternalDictionaryService struct {
+	mu       sync.RWMutex
+	registry *DictionaryRegistry
+	history  DictionaryHistory
+	logger   *log.Entry
+	cfg      *config.Config
+}
+
+// NewDictionaryService combines dictionaries from files with the ones registered earlier through the service,
+// every dictionary which differs from its latest version gets a new version
+func NewDictionaryService(cfg *config.Config, registry *DictionaryRegistry, history DictionaryHistory, logger *log.Entry) (*InternalDictionaryService, error) {
+	s := &InternalDictionaryService{history: history, logger: logger, cfg: cfg}
+
+	definitions, err := s.mergeWithHistory(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err = NewDictionaryRegistry(definitions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordVersions(registry, FileDictionaryAuthor, ""); err != nil {
+		return nil, err
+	}
+	s.registry = registry
+	return s, nil
+}
+
+// mergeWithHistory prefers the latest version of dictionary, unless its file has been edited since the last time it was read
+func (s *InternalDictionaryService) mergeWithHistory(registry *DictionaryRegistry) ([]Dictionary, error) {
+	definitions := make(map[string]Dictionary)
+	for _, definition := range registry.Definitions() {
+		definitions[definition.Name] = definition
+	}
+
+	names, err := s.history.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		versions, err := s.history.Versions(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		latest := versions[len(versions)-1]
+
+		fileDefinition, ok := definitions[name]
+		if ok == false {
+			definitions[name] = latest.Definition
+			continue
+		}
+
+		for i := len(versions) - 1; i >= 0; i-- {
+			if versions[i].Author == FileDictionaryAuthor {
+				if sameDictionaries(versions[i].Definition, fileDefinition) {
+					definitions[name] = latest.Definition
+				}
+				break
+			}
+		}
+	}
+
+	var output []Dictionary
+	for _, definition := range definitions {
+		output = append(output, definition)
+	}
+	return output, nil
+}
+
+// recordVersions appends version of every dictionary which definition or expanded entries changed,
+// dictionaries changed only because of `changed` dictionary have it as a cause
+func (s *InternalDictionaryService) recordVersions(registry *DictionaryRegistry, author string, changed string) error {
+	now := time.Now().UTC()
+
+	// versions are appended together, so a failure doesn't leave the change recorded only for some of the dictionaries
+	var recorded []DictionaryVersion
+	for _, name := range registry.Names() {
+		definition, err := registry.Definition(name)
+		if err != nil {
+			return err
+		}
+		dictionary, err := registry.Get(name)
+		if err != nil {
+			return err
+		}
+
+		versions, err := s.history.Versions(name)
+		if err != nil {
+			return err
+		}
+
+		version := DictionaryVersion{
+			Name:       name,
+			Version:    1,
+			Author:     author,
+			CreatedAt:  now,
+			Definition: definition,
+			Dictionary: dictionary,
+			Diff:       DiffDictionaries(Dictionary{}, dictionary),
+		}
+
+		if len(versions) > 0 {
+			latest := versions[len(versions)-1]
+			if sameDictionaries(latest.Definition, definition) && sameDictionaries(latest.Dictionary, dictionary) {
+				continue
+			}
+			version.Version = latest.Version + 1
+			version.Diff = DiffDictionaries(latest.Dictionary, dictionary)
+		}
+		if changed != "" && changed != name {
+			version.Cause = changed
+		}
+
+		recorded = append(recorded, version)
+	}
+	return s.history.Append(recorded...)
+}
+
+func (s *InternalDictionaryService) GetDictionariesNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.registry.Names()
+}
+
+func (s *InternalDictionaryService) GetDictionary(name string) (Dictionary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.registry.Get(name)
+}
+
+func (s *InternalDictionaryService) GetDictionaryDefinition(name string) (Dictionary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.registry.Definition(name)
+}
+
+func (s *InternalDictionaryService) GetDictionaryVersions(name string) ([]DictionaryVersion, error) {
+	versions, err := s.history.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %q", DictionaryNotFoundError, name)
+	}
+	return versions, nil
+}
+
+// GetDictionaryVersion returns the latest version when version is 0
+func (s *InternalDictionaryService) GetDictionaryVersion(name string, version int) (DictionaryVersion, error) {
+	versions, err := s.GetDictionaryVersions(name)
+	if err != nil {
+		return DictionaryVersion{}, err
+	}
+
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return DictionaryVersion{}, fmt.Errorf("%w: %q version %d", DictionaryVersionNotFoundError, name, version)
+}
+
+func (s *InternalDictionaryService) DiffDictionaryVersions(name string, from int, to int) (DictionaryDiff, error) {
+	fromVersion, err := s.GetDictionaryVersion(name, from)
+	if err != nil {
+		return DictionaryDiff{}, err
+	}
+
+	toVersion, err := s.GetDictionaryVersion(name, to)
+	if err != nil {
+		return DictionaryDiff{}, err
+	}
+	return DiffDictionaries(fromVersion.Dictionary, toVersion.Dictionary), nil
+}
+
+func ValidateDictionaryName(name string) error {
+	if dictionaryNameRegexp.MatchString(name) == false {
+		return fmt.Errorf("%w: %q", InvalidDictionaryNameError, name)
+	}
+
+	segments := strings.Split(name, "/")
+	for _, segment := range segments {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", InvalidDictionaryNameError, name)
+		}
+	}
+	if isOneOf(segments[len(segments)-1], reservedDictionaryNames) {
+		return fmt.Errorf("%w: %q is reserved", InvalidDictionaryNameError, segments[len(segments)-1])
+	}
+	return nil
+}
+
+// SaveDictionary registers new dictionary or replaces the existing one, saving the same definition again doesn't create a version
+func (s *InternalDictionaryService) SaveDictionary(definition Dictionary, author string) (DictionaryVersion, error) {
+	if err := ValidateDictionaryName(definition.Name); err != nil {
+		return DictionaryVersion{}, err
+	}
+
+	// entries are normalised in a copy, the caller's definition is left as it was
+	entries := make([]DictionaryEntry, len(definition.Entries))
+	for i, entry := range definition.Entries {
+		entry, err := normaliseEntry(entry)
+		if err != nil {
+			return DictionaryVersion{}, fmt.Errorf("entries[%d]: %w", i, err)
+		}
+		entries[i] = entry
+	}
+	definition.Entries = entries
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	definitions := []Dictionary{definition}
+	for _, v := range s.registry.Definitions() {
+		if v.Name != definition.Name {
+			definitions = append(definitions, v)
+		}
+	}
+
+	registry, err := NewDictionaryRegistry(definitions...)
+	if err != nil {
+		return DictionaryVersion{}, err
+	}
+
+	if err := s.recordVersions(registry, author, definition.Name); err != nil {
+		return DictionaryVersion{}, err
+	}
+	s.registry = registry
+
+	return s.GetDictionaryVersion(definition.Name, 0)
+}
+
+// IsDictionaryValidationError tells if the dictionary has been rejected because of its content
+func IsDictionaryValidationError(err error) bool {
+	var dictErr *DictionaryError
+	var cycleErr *DictionaryCycleError
+
+	for _, validationErr := range []error{
+		InvalidDictionaryNameError, DictionaryNotFoundError, DuplicatedDictionaryError, InvalidComposeError,
+		EmptyWordError, UnknownMatchModeError, InvalidWeightError,
+	} {
+		if errors.Is(err, validationErr) {
+			return true
+		}
+	}
+	return errors.As(err, &dictErr) || errors.As(err, &cycleErr)
+}
@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DuplicatesGroup  = "group"
+	DuplicatesDedupe = "dedupe"
+
+	// minHashFunctions is the length of MinHash signature, the error of similarity is about 1/sqrt(64)
+	minHashFunctions = 64
+	// shingleSize is how many following words are one shingle
+	shingleSize = 3
+	// duplicateSimilarity is the estimated jaccard similarity of lyrics above which songs are duplicates
+	duplicateSimilarity = 0.7
+
+	VariantRemix        = "remix"
+	VariantLive         = "live"
+	VariantTranslation  = "translation"
+	VariantSnippet      = "snippet"
+	VariantAcoustic     = "acoustic"
+	VariantInstrumental = "instrumental"
+	VariantDemo         = "demo"
+	VariantVersion      = "version"
+	VariantSkit         = "skit"
+)
+
+var (
+	UnknownDuplicatesModeError = errors.New("unknown duplicates mode")
+
+	// variantKeywords are looked for in parentheses and brackets of titles, the first matching one wins
+	variantKeywords = []struct {
+		variant  string
+		keywords []string
+	}{
+		{VariantTranslation, []string{"translation", "tłumaczenie", "traducción", "traduction", "übersetzung"}},
+		{VariantRemix, []string{"remix", "mix", "rmx", "bootleg"}},
+		{VariantLive, []string{"live", "na żywo", "koncert"}},
+		{VariantSnippet, []string{"snippet", "preview"}},
+		{VariantAcoustic, []string{"acoustic", "unplugged", "akustycznie"}},
+		{VariantInstrumental, []string{"instrumental"}},
+		{VariantDemo, []string{"demo"}},
+		{VariantVersion, []string{"version", "wersja", "edit", "extended", "radio", "remaster", "remastered", "clean", "explicit"}},
+	}
+	titleBracketsRegexp = regexp.MustCompile(`\s*[(\[]([^)\]]*)[)\]]`)
+	titleFeatRegexp     = regexp.MustCompile(`(?i)\s+(feat\.?|ft\.?|featuring)\s.*$`)
+	skitRegexp          = regexp.MustCompile(`(?i)\b(skit|interlude)\b`)
+)
+
+// ValidateDuplicatesMode accepts empty mode too, which means duplicates are kept
+func ValidateDuplicatesMode(mode string) error {
+	if mode == "" || mode == DuplicatesGroup || mode == DuplicatesDedupe {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", UnknownDuplicatesModeError, mode)
+}
+
+// TitleVariant tells if the title is remix, live version, translation etc. from the words in brackets,
+// empty variant is the original song
+func TitleVariant(title string) string {
+	for _, match := range titleBracketsRegexp.FindAllStringSubmatch(title, -1) {
+		inside := " " + strings.ToLower(match[1]) + " "
+		for _, variant := range variantKeywords {
+			for _, keyword := range variant.keywords {
+				if strings.Contains(inside, " "+keyword+" ") || strings.Contains(inside, " "+keyword+"-") {
+					return variant.variant
+				}
+			}
+		}
+	}
+	if skitRegexp.MatchString(title) {
+		return VariantSkit
+	}
+	return ""
+}
+
+// BaseTitle is title without brackets and featured artists, variants of the same song have the same base title
+func BaseTitle(title string) string {
+	title = titleBracketsRegexp.ReplaceAllString(title, "")
+	title = titleFeatRegexp.ReplaceAllString(title, "")
+	return strings.Join(lineTokens(title), " ")
+}
+
+type minHashSignature []uint64
+
+// newMinHashSignature hashes every shingle of words with many seeded hash functions and keeps the minimal values,
+// lyrics shorter than a shingle have no signature
+func newMinHashSignature(lyrics Lyrics) minHashSignature {
+	tokens := lyrics.Tokens()
+	if len(tokens) < shingleSize {
+		return nil
+	}
+
+	signature := make(minHashSignature, minHashFunctions)
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(tokens[i:i+shingleSize], " ")))
+		base := hash.Sum64()
+		for j := range signature {
+			// hashes of the functions are derived from one hash, it's much faster than hashing again
+			value := (base ^ uint64(j)*0x9E3779B97F4A7C15) * 0xBF58476D1CE4E5B9
+			value ^= value >> 31
+			if value < signature[j] {
+				signature[j] = value
+			}
+		}
+	}
+	return signature
+}
+
+// similarity estimates jaccard similarity of shingles as the part of equal minimal hashes
+func (s minHashSignature) similarity(other minHashSignature) float64 {
+	if s == nil || other == nil {
+		return 0
+	}
+	equal := 0
+	for i := range s {
+		if s[i] == other[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(s))
+}
+
+// LyricsSimilarity estimates how much of word sequences both lyrics share, from 0 to 1
+func LyricsSimilarity(a Lyrics, b Lyrics) float64 {
+	return newMinHashSignature(a).similarity(newMinHashSignature(b))
+}
+
+type SongDuplicate struct {
+	Song Song
+	// Variant comes from title, it's empty when the duplicate has been found only by lyrics
+	Variant string
+	// Similarity of lyrics to the original song
+	Similarity float64
+}
+
+type SongGroup struct {
+	Song       Song
+	Variant    string
+	Duplicates []SongDuplicate
+}
+
+// GroupDuplicates groups variants (remixes, live versions etc.) with the song of the same base title and songs with very
+// similar lyrics, the original of group is song without
+// variant in title with the longest lyrics. Groups are in order of songs
+func GroupDuplicates(songs []Song) []SongGroup {
+	parents := make([]int, len(songs))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	union := func(a, b int) {
+		a, b = find(a), find(b)
+		if a < b {
+			parents[b] = a
+		} else if b < a {
+			parents[a] = b
+		}
+	}
+
+	titles := make(map[string][]int)
+	var titlesOrder []string
+	signatures := make([]minHashSignature, len(songs))
+	variants := make([]string, len(songs))
+	for i, song := range songs {
+		variants[i] = TitleVariant(song.Info.Title)
+		signatures[i] = newMinHashSignature(song.Lyrics)
+		if title := BaseTitle(song.Info.Title); title != "" {
+			if _, ok := titles[title]; ok == false {
+				titlesOrder = append(titlesOrder, title)
+			}
+			titles[title] = append(titles[title], i)
+		}
+	}
+	// The same title alone doesn't make songs duplicates, two "Intro"s of different albums are different songs,
+	// so only variants are joined with the song of their title, the one with the most similar lyrics
+	for _, title := range titlesOrder {
+		var originals []int
+		for _, i := range titles[title] {
+			if variants[i] == "" {
+				originals = append(originals, i)
+			}
+		}
+		for _, i := range titles[title] {
+			if variants[i] == "" {
+				continue
+			}
+			if len(originals) == 0 {
+				union(titles[title][0], i)
+				continue
+			}
+			best := originals[0]
+			for _, j := range originals[1:] {
+				if signatures[i].similarity(signatures[j]) > signatures[i].similarity(signatures[best]) {
+					best = j
+				}
+			}
+			union(best, i)
+		}
+	}
+	for i := range songs {
+		for j := i + 1; j < len(songs); j++ {
+			if find(i) != find(j) && signatures[i].similarity(signatures[j]) >= duplicateSimilarity {
+				union(i, j)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range songs {
+		root := find(i)
+		if _, ok := members[root]; ok == false {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	var groups []SongGroup
+	for _, root := range roots {
+		indexes := members[root]
+		sort.SliceStable(indexes, func(a, b int) bool {
+			i, j := indexes[a], indexes[b]
+			if (variants[i] == "") != (variants[j] == "") {
+				return variants[i] == ""
+			}
+			return len(songs[i].Lyrics) > len(songs[j].Lyrics)
+		})
+
+		original := indexes[0]
+		group := SongGroup{Song: songs[original], Variant: variants[original]}
+		for _, i := range indexes[1:] {
+			group.Duplicates = append(group.Duplicates, SongDuplicate{
+				Song:       songs[i],
+				Variant:    variants[i],
+				Similarity: math.Round(signatures[original].similarity(signatures[i])*100) / 100,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Deduplicate keeps only the originals of groups of duplicates, skits are dropped too, as they aren't real songs
+func Deduplicate(songs []Song) []Song {
+	var output []Song
+	for _, group := range GroupDuplicates(songs) {
+		if group.Variant != VariantSkit {
+			output = append(output, group.Song)
+		}
+	}
+	return output
+}
+
+func DeduplicateArtists(artists map[string][]Song) map[string][]Song {
+	output := make(map[string][]Song)
+	for name, songs := range artists {
+		output[name] = Deduplicate(songs)
+	}
+	return output
+}
@@ -0,0 +1,398 @@
+package internal
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type ExportFormat string
+
+const (
+	ExportJSONL ExportFormat = "jsonl"
+	ExportCSV   ExportFormat = "csv"
+	// ExportText is a tree of plain text files, `lyrics/` of it can be used as LYRICS_DIR
+	ExportText ExportFormat = "txt"
+
+	ExportManifestFile = "manifest.json"
+)
+
+var (
+	UnknownExportFormatError = errors.New("unknown export format")
+
+	ExportCSVHeader = []string{"genius_id", "artist", "artist_genius_id", "title", "page_path", "language", "fetched_at", "lyrics", "normalised_lyrics", "words"}
+)
+
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), ".") {
+	case "", "jsonl", "ndjson":
+		return ExportJSONL, nil
+	case "csv":
+		return ExportCSV, nil
+	case "txt", "text", "dir":
+		return ExportText, nil
+	}
+	return "", fmt.Errorf("%w: %q", UnknownExportFormatError, s)
+}
+
+type ExportOptions struct {
+	Format ExportFormat
+	// WordCounts adds occurrences of every word of the song
+	WordCounts bool
+	// Deduplicate drops remixes, live versions etc. like `--duplicates=dedupe`
+	Deduplicate bool
+}
+
+type ExportedSong struct {
+	GeniusID         int              `json:"genius_id" yaml:"genius_id"`
+	Artist           string           `json:"artist" yaml:"artist"`
+	ArtistGeniusID   int              `json:"artist_genius_id" yaml:"artist_genius_id"`
+	Title            string           `json:"title" yaml:"title"`
+	PagePath         string           `json:"page_path" yaml:"page_path"`
+	Language         string           `json:"language" yaml:"language,omitempty"`
+	FetchedAt        *time.Time       `json:"fetched_at,omitempty" yaml:"fetched_at,omitempty"`
+	Lyrics           Lyrics           `json:"lyrics" yaml:"-"`
+	NormalisedLyrics string           `json:"normalised_lyrics" yaml:"-"`
+	Words            WordsOccurrences `json:"words,omitempty" yaml:"-"`
+}
+
+type ExportManifestSong struct {
+	GeniusID int    `json:"genius_id"`
+	Title    string `json:"title"`
+	// FetchedAt is when lyrics have been downloaded, it's known only for lyrics stored in the database
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	// Files are set only by txt format, the other formats keep every song in one file
+	Files []string `json:"files,omitempty"`
+}
+
+// ExportManifest describes the dump, it's written next to the songs as manifest.json
+type ExportManifest struct {
+	Artist         string               `json:"artist"`
+	ArtistGeniusID int                  `json:"artist_genius_id"`
+	Format         ExportFormat         `json:"format"`
+	WordCounts     bool                 `json:"word_counts"`
+	Deduplicated   bool                 `json:"deduplicated"`
+	ExportedAt     time.Time            `json:"exported_at"`
+	SongsCount     int                  `json:"songs_count"`
+	Files          []string             `json:"files"`
+	Songs          []ExportManifestSong `json:"songs"`
+}
+
+// ExportTarget is where files of the dump are written, names are slash separated paths
+type ExportTarget interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+var _ ExportTarget = &DirExportTarget{}
+
+type DirExportTarget struct {
+	dir string
+}
+
+func NewDirExportTarget(dir string) *DirExportTarget {
+	return &DirExportTarget{dir: dir}
+}
+
+func (t *DirExportTarget) Create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(t.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+var _ ExportTarget = &ZipExportTarget{}
+
+// ZipExportTarget packs the dump into zip archive, Close has to be called to finish it
+type ZipExportTarget struct {
+	writer *zip.Writer
+}
+
+func NewZipExportTarget(w io.Writer) *ZipExportTarget {
+	return &ZipExportTarget{writer: zip.NewWriter(w)}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Create returns writer which is valid only until the next Create, like zip.Writer does
+func (t *ZipExportTarget) Create(name string) (io.WriteCloser, error) {
+	w, err := t.writer.Create(name)
+	return nopWriteCloser{w}, err
+}
+
+func (t *ZipExportTarget) Close() error {
+	return t.writer.Close()
+}
+
+type ExportService interface {
+	ExportArtist(artistName string, options ExportOptions, target ExportTarget) (ExportManifest, error)
+}
+
+var _ ExportService = &InternalExportService{}
+
+type InternalExportService struct {
+	lyricsService LyricsService
+	// repository gives fetch dates of stored lyrics, it's nil without database
+	repository LyricsRepository
+	cfg        *config.Config
+	logger     *log.Entry
+}
+
+func NewExportService(cfg *config.Config, lyricsService LyricsService, repository LyricsRepository, logger *log.Entry) *InternalExportService {
+	return &InternalExportService{lyricsService: lyricsService, repository: repository, cfg: cfg, logger: logger}
+}
+
+// ExportArtist writes every song of the artist and manifest.json to the target
+func (s *InternalExportService) ExportArtist(artistName string, options ExportOptions, target ExportTarget) (ExportManifest, error) {
+	manifest := ExportManifest{Format: options.Format, WordCounts: options.WordCounts, Deduplicated: options.Deduplicate,
+		ExportedAt: time.Now().UTC(), Files: []string{}, Songs: []ExportManifestSong{}}
+
+	artist, err := s.lyricsService.GetArtist(artistName)
+	if err != nil {
+		return manifest, err
+	}
+	if artist.GeniusID == 0 {
+		return manifest, fmt.Errorf("%w: %q", ArtistNotFoundError, artistName)
+	}
+	manifest.Artist, manifest.ArtistGeniusID = artist.Name, artist.GeniusID
+
+	songs, err := s.lyricsService.GetSongsByArtist(artistName)
+	if err != nil {
+		return manifest, err
+	}
+	if options.Deduplicate {
+		songs = Deduplicate(songs)
+	}
+
+	exported := make([]ExportedSong, len(songs))
+	for i, song := range songs {
+		exported[i] = s.exportedSong(song, options.WordCounts)
+		exported[i].ArtistGeniusID = artist.GeniusID
+	}
+
+	switch options.Format {
+	case ExportJSONL:
+		err = writeExportFile(target, &manifest, "songs.jsonl", func(w io.Writer) error {
+			return writeExportJSONL(w, exported)
+		})
+	case ExportCSV:
+		err = writeExportFile(target, &manifest, "songs.csv", func(w io.Writer) error {
+			return writeExportCSV(w, exported)
+		})
+	case ExportText:
+		err = writeExportText(target, &manifest, exported, options.WordCounts)
+	default:
+		err = fmt.Errorf("%w: %q", UnknownExportFormatError, options.Format)
+	}
+	if err != nil {
+		return manifest, err
+	}
+
+	if options.Format != ExportText {
+		for _, song := range exported {
+			manifest.Songs = append(manifest.Songs, ExportManifestSong{GeniusID: song.GeniusID, Title: song.Title, FetchedAt: song.FetchedAt})
+		}
+	}
+	manifest.SongsCount = len(manifest.Songs)
+
+	w, err := target.Create(ExportManifestFile)
+	if err != nil {
+		return manifest, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		w.Close()
+		return manifest, err
+	}
+	return manifest, w.Close()
+}
+
+func (s *InternalExportService) exportedSong(song Song, wordCounts bool) ExportedSong {
+	exported := ExportedSong{
+		GeniusID:         song.Info.GeniusID,
+		Artist:           song.Info.AuthorName,
+		Title:            song.Info.Title,
+		PagePath:         song.Info.PageEndpoint,
+		Language:         song.Language(),
+		Lyrics:           song.Lyrics,
+		NormalisedLyrics: song.Lyrics.Normalised(),
+	}
+	if wordCounts {
+		exported.Words = song.Lyrics.FindWords()
+	}
+
+	if s.repository != nil {
+		stored, err := s.repository.GetLyrics(song.Info.GeniusID)
+		if err == nil {
+			fetchedAt := stored.FetchedAt.UTC()
+			exported.FetchedAt = &fetchedAt
+		} else if errors.Is(err, NotStoredError) == false {
+			s.logger.WithError(err).Error("cannot read fetch date of lyrics")
+		}
+	}
+	return exported
+}
+
+// writeExportFile creates the file in target and adds it to the manifest
+func writeExportFile(target ExportTarget, manifest *ExportManifest, name string, write func(w io.Writer) error) error {
+	w, err := target.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		w.Close()
+		return err
+	}
+	manifest.Files = append(manifest.Files, name)
+	return w.Close()
+}
+
+func writeExportJSONL(w io.Writer, songs []ExportedSong) error {
+	encoder := json.NewEncoder(w)
+	for _, song := range songs {
+		if err := encoder.Encode(song); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeExportCSV writes one song per row, words are JSON object, so the column can be parsed easily
+func writeExportCSV(w io.Writer, songs []ExportedSong) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ExportCSVHeader); err != nil {
+		return err
+	}
+
+	for _, song := range songs {
+		fetchedAt, words := "", ""
+		if song.FetchedAt != nil {
+			fetchedAt = song.FetchedAt.Format(time.RFC3339)
+		}
+		if song.Words != nil {
+			data, err := json.Marshal(song.Words)
+			if err != nil {
+				return err
+			}
+			words = string(data)
+		}
+
+		err := writer.Write([]string{strconv.Itoa(song.GeniusID), song.Artist, strconv.Itoa(song.ArtistGeniusID), song.Title, song.PagePath, song.Language,
+			fetchedAt, string(song.Lyrics), song.NormalisedLyrics, words})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type exportFile struct {
+	name  string
+	write func(w io.Writer) error
+}
+
+// writeExportText writes lyrics/<artist>/<title>.txt with front-matter, normalised/<artist>/<title>.txt
+// and words/<artist>/<title>.csv when word counts are requested
+func writeExportText(target ExportTarget, manifest *ExportManifest, songs []ExportedSong, wordCounts bool) error {
+	used := make(map[string]struct{})
+	for _, song := range songs {
+		name := path.Join(exportFileName(song.Artist, "unknown"), exportFileName(song.Title, strconv.Itoa(song.GeniusID)))
+		if _, ok := used[strings.ToLower(name)]; ok {
+			name = fmt.Sprintf("%s (%d)", name, song.GeniusID)
+		}
+		used[strings.ToLower(name)] = struct{}{}
+
+		manifestSong := ExportManifestSong{GeniusID: song.GeniusID, Title: song.Title, FetchedAt: song.FetchedAt}
+		files := []exportFile{
+			{"lyrics/" + name + ".txt", func(w io.Writer) error {
+				frontMatter, err := yaml.Marshal(song)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(w, "---\n%s---\n%s\n", frontMatter, song.Lyrics)
+				return err
+			}},
+			{"normalised/" + name + ".txt", func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, song.NormalisedLyrics)
+				return err
+			}},
+		}
+		if wordCounts {
+			files = append(files, exportFile{"words/" + name + ".csv", func(w io.Writer) error {
+				return writeWordCounts(w, song.Words)
+			}})
+		}
+
+		for _, file := range files {
+			if err := writeExportFile(target, manifest, file.name, file.write); err != nil {
+				return err
+			}
+			manifestSong.Files = append(manifestSong.Files, file.name)
+		}
+		manifest.Songs = append(manifest.Songs, manifestSong)
+	}
+	return nil
+}
+
+func writeWordCounts(w io.Writer, words WordsOccurrences) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"word", "count"}); err != nil {
+		return err
+	}
+	for _, word := range words.Sorted(OrderCountDesc) {
+		if err := writer.Write([]string{string(word.Word), strconv.Itoa(word.Count)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// maxExportFileName is the length of file names in bytes, without the extension and ` (<genius id>)` of duplicated names,
+// most of file systems allow 255 bytes
+const maxExportFileName = 200
+
+// exportFileName makes name safe for every file system, `fallback` is used when nothing is left
+func exportFileName(name string, fallback string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > maxExportFileName {
+		// the name is cut at the beginning of a rune, so it stays valid UTF-8
+		end := maxExportFileName
+		for end > 0 && utf8.RuneStart(name[end]) == false {
+			end--
+		}
+		name = name[:end]
+	}
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
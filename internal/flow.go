@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// verseSections are prefixes of section names which are verses, stanzas without header are treated as verses too
+var verseSections = []string{"verse", "zwrotka"}
+
+type FlowStats struct {
+	Lines             int     `json:"lines"`
+	Syllables         int     `json:"syllables"`
+	SyllablesPerLine  float64 `json:"syllables_per_line"`
+	Variance          float64 `json:"variance"`
+	StdDev            float64 `json:"std_dev"`
+	MinLineSyllables  int     `json:"min_line_syllables"`
+	MaxLineSyllables  int     `json:"max_line_syllables"`
+	Verses            int     `json:"verses"`
+	SyllablesPerVerse float64 `json:"syllables_per_verse"`
+}
+
+type StanzaFlow struct {
+	Section       string   `json:"section,omitempty"`
+	Performers    []string `json:"performers,omitempty"`
+	LineSyllables []int    `json:"line_syllables"`
+	Syllables     int      `json:"syllables"`
+}
+
+func (s StanzaFlow) isVerse() bool {
+	section := strings.ToLower(s.Section)
+	if section == "" {
+		return true
+	}
+	for _, prefix := range verseSections {
+		if strings.HasPrefix(section, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type PerformerFlow struct {
+	Performer string    `json:"performer"`
+	Flow      FlowStats `json:"flow"`
+}
+
+type FlowAnalysis struct {
+	Total      FlowStats       `json:"total"`
+	Performers []PerformerFlow `json:"performers"`
+	Stanzas    []StanzaFlow    `json:"stanzas"`
+}
+
+// LineSyllables counts syllables of every word of the line
+func LineSyllables(line string, language string) int {
+	syllables := 0
+	for _, token := range lineTokens(line) {
+		syllables += Syllables(token, language)
+	}
+	return syllables
+}
+
+// AnalyseFlow counts syllables of lines and stanzas, stanzas without performers in header belong to the performer,
+// which is usually the primary artist of the song
+func AnalyseFlow(lyrics Lyrics, language string, performer string) FlowAnalysis {
+	if language != "pl" {
+		language = "en"
+	}
+	var stanzas []StanzaFlow
+	for _, stanza := range lyrics.Stanzas() {
+		flow := StanzaFlow{Section: stanza.Section, Performers: stanza.Performers, LineSyllables: []int{}}
+		if len(flow.Performers) == 0 && performer != "" {
+			flow.Performers = []string{performer}
+		}
+		for _, line := range stanza.Lines {
+			syllables := LineSyllables(line, language)
+			if syllables == 0 {
+				continue
+			}
+			flow.LineSyllables = append(flow.LineSyllables, syllables)
+			flow.Syllables += syllables
+		}
+		if len(flow.LineSyllables) > 0 {
+			stanzas = append(stanzas, flow)
+		}
+	}
+
+	if stanzas == nil {
+		stanzas = []StanzaFlow{}
+	}
+	return FlowAnalysis{Total: NewFlowStats(stanzas), Performers: PerformersFlow(stanzas), Stanzas: stanzas}
+}
+
+// NewFlowStats calculates mean and variance of syllables per line, verses are averaged separately
+func NewFlowStats(stanzas []StanzaFlow) FlowStats {
+	stats := FlowStats{}
+	verseSyllables := 0
+	for _, stanza := range stanzas {
+		for _, syllables := range stanza.LineSyllables {
+			if stats.Lines == 0 || syllables < stats.MinLineSyllables {
+				stats.MinLineSyllables = syllables
+			}
+			if syllables > stats.MaxLineSyllables {
+				stats.MaxLineSyllables = syllables
+			}
+			stats.Lines++
+			stats.Syllables += syllables
+		}
+		if stanza.isVerse() {
+			stats.Verses++
+			verseSyllables += stanza.Syllables
+		}
+	}
+	if stats.Lines == 0 {
+		return stats
+	}
+
+	mean := float64(stats.Syllables) / float64(stats.Lines)
+	squares := 0.0
+	for _, stanza := range stanzas {
+		for _, syllables := range stanza.LineSyllables {
+			squares += (float64(syllables) - mean) * (float64(syllables) - mean)
+		}
+	}
+	stats.SyllablesPerLine = roundFlow(mean)
+	stats.Variance = roundFlow(squares / float64(stats.Lines))
+	stats.StdDev = roundFlow(math.Sqrt(squares / float64(stats.Lines)))
+	if stats.Verses > 0 {
+		stats.SyllablesPerVerse = roundFlow(float64(verseSyllables) / float64(stats.Verses))
+	}
+	return stats
+}
+
+// PerformersFlow groups stanzas by performers, stanza performed by a few of them counts for each one,
+// the performer with the most lines goes first
+func PerformersFlow(stanzas []StanzaFlow) []PerformerFlow {
+	names := make(map[string]string)
+	byPerformer := make(map[string][]StanzaFlow)
+	for _, stanza := range stanzas {
+		for _, performer := range stanza.Performers {
+			key := strings.ToLower(performer)
+			if _, ok := names[key]; ok == false {
+				names[key] = performer
+			}
+			byPerformer[key] = append(byPerformer[key], stanza)
+		}
+	}
+
+	output := []PerformerFlow{}
+	for key, performerStanzas := range byPerformer {
+		output = append(output, PerformerFlow{Performer: names[key], Flow: NewFlowStats(performerStanzas)})
+	}
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].Flow.Lines != output[j].Flow.Lines {
+			return output[i].Flow.Lines > output[j].Flow.Lines
+		}
+		return output[i].Performer < output[j].Performer
+	})
+	return output
+}
+
+func roundFlow(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -87,6 +87,8 @@ type GeniusProvider interface {
 	GetArtist(artistName string) (GeniusArtist, error)
 	GetSongInfosByArtistID(artistID int) ([]GeniusSongInfo, error)
 	GetSongsByArtistID(artistID int) ([]GeniusSong, error)
+	// GetLyrics downloads lyrics of the song which info is already known
+	GetLyrics(songInfo GeniusSongInfo) (Lyrics, error)
 }
 
 type InternalGeniusProvider struct {
@@ -126,7 +128,7 @@ func (s *InternalGeniusProvider) GetSongByID(id int) (GeniusSong, error) {
 		s.logger.WithError(err).Error("GetSongByID getting song info by ID ", id)
 	}
 
-	lyrics, err := s.getLyrics(songInfo)
+	lyrics, err := s.GetLyrics(songInfo)
 	return GeniusSong{
 		Lyrics: lyrics,
 		Info:   songInfo,
@@ -134,34 +136,7 @@ func (s *InternalGeniusProvider) GetSongByID(id int) (GeniusSong, error) {
 }
 
 func (s *InternalGeniusProvider) GetSongsByIDs(ids []int) ([]GeniusSong, error) {
-	songCh := make(chan GeniusSong, s.cfg.MaxChannelBufferSize)
-	wg := sync.WaitGroup{}
-
-	for _, id := range ids {
-		wg.Add(1)
-		id := id
-		go func() {
-			defer wg.Done()
-			song, err := s.GetSongByID(id)
-			if err != nil {
-				s.logger.WithError(err).Error()
-				return
-			}
-			songCh <- song
-		}()
-
-	}
-
-	go func() {
-		wg.Wait()
-		close(songCh)
-	}()
-
-	var songs []GeniusSong
-	for song := range songCh {
-		songs = append(songs, song)
-	}
-	return songs, nil
+	return getSongsByIDs(s, ids, s.logger), nil
 }
 
 func (s *InternalGeniusProvider) GetSongByName(name string) (GeniusSong, error) {
@@ -280,43 +255,7 @@ func getUrlForSongsList(host string, artistID int, songEndpoint string, page int
 }
 
 func (s *InternalGeniusProvider) GetSongsByArtistID(artistID int) ([]GeniusSong, error) {
-	songInfos, err := s.GetSongInfosByArtistID(artistID)
-	if err != nil {
-		return []GeniusSong{}, err
-	}
-
-	songCh := make(chan GeniusSong, s.cfg.MaxChannelBufferSize)
-	wg := sync.WaitGroup{}
-
-	for _, songInfo := range songInfos {
-		wg.Add(1)
-		songInfo := songInfo
-		go func() {
-			defer wg.Done()
-
-			lyrics, err := s.getLyrics(songInfo)
-			if err != nil {
-				return
-			}
-
-			songCh <- GeniusSong{
-				Lyrics: lyrics,
-				Info:   songInfo,
-			}
-		}()
-	}
-
-	go func() {
-		wg.Wait()
-		close(songCh)
-	}()
-
-	var songs []GeniusSong
-	for song := range songCh {
-		songs = append(songs, song)
-	}
-
-	return songs, nil
+	return getSongsByArtistID(s, artistID)
 }
 
 func (s *InternalGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusSongInfo, error) {
@@ -340,8 +279,8 @@ func (s *InternalGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusS
 	for i := 0; i <= s.cfg.MaxPagesForArtist; i++ {
 		i := i
 
+		wg.Add(1)
 		go func() {
-			wg.Add(1)
 			defer wg.Done()
 			songsUrl := getUrlForSongsList(s.cfg.GeniusApiHost, artistID, songEndpoint, i)
 			if s.cfg.Debug {
@@ -397,7 +336,7 @@ func (s *InternalGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusS
 	return songs, nil
 }
 
-func (s *InternalGeniusProvider) getLyrics(songInfo GeniusSongInfo) (Lyrics, error) {
+func (s *InternalGeniusProvider) GetLyrics(songInfo GeniusSongInfo) (Lyrics, error) {
 	if songInfo.LyricsState == LyricsComplete {
 		return s.getLyricsFromPath(songInfo.PagePath)
 	}
@@ -421,13 +360,14 @@ REQUEST:
 		s.logger.WithError(err).Error("creating http client")
 		return "", err
 	}
-	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
 		s.logger.WithField("status_code", res.StatusCode).Error("wrong status code")
 	}
 
+	// The body is closed before retry, so the request budget isn't held by the previous attempt
 	buf, err := io.ReadAll(res.Body)
+	res.Body.Close()
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(buf))
 	if err != nil {
@@ -446,6 +386,10 @@ REQUEST:
 		})
 	}
 
+	// Line breaks are kept, rhymes and sections can't be found without them
+	doc.Find("br").ReplaceWithHtml("\n")
+	doc.Find("[data-lyrics-container]").AppendHtml("\n")
+
 	var lyrics string
 
 	// This list of selectors is needed in order to work around AB Tests, in future there could be pattern scanning
@@ -468,3 +412,73 @@ REQUEST:
 	}
 	return Lyrics(lyrics), err
 }
+
+// getSongByID joins info and lyrics of the song, both are taken from the provider, so its cache or storage is used
+func getSongByID(provider GeniusProvider, id int) (GeniusSong, error) {
+	songInfo, err := provider.GetSongInfoByID(id)
+	if err != nil {
+		return GeniusSong{}, err
+	}
+
+	lyrics, err := provider.GetLyrics(songInfo)
+	return GeniusSong{Lyrics: lyrics, Info: songInfo}, err
+}
+
+// getSongsByIDs gets songs concurrently with provider.GetSongByID, songs which fail are logged and skipped
+func getSongsByIDs(provider GeniusProvider, ids []int, logger *log.Entry) []GeniusSong {
+	songs := make([]*GeniusSong, len(ids))
+	wg := sync.WaitGroup{}
+	for i, id := range ids {
+		wg.Add(1)
+		i, id := i, id
+		go func() {
+			defer wg.Done()
+			song, err := provider.GetSongByID(id)
+			if err != nil {
+				logger.WithError(err).Error()
+				return
+			}
+			songs[i] = &song
+		}()
+	}
+	wg.Wait()
+
+	return foundSongs(songs)
+}
+
+// getSongsByArtistID gets lyrics of all songs of the artist concurrently, songs which lyrics fail are skipped
+func getSongsByArtistID(provider GeniusProvider, artistID int) ([]GeniusSong, error) {
+	songInfos, err := provider.GetSongInfosByArtistID(artistID)
+	if err != nil {
+		return []GeniusSong{}, err
+	}
+
+	songs := make([]*GeniusSong, len(songInfos))
+	wg := sync.WaitGroup{}
+	for i, songInfo := range songInfos {
+		wg.Add(1)
+		i, songInfo := i, songInfo
+		go func() {
+			defer wg.Done()
+			lyrics, err := provider.GetLyrics(songInfo)
+			if err != nil {
+				return
+			}
+			songs[i] = &GeniusSong{Lyrics: lyrics, Info: songInfo}
+		}()
+	}
+	wg.Wait()
+
+	return foundSongs(songs), nil
+}
+
+// foundSongs skips songs which couldn't be downloaded, the order is kept
+func foundSongs(songs []*GeniusSong) []GeniusSong {
+	var output []GeniusSong
+	for _, song := range songs {
+		if song != nil {
+			output = append(output, *song)
+		}
+	}
+	return output
+}
@@ -0,0 +1,527 @@
+package internal
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the stored lyrics
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the stored lyrics by the imported ones
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictNewer keeps lyrics which have been fetched later
+	ConflictNewer ConflictPolicy = "newer"
+)
+
+var (
+	UnknownConflictPolicyError = errors.New("unknown conflict policy")
+	UnknownDumpError           = errors.New("cannot find songs in the dump")
+	TextDumpWithoutIDsError    = errors.New("text files have no genius_id in front-matter, use them as LYRICS_DIR instead")
+	InvalidSongError           = errors.New("invalid song")
+
+	conflictPolicies = []string{string(ConflictSkip), string(ConflictOverwrite), string(ConflictNewer)}
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	if s == "" {
+		return ConflictSkip, nil
+	}
+	if isOneOf(strings.ToLower(s), conflictPolicies) {
+		return ConflictPolicy(strings.ToLower(s)), nil
+	}
+	return "", fmt.Errorf("%w: %q", UnknownConflictPolicyError, s)
+}
+
+type ImportOptions struct {
+	OnConflict ConflictPolicy
+	// DryRun only validates the dump and reports what would be imported
+	DryRun bool
+}
+
+// replaces tells if song wins the conflict with lyrics fetched at the given time, which is nil when it's unknown
+func (o ImportOptions) replaces(song importedSong, fetchedAt *time.Time) bool {
+	if o.OnConflict == ConflictOverwrite {
+		return true
+	}
+	return o.OnConflict == ConflictNewer && song.FetchedAt != nil && (fetchedAt == nil || song.FetchedAt.After(*fetchedAt))
+}
+
+// ImportProblem is song which couldn't be read, Source is file and line (or row) of it
+type ImportProblem struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// ImportConflict is song with different lyrics in the dump twice or in the dump and in the database
+type ImportConflict struct {
+	GeniusID int    `json:"genius_id"`
+	Title    string `json:"title"`
+	Source   string `json:"source"`
+	// With is the source of the other lyrics, "database" for the stored ones
+	With string `json:"with"`
+	// Resolution is "skipped" or "overwritten"
+	Resolution string `json:"resolution"`
+}
+
+type ImportResult struct {
+	Imported int `json:"imported"`
+	// Unchanged are songs which are stored with the same lyrics already
+	Unchanged int `json:"unchanged"`
+	// Duplicates are songs which are in the dump more than once with the same lyrics
+	Duplicates int              `json:"duplicates"`
+	Invalid    []ImportProblem  `json:"invalid"`
+	Conflicts  []ImportConflict `json:"conflicts"`
+}
+
+type ImportService interface {
+	Import(path string, options ImportOptions) (ImportResult, error)
+}
+
+var _ ImportService = &InternalImportService{}
+
+// InternalImportService reads dumps written by ExportService into the database, so the songs don't have to be downloaded
+type InternalImportService struct {
+	repository LyricsRepository
+	cfg        *config.Config
+	logger     *log.Entry
+}
+
+func NewImportService(cfg *config.Config, repository LyricsRepository, logger *log.Entry) *InternalImportService {
+	return &InternalImportService{repository: repository, cfg: cfg, logger: logger}
+}
+
+type importedSong struct {
+	ExportedSong
+	source string
+}
+
+// Import reads directory or zip archive of export, or one songs.jsonl or songs.csv file. Songs are deduplicated by genius ID,
+// songs with different lyrics are conflicts resolved by OnConflict, in the dump as well as with the stored ones
+func (s *InternalImportService) Import(path string, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{Invalid: []ImportProblem{}, Conflicts: []ImportConflict{}}
+
+	dump, name, closer, err := openDump(path)
+	if err != nil {
+		return result, err
+	}
+	defer closer.Close()
+
+	songs, problems, err := readDump(dump, name)
+	if err != nil {
+		return result, err
+	}
+	result.Invalid = append(result.Invalid, problems...)
+
+	var unique []importedSong
+	seen := make(map[int]int)
+	for _, song := range songs {
+		if err := validateImportedSong(song.ExportedSong); err != nil {
+			result.Invalid = append(result.Invalid, ImportProblem{Source: song.source, Reason: err.Error()})
+			continue
+		}
+
+		index, ok := seen[song.GeniusID]
+		if ok == false {
+			seen[song.GeniusID] = len(unique)
+			unique = append(unique, song)
+			continue
+		}
+		kept := unique[index]
+		if kept.Lyrics == song.Lyrics {
+			result.Duplicates++
+			continue
+		}
+
+		conflict := ImportConflict{GeniusID: song.GeniusID, Title: song.Title, Source: song.source, With: kept.source, Resolution: "skipped"}
+		if options.replaces(song, kept.FetchedAt) {
+			conflict.Resolution = "overwritten"
+			unique[index] = song
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+
+	var imported []importedSong
+	for _, song := range unique {
+		stored, err := s.repository.GetLyrics(song.GeniusID)
+		if err != nil && errors.Is(err, NotStoredError) == false {
+			return result, err
+		}
+
+		switch {
+		case err != nil:
+			imported = append(imported, song)
+		case stored.Lyrics == song.Lyrics:
+			result.Unchanged++
+		case options.replaces(song, &stored.FetchedAt):
+			result.Conflicts = append(result.Conflicts, ImportConflict{GeniusID: song.GeniusID, Title: song.Title,
+				Source: song.source, With: "database", Resolution: "overwritten"})
+			imported = append(imported, song)
+		default:
+			result.Conflicts = append(result.Conflicts, ImportConflict{GeniusID: song.GeniusID, Title: song.Title,
+				Source: song.source, With: "database", Resolution: "skipped"})
+		}
+	}
+
+	result.Imported = len(imported)
+	if options.DryRun {
+		return result, nil
+	}
+	return result, s.save(imported)
+}
+
+// save stores songs and their artists, so they can be used by OFFLINE mode. The dump may have only some songs of the artist,
+// so it's not saved as the listing of the artist, the whole one is still downloaded (or synced) when genius is available.
+// Songs which are stored already get only the lyrics, their stored infos (and removal by sync) are kept
+func (s *InternalImportService) save(songs []importedSong) error {
+	artists := make(map[int]GeniusArtist)
+	var songInfos []GeniusSongInfo
+	for _, song := range songs {
+		artist := GeniusArtist{ID: song.ArtistGeniusID, Name: song.Artist}
+		if artist.ID != 0 {
+			artist.ApiPath = fmt.Sprintf("/artists/%d", artist.ID)
+			artists[artist.ID] = artist
+		}
+
+		_, err := s.repository.GetSongInfo(song.GeniusID)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, NotStoredError) == false {
+			return err
+		}
+		songInfos = append(songInfos, GeniusSongInfo{ID: song.GeniusID, PagePath: song.PagePath, FullTitle: song.Title, PrimaryArtist: artist, LyricsState: LyricsComplete})
+	}
+
+	for _, artist := range artists {
+		_, err := s.repository.GetArtist(artist.Name)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, NotStoredError) == false {
+			return err
+		}
+		if err := s.repository.SaveArtist(artist.Name, artist); err != nil {
+			return err
+		}
+	}
+	if len(songInfos) > 0 {
+		if err := s.repository.SaveSongInfos(songInfos); err != nil {
+			return err
+		}
+	}
+
+	for _, song := range songs {
+		fetchedAt := time.Now()
+		if song.FetchedAt != nil {
+			fetchedAt = *song.FetchedAt
+		}
+		if err := s.repository.SaveStoredLyrics(song.GeniusID, StoredLyrics{Lyrics: song.Lyrics, FetchedAt: fetchedAt}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateImportedSong(song ExportedSong) error {
+	switch {
+	case song.GeniusID <= 0:
+		return fmt.Errorf("%w: genius_id is missing", InvalidSongError)
+	case strings.TrimSpace(song.Title) == "":
+		return fmt.Errorf("%w: title is missing", InvalidSongError)
+	case strings.TrimSpace(string(song.Lyrics)) == "":
+		return fmt.Errorf("%w: lyrics are empty", InvalidSongError)
+	}
+	return nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// openDump opens directory or zip archive of export, `name` is set when path is a single file of songs
+func openDump(dumpPath string) (dump fs.FS, name string, closer io.Closer, err error) {
+	info, err := os.Stat(dumpPath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(dumpPath), "", nopCloser{}, nil
+	}
+	if strings.EqualFold(filepath.Ext(dumpPath), ".zip") {
+		archive, err := zip.OpenReader(dumpPath)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return archive, "", archive, nil
+	}
+	return os.DirFS(filepath.Dir(dumpPath)), filepath.Base(dumpPath), nopCloser{}, nil
+}
+
+// readDump finds songs by manifest.json, or by songs.jsonl, songs.csv and lyrics/ when there is no manifest.
+// Dump without any of them is treated as a tree of text files like `lyrics/` of text export, with genius_id in front-matter
+// of every file. Tree without any genius_id (ex. LYRICS_DIR) is TextDumpWithoutIDsError
+func readDump(dump fs.FS, name string) ([]importedSong, []ImportProblem, error) {
+	var files []string
+	if name != "" {
+		files = []string{name}
+	} else if data, err := fs.ReadFile(dump, ExportManifestFile); err == nil {
+		var manifest ExportManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", ExportManifestFile, err)
+		}
+		files = manifest.Files
+		if manifest.Format == ExportText {
+			files = []string{"lyrics"}
+		}
+	} else {
+		for _, candidate := range []string{"songs.jsonl", "songs.csv", "lyrics"} {
+			if _, err := fs.Stat(dump, candidate); err == nil {
+				files = append(files, candidate)
+			}
+		}
+		if len(files) == 0 {
+			files = []string{"."}
+		}
+	}
+
+	var songs []importedSong
+	var problems []ImportProblem
+	for _, file := range files {
+		var fileSongs []importedSong
+		var fileProblems []ImportProblem
+		var err error
+
+		info, statErr := fs.Stat(dump, file)
+		switch ext := strings.ToLower(path.Ext(file)); {
+		case statErr != nil:
+			return nil, nil, statErr
+		case info.IsDir():
+			fileSongs, fileProblems, err = readTextDump(dump, file)
+		case ext == ".jsonl" || ext == ".ndjson":
+			fileSongs, fileProblems, err = readJSONLDump(dump, file)
+		case ext == ".csv" && strings.HasPrefix(file, "words/") == false:
+			fileSongs, fileProblems, err = readCSVDump(dump, file)
+		case isOneOf(ext, lyricsFileExtensions) && strings.HasPrefix(file, "normalised/") == false:
+			fileSongs, fileProblems, err = readTextFile(dump, file, "")
+		default:
+			// normalised lyrics and word counts can be calculated again
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		songs = append(songs, fileSongs...)
+		problems = append(problems, fileProblems...)
+	}
+
+	if len(songs) == 0 && len(problems) == 0 {
+		return nil, nil, UnknownDumpError
+	}
+	return songs, problems, nil
+}
+
+func readJSONLDump(dump fs.FS, name string) ([]importedSong, []ImportProblem, error) {
+	f, err := dump.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var songs []importedSong
+	var problems []ImportProblem
+	scanner := bufio.NewScanner(f)
+	// lyrics are long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		source := fmt.Sprintf("%s:%d", name, line)
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var song ExportedSong
+		if err := json.Unmarshal(scanner.Bytes(), &song); err != nil {
+			problems = append(problems, ImportProblem{Source: source, Reason: err.Error()})
+			continue
+		}
+		songs = append(songs, importedSong{ExportedSong: song, source: source})
+	}
+	return songs, problems, scanner.Err()
+}
+
+// readCSVDump finds columns by the header, so they may be in any order and some of them may be missing
+func readCSVDump(dump fs.FS, name string) ([]importedSong, []ImportProblem, error) {
+	f, err := dump.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	var songs []importedSong
+	var problems []ImportProblem
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		source := fmt.Sprintf("%s:%d", name, row)
+		if err != nil {
+			problems = append(problems, ImportProblem{Source: source, Reason: err.Error()})
+			continue
+		}
+
+		song, err := csvSong(record, columns)
+		if err != nil {
+			problems = append(problems, ImportProblem{Source: source, Reason: err.Error()})
+			continue
+		}
+		songs = append(songs, importedSong{ExportedSong: song, source: source})
+	}
+	return songs, problems, nil
+}
+
+func csvSong(record []string, columns map[string]int) (ExportedSong, error) {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	number := func(column string) (int, error) {
+		if value(column) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value(column))
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", column, err)
+		}
+		return n, nil
+	}
+
+	song := ExportedSong{Artist: value("artist"), Title: value("title"), PagePath: value("page_path"), Language: value("language"),
+		Lyrics: Lyrics(value("lyrics")), NormalisedLyrics: value("normalised_lyrics")}
+	var err error
+	if song.GeniusID, err = number("genius_id"); err != nil {
+		return song, err
+	}
+	if song.ArtistGeniusID, err = number("artist_genius_id"); err != nil {
+		return song, err
+	}
+	if fetchedAt := value("fetched_at"); fetchedAt != "" {
+		t, err := time.Parse(time.RFC3339, fetchedAt)
+		if err != nil {
+			return song, fmt.Errorf("fetched_at: %w", err)
+		}
+		song.FetchedAt = &t
+	}
+	return song, nil
+}
+
+// readTextDump reads lyrics/<artist>/<title>.txt files of txt export, the artist is taken from the folder
+// when front-matter doesn't have it
+func readTextDump(dump fs.FS, dir string) ([]importedSong, []ImportProblem, error) {
+	var names []string
+	err := fs.WalkDir(dump, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() == false && isOneOf(strings.ToLower(path.Ext(name)), lyricsFileExtensions) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(names)
+
+	var songs []importedSong
+	var problems []ImportProblem
+	for _, name := range names {
+		relative := name
+		if dir != "." {
+			relative = strings.TrimPrefix(name, dir+"/")
+		}
+		artist := ""
+		if parts := strings.Split(relative, "/"); len(parts) > 1 {
+			artist = parts[0]
+		}
+		fileSongs, fileProblems, err := readTextFile(dump, name, artist)
+		if err != nil {
+			return nil, nil, err
+		}
+		songs = append(songs, fileSongs...)
+		problems = append(problems, fileProblems...)
+	}
+
+	for _, song := range songs {
+		if song.GeniusID != 0 {
+			return songs, problems, nil
+		}
+	}
+	if len(songs) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", TextDumpWithoutIDsError, dir)
+	}
+	return songs, problems, nil
+}
+
+func readTextFile(dump fs.FS, name string, artist string) ([]importedSong, []ImportProblem, error) {
+	data, err := fs.ReadFile(dump, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	text := strings.ReplaceAll(strings.TrimPrefix(string(data), "\xef\xbb\xbf"), "\r\n", "\n")
+
+	var song ExportedSong
+	if frontMatter, _, ok := splitFrontMatter(text); ok {
+		if err := yaml.Unmarshal([]byte(frontMatter), &song); err != nil {
+			return nil, []ImportProblem{{Source: name, Reason: err.Error()}}, nil
+		}
+	}
+	fileArtist, fileTitle, lyrics := ParseLyricsFile(name, []byte(text))
+	song.Lyrics = lyrics
+	if song.Artist == "" {
+		song.Artist = fileArtist
+	}
+	if song.Artist == "" {
+		song.Artist = artist
+	}
+	if song.Title == "" {
+		song.Title = fileTitle
+	}
+	if song.Title == "" {
+		song.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	return []importedSong{{ExportedSong: song, source: name}}, nil, nil
+}
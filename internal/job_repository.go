@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var JobNotFoundError = errors.New("job not found")
+
+// JobRepository keeps jobs, so they survive restarts, getters return JobNotFoundError for unknown jobs
+type JobRepository interface {
+	// SaveJob creates the job or updates it, the result is kept
+	SaveJob(job Job) error
+	GetJob(id string) (Job, error)
+	// GetUnfinishedJobs returns queued and running jobs, the oldest first
+	GetUnfinishedJobs() ([]Job, error)
+	SaveJobResult(id string, result JobResult) error
+	// GetJobResult returns JobNotFoundError until the result is saved
+	GetJobResult(id string) (JobResult, error)
+}
+
+var _ JobRepository = &MemoryJobRepository{}
+
+// MemoryJobRepository is used without database, jobs are lost on restart then
+type MemoryJobRepository struct {
+	mutex   sync.RWMutex
+	jobs    map[string]Job
+	results map[string]JobResult
+}
+
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{jobs: make(map[string]Job), results: make(map[string]JobResult)}
+}
+
+func (r *MemoryJobRepository) SaveJob(job Job) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *MemoryJobRepository) GetJob(id string) (Job, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	job, ok := r.jobs[id]
+	if ok == false {
+		return Job{}, JobNotFoundError
+	}
+	return job, nil
+}
+
+func (r *MemoryJobRepository) GetUnfinishedJobs() ([]Job, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var jobs []Job
+	for _, job := range r.jobs {
+		if job.IsFinished() == false {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (r *MemoryJobRepository) SaveJobResult(id string, result JobResult) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.jobs[id]; ok == false {
+		return JobNotFoundError
+	}
+	r.results[id] = result
+	return nil
+}
+
+func (r *MemoryJobRepository) GetJobResult(id string) (JobResult, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result, ok := r.results[id]
+	if ok == false {
+		return JobResult{}, JobNotFoundError
+	}
+	return result, nil
+}
+
+var _ JobRepository = &SQLLyricsRepository{}
+
+// jobs are kept next to lyrics, dates are in nanoseconds, so jobs created in the same second keep their order
+
+func jobNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return JobNotFoundError
+	}
+	return err
+}
+
+func unixNano(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(value sql.NullInt64) *time.Time {
+	if value.Valid == false {
+		return nil
+	}
+	t := time.Unix(0, value.Int64)
+	return &t
+}
+
+func (r *SQLLyricsRepository) SaveJob(job Job) error {
+	spec, err := json.Marshal(job.Spec)
+	if err != nil {
+		return err
+	}
+	progress, err := json.Marshal(job.Progress)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(r.rebind(`INSERT INTO jobs (id, spec, status, progress, error, created_at, started_at, finished_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET spec = excluded.spec, status = excluded.status, progress = excluded.progress, error = excluded.error,
+		created_at = excluded.created_at, started_at = excluded.started_at, finished_at = excluded.finished_at`),
+		job.ID, string(spec), string(job.Status), string(progress), job.Error, job.CreatedAt.UnixNano(), unixNano(job.StartedAt), unixNano(job.FinishedAt))
+	return err
+}
+
+func scanJob(row rowScanner) (Job, error) {
+	var job Job
+	var spec, progress string
+	var createdAt int64
+	var startedAt, finishedAt sql.NullInt64
+	err := row.Scan(&job.ID, &spec, &job.Status, &progress, &job.Error, &createdAt, &startedAt, &finishedAt)
+	if err != nil {
+		return Job{}, jobNotFound(err)
+	}
+	if err := json.Unmarshal([]byte(spec), &job.Spec); err != nil {
+		return Job{}, err
+	}
+	if err := json.Unmarshal([]byte(progress), &job.Progress); err != nil {
+		return Job{}, err
+	}
+	job.CreatedAt = time.Unix(0, createdAt)
+	job.StartedAt, job.FinishedAt = fromUnixNano(startedAt), fromUnixNano(finishedAt)
+	return job, nil
+}
+
+const jobColumns = `id, spec, status, progress, error, created_at, started_at, finished_at`
+
+func (r *SQLLyricsRepository) GetJob(id string) (Job, error) {
+	return scanJob(r.db.QueryRow(r.rebind(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`), id))
+}
+
+func (r *SQLLyricsRepository) GetUnfinishedJobs() ([]Job, error) {
+	rows, err := r.db.Query(r.rebind(`SELECT `+jobColumns+` FROM jobs WHERE status IN (?, ?) ORDER BY created_at, id`), string(JobQueued), string(JobRunning))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *SQLLyricsRepository) SaveJobResult(id string, result JobResult) error {
+	by, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.Exec(r.rebind(`UPDATE jobs SET result = ? WHERE id = ?`), string(by), id)
+	if err != nil {
+		return err
+	}
+	if updated, err := res.RowsAffected(); err == nil && updated == 0 {
+		return JobNotFoundError
+	}
+	return nil
+}
+
+func (r *SQLLyricsRepository) GetJobResult(id string) (JobResult, error) {
+	var by sql.NullString
+	if err := r.db.QueryRow(r.rebind(`SELECT result FROM jobs WHERE id = ?`), id).Scan(&by); err != nil {
+		return JobResult{}, jobNotFound(err)
+	}
+	if by.Valid == false {
+		return JobResult{}, JobNotFoundError
+	}
+	var result JobResult
+	err := json.Unmarshal([]byte(by.String), &result)
+	return result, err
+}
@@ -0,0 +1,540 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	UnknownJobKindError = errors.New("unknown job kind")
+	InvalidJobSpecError = errors.New("invalid job spec")
+	JobFinishedError    = errors.New("job has already finished")
+	JobNotDoneError     = errors.New("job isn't done")
+)
+
+type JobKind string
+
+const (
+	// JobSongs lists songs of the artist like `/artists/:artist_name/songs`
+	JobSongs JobKind = "songs"
+	// JobWords counts words of every song like `/artists/:artist_name/songs/words`
+	JobWords JobKind = "words"
+	// JobAnalysis analyses the artist like `/artists/:artist_name/analysis`
+	JobAnalysis JobKind = "analysis"
+)
+
+var JobKinds = []string{string(JobSongs), string(JobWords), string(JobAnalysis)}
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// jobProgressInterval is how often the progress of running job is saved, GetJob shows it live anyway
+const jobProgressInterval = time.Second
+
+// JobSpec is what should be done, the filters work like query params of the endpoints
+type JobSpec struct {
+	Kind   JobKind `json:"kind"`
+	Artist string  `json:"artist"`
+	// Query is the same as `?q=`, see ParseQuery
+	Query         string `json:"q,omitempty"`
+	Language      string `json:"language,omitempty"`
+	Duplicates    string `json:"duplicates,omitempty"`
+	DistinctLines bool   `json:"distinct_lines,omitempty"`
+	// StopWordsLanguage is used only by "words" jobs
+	StopWordsLanguage string `json:"stop_words_language,omitempty"`
+	// Themes are names of theme dictionaries used by "analysis" jobs, all of them by default
+	Themes []string `json:"themes,omitempty"`
+}
+
+// Validate checks the spec and sets default kind, the query is checked when the job is created
+func (s *JobSpec) Validate() error {
+	if s.Kind == "" {
+		s.Kind = JobWords
+	}
+	if isOneOf(string(s.Kind), JobKinds) == false {
+		return fmt.Errorf("%w: %q, use one of: %s", UnknownJobKindError, s.Kind, strings.Join(JobKinds, ", "))
+	}
+	if strings.TrimSpace(s.Artist) == "" {
+		return fmt.Errorf("%w: artist is required", InvalidJobSpecError)
+	}
+	// grouped duplicates are only a way of printing songs
+	if s.Duplicates != "" && s.Duplicates != DuplicatesDedupe {
+		return fmt.Errorf("%w: duplicates can be only %q", InvalidJobSpecError, DuplicatesDedupe)
+	}
+	return nil
+}
+
+type Job struct {
+	ID         string         `json:"id"`
+	Spec       JobSpec        `json:"spec"`
+	Status     JobStatus      `json:"status"`
+	Progress   ScrapeProgress `json:"progress"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+func (j Job) IsFinished() bool {
+	return j.Status == JobDone || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+type JobSong struct {
+	GeniusID  int             `json:"genius_id"`
+	Title     string          `json:"title"`
+	PagePath  string          `json:"page_path"`
+	Languages []LanguageScore `json:"languages,omitempty"`
+	// WordsCount and StopWordsLanguage are set by "words" jobs
+	WordsCount        WordsOccurrences `json:"words_count,omitempty"`
+	StopWordsLanguage string           `json:"stop_words_language,omitempty"`
+	// Mood is set by "analysis" jobs
+	Mood string `json:"mood,omitempty"`
+}
+
+// JobArtistAnalysis is the summary of ArtistAnalysis, analyses of songs are too big to be kept
+type JobArtistAnalysis struct {
+	Rhymes     RhymeSummary    `json:"rhymes"`
+	Flow       FlowStats       `json:"flow"`
+	Performers []PerformerFlow `json:"performers"`
+	Sentiment  Sentiment       `json:"sentiment"`
+	Moods      map[string]int  `json:"moods"`
+	Themes     []ThemeScore    `json:"themes"`
+	Repetition Repetition      `json:"repetition"`
+}
+
+type JobResult struct {
+	Songs []JobSong `json:"songs"`
+	// Analysis is set by "analysis" jobs
+	Analysis *JobArtistAnalysis `json:"analysis,omitempty"`
+	// Dictionaries are versions of dictionaries used by the query, so the result can be reproduced
+	Dictionaries []DictionaryVersionRef `json:"dictionaries,omitempty"`
+}
+
+type DictionaryVersionRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+type JobService interface {
+	CreateJob(spec JobSpec) (Job, error)
+	// GetJob shows live progress of running job
+	GetJob(id string) (Job, error)
+	// GetJobResult returns JobNotDoneError until the job is done
+	GetJobResult(id string) (JobResult, error)
+	// CancelJob stops queued or running job and saves it as cancelled right away, songs which are being downloaded
+	// are finished though. JobFinishedError is returned for finished jobs
+	CancelJob(id string) (Job, error)
+}
+
+var _ JobService = &InternalJobService{}
+
+// runningJob is the live state of job, it's saved to the repository every jobProgressInterval. Saves of the job are done
+// under its mutex, so the last state is the one which is stored
+type runningJob struct {
+	mutex     sync.Mutex
+	job       Job
+	cancel    context.CancelFunc
+	cancelled bool
+	savedAt   time.Time
+}
+
+// InternalJobService runs jobs on JobsWorkers workers, queued jobs and jobs interrupted by restart are run again by Start
+type InternalJobService struct {
+	cfg               *config.Config
+	lyricsService     LyricsService
+	dictionaryService DictionaryService
+	stopWords         *StopWordsRegistry
+	repository        JobRepository
+	logger            *log.Entry
+	now               func() time.Time
+
+	mutex sync.Mutex
+	wake  *sync.Cond
+	queue []string
+	// starting are jobs which have been taken from the queue and are being read by workers, true means cancelled
+	starting map[string]bool
+	running  map[string]*runningJob
+	closed   bool
+	workers  sync.WaitGroup
+}
+
+func NewJobService(cfg *config.Config, lyricsService LyricsService, dictionaryService DictionaryService, stopWords *StopWordsRegistry, repository JobRepository, logger *log.Entry) *InternalJobService {
+	service := &InternalJobService{
+		cfg:               cfg,
+		lyricsService:     lyricsService,
+		dictionaryService: dictionaryService,
+		stopWords:         stopWords,
+		repository:        repository,
+		logger:            logger,
+		now:               time.Now,
+		starting:          make(map[string]bool),
+		running:           make(map[string]*runningJob),
+	}
+	service.wake = sync.NewCond(&service.mutex)
+	return service
+}
+
+// Start queues unfinished jobs again and starts workers, jobs which were running are started from the beginning
+func (s *InternalJobService) Start() error {
+	jobs, err := s.repository.GetUnfinishedJobs()
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, job := range jobs {
+		if job.Status == JobRunning {
+			job.Status, job.Progress, job.StartedAt = JobQueued, ScrapeProgress{}, nil
+			if err := s.repository.SaveJob(job); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, job.ID)
+	}
+	s.mutex.Lock()
+	s.queue = append(ids, s.queue...)
+	s.mutex.Unlock()
+	if len(jobs) > 0 {
+		s.logger.Infof("%d unfinished jobs queued again", len(jobs))
+	}
+
+	workers := s.cfg.JobsWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.work()
+	}
+	return nil
+}
+
+// Close stops workers, running jobs are left queued, so they are run again after restart
+func (s *InternalJobService) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	for _, running := range s.running {
+		running.cancel()
+	}
+	s.wake.Broadcast()
+	s.mutex.Unlock()
+	s.workers.Wait()
+}
+
+func newJobID() (string, error) {
+	by := make([]byte, 16)
+	if _, err := rand.Read(by); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(by), nil
+}
+
+func (s *InternalJobService) CreateJob(spec JobSpec) (Job, error) {
+	if err := spec.Validate(); err != nil {
+		return Job{}, err
+	}
+	// wrong query or missing dictionary should be known now, not after minutes of downloading
+	if strings.TrimSpace(spec.Query) != "" {
+		if _, err := ParseQuery(spec.Query, s.dictionaryService); err != nil {
+			return Job{}, err
+		}
+	}
+	if spec.Kind == JobAnalysis {
+		if _, err := GetThemes(s.dictionaryService, spec.Themes); err != nil {
+			return Job{}, err
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Spec: spec, Status: JobQueued, CreatedAt: s.now()}
+	if err := s.repository.SaveJob(job); err != nil {
+		return Job{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queue = append(s.queue, id)
+	s.wake.Signal()
+	return job, nil
+}
+
+func (s *InternalJobService) GetJob(id string) (Job, error) {
+	s.mutex.Lock()
+	running, ok := s.running[id]
+	s.mutex.Unlock()
+	if ok {
+		running.mutex.Lock()
+		defer running.mutex.Unlock()
+		return running.job, nil
+	}
+	return s.repository.GetJob(id)
+}
+
+func (s *InternalJobService) GetJobResult(id string) (JobResult, error) {
+	job, err := s.GetJob(id)
+	if err != nil {
+		return JobResult{}, err
+	}
+	if job.Status != JobDone {
+		return JobResult{}, fmt.Errorf("%w, it's %s", JobNotDoneError, job.Status)
+	}
+	return s.repository.GetJobResult(id)
+}
+
+func (s *InternalJobService) CancelJob(id string) (Job, error) {
+	s.mutex.Lock()
+	running, ok := s.running[id]
+	if ok == false {
+		for i, queued := range s.queue {
+			if queued == id {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+		if _, ok := s.starting[id]; ok {
+			s.starting[id] = true
+		}
+	}
+	s.mutex.Unlock()
+
+	if running != nil {
+		// the worker stops after songs which are being downloaded, the job is saved as cancelled right away anyway
+		running.mutex.Lock()
+		defer running.mutex.Unlock()
+		if running.job.IsFinished() {
+			return running.job, JobFinishedError
+		}
+		now := s.now()
+		running.cancelled = true
+		running.job.Status, running.job.FinishedAt = JobCancelled, &now
+		running.cancel()
+		return running.job, s.repository.SaveJob(running.job)
+	}
+
+	job, err := s.repository.GetJob(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.IsFinished() {
+		return job, JobFinishedError
+	}
+	now := s.now()
+	job.Status, job.FinishedAt = JobCancelled, &now
+	return job, s.repository.SaveJob(job)
+}
+
+func (s *InternalJobService) work() {
+	defer s.workers.Done()
+	for {
+		s.mutex.Lock()
+		for len(s.queue) == 0 && s.closed == false {
+			s.wake.Wait()
+		}
+		if s.closed {
+			s.mutex.Unlock()
+			return
+		}
+		id := s.queue[0]
+		s.queue = s.queue[1:]
+		s.starting[id] = false
+		s.mutex.Unlock()
+
+		job, err := s.repository.GetJob(id)
+
+		s.mutex.Lock()
+		cancelled := s.starting[id]
+		delete(s.starting, id)
+		if s.closed {
+			// it's still queued in the repository, so it's run after restart
+			s.mutex.Unlock()
+			return
+		}
+		if err != nil {
+			s.mutex.Unlock()
+			s.logger.WithError(err).Error("cannot get queued job ", id)
+			continue
+		}
+		if cancelled {
+			s.mutex.Unlock()
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		now := s.now()
+		job.Status, job.StartedAt = JobRunning, &now
+		running := &runningJob{job: job, cancel: cancel}
+		s.running[id] = running
+		s.mutex.Unlock()
+
+		s.run(ctx, running)
+		cancel()
+	}
+}
+
+// run runs the job and saves how it has finished, job interrupted by Close is saved as queued
+func (s *InternalJobService) run(ctx context.Context, running *runningJob) {
+	s.saveProgress(running, true)
+	result, err := s.execute(ctx, running)
+	// the job stays running until it's saved, so CancelJob doesn't cancel finished job in the repository
+	defer func() {
+		s.mutex.Lock()
+		delete(s.running, running.job.ID)
+		s.mutex.Unlock()
+	}()
+
+	if err == nil && ctx.Err() == nil {
+		if saveErr := s.repository.SaveJobResult(running.job.ID, result); saveErr != nil {
+			s.logger.WithError(saveErr).Error("cannot save result of job ", running.job.ID)
+			err = errors.New("cannot save the result")
+		}
+	}
+
+	running.mutex.Lock()
+	defer running.mutex.Unlock()
+	now := s.now()
+	job := &running.job
+	switch {
+	case running.cancelled:
+		// it has been saved as cancelled by CancelJob, it's saved again with the last progress
+	case ctx.Err() != nil:
+		job.Status, job.Progress, job.StartedAt = JobQueued, ScrapeProgress{}, nil
+	case err != nil:
+		job.Status, job.Error, job.FinishedAt = JobFailed, err.Error(), &now
+	default:
+		job.Status, job.FinishedAt = JobDone, &now
+	}
+	if err := s.repository.SaveJob(*job); err != nil {
+		s.logger.WithError(err).Error("cannot save job ", job.ID)
+	}
+}
+
+// saveProgress saves the running job, not more often than jobProgressInterval unless it's forced
+func (s *InternalJobService) saveProgress(running *runningJob, force bool) {
+	running.mutex.Lock()
+	defer running.mutex.Unlock()
+	if force == false && s.now().Sub(running.savedAt) < jobProgressInterval {
+		return
+	}
+	running.savedAt = s.now()
+	if err := s.repository.SaveJob(running.job); err != nil {
+		s.logger.WithError(err).Error("cannot save progress of job ", running.job.ID)
+	}
+}
+
+func (s *InternalJobService) execute(ctx context.Context, running *runningJob) (JobResult, error) {
+	spec := running.job.Spec
+	infos, err := s.lyricsService.GetSongsInfosByArtist(spec.Artist)
+	if err != nil {
+		return JobResult{}, err
+	}
+	running.mutex.Lock()
+	running.job.Progress.SongsListed = len(infos)
+	running.mutex.Unlock()
+	s.saveProgress(running, true)
+
+	songs := s.scrape(ctx, running, infos)
+	if ctx.Err() != nil {
+		return JobResult{}, ctx.Err()
+	}
+	return s.analyse(spec, songs)
+}
+
+// scrape downloads lyrics of songs, songs which fail are skipped, the order of listing is kept
+func (s *InternalJobService) scrape(ctx context.Context, running *runningJob, infos []SongInfo) []Song {
+	songs := make([]*Song, len(infos))
+	ScrapeSongs(ctx, s.lyricsService, infos, s.cfg.JobsConcurrentSongs, func(index int, song Song, err error) {
+		running.mutex.Lock()
+		running.job.Progress.Add(infos[index], err)
+		if err == nil {
+			songs[index] = &song
+		}
+		running.mutex.Unlock()
+		s.saveProgress(running, false)
+	})
+
+	var output []Song
+	for _, song := range songs {
+		if song != nil {
+			output = append(output, *song)
+		}
+	}
+	return output
+}
+
+func (s *InternalJobService) analyse(spec JobSpec, songs []Song) (JobResult, error) {
+	result := JobResult{Songs: []JobSong{}}
+	if spec.Duplicates == DuplicatesDedupe {
+		songs = Deduplicate(songs)
+	}
+	if spec.DistinctLines {
+		songs = WithDistinctLines(songs)
+	}
+	songs = FilterSongsByLanguage(songs, spec.Language)
+	if strings.TrimSpace(spec.Query) != "" {
+		query, err := ParseQuery(spec.Query, s.dictionaryService)
+		if err != nil {
+			return result, err
+		}
+		songs = FilterSongsByQuery(songs, query)
+		for _, version := range query.DictionaryVersions() {
+			result.Dictionaries = append(result.Dictionaries, DictionaryVersionRef{Name: version.Name, Version: version.Version})
+		}
+	}
+
+	var moods []string
+	if spec.Kind == JobAnalysis {
+		themes, err := GetThemes(s.dictionaryService, spec.Themes)
+		if err != nil {
+			return result, err
+		}
+		analysis := AnalyseArtist(songs, themes)
+		result.Analysis = &JobArtistAnalysis{
+			Rhymes:     analysis.Rhymes,
+			Flow:       analysis.Flow,
+			Performers: analysis.Performers,
+			Sentiment:  analysis.Sentiment,
+			Moods:      analysis.Moods,
+			Themes:     analysis.Themes,
+			Repetition: analysis.Repetition,
+		}
+		for _, songAnalysis := range analysis.Songs {
+			moods = append(moods, songAnalysis.Sentiment.Total.Mood)
+		}
+	}
+
+	for i, song := range songs {
+		jobSong := JobSong{GeniusID: song.Info.GeniusID, Title: song.Info.Title, PagePath: song.Info.PageEndpoint, Languages: song.Languages}
+		if spec.Kind == JobWords {
+			words := song.Lyrics.FindWords()
+			stopWords, language, err := s.stopWords.Resolve(spec.StopWordsLanguage, LanguagesOf(song.Languages), words)
+			if err != nil {
+				return result, err
+			}
+			if language == StopWordsNone {
+				language = ""
+			}
+			jobSong.WordsCount, jobSong.StopWordsLanguage = words.WithoutStopWords(stopWords), language
+		}
+		if moods != nil {
+			jobSong.Mood = moods[i]
+		}
+		result.Songs = append(result.Songs, jobSong)
+	}
+	return result, nil
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"embed"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed languages/*.txt
+var languagesFS embed.FS
+
+const (
+	languageNgramSize = 3
+	// lyrics are split into chunks of words, every chunk gets its own language, so mixed songs are recognised
+	languageChunkWords = 12
+	// languages used in smaller part of the song are skipped
+	minLanguageShare = 0.2
+)
+
+type LanguageScore struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+// LanguagesOf returns language codes of the scores, ordered the same way
+func LanguagesOf(scores []LanguageScore) []string {
+	var languages []string
+	for _, score := range scores {
+		languages = append(languages, score.Language)
+	}
+	return languages
+}
+
+type languageProfile struct {
+	ngrams map[string]int
+	total  int
+}
+
+// LanguageDetector compares character trigrams of lyrics with profiles built from sample texts, it works offline
+type LanguageDetector struct {
+	profiles   map[string]languageProfile
+	vocabulary int
+}
+
+// NewLanguageDetector builds profiles from samples by language code, ex. {"en": "some english text"}
+func NewLanguageDetector(samples map[string]string) *LanguageDetector {
+	d := &LanguageDetector{profiles: make(map[string]languageProfile)}
+
+	distinct := make(map[string]struct{})
+	for language, sample := range samples {
+		profile := languageProfile{ngrams: make(map[string]int)}
+		for _, ngram := range ngrams(detectionWords(sample)) {
+			profile.ngrams[ngram]++
+			profile.total++
+			distinct[ngram] = struct{}{}
+		}
+		d.profiles[language] = profile
+	}
+	d.vocabulary = len(distinct) + 1
+	return d
+}
+
+var (
+	defaultLanguageDetector     *LanguageDetector
+	defaultLanguageDetectorOnce sync.Once
+)
+
+// DefaultLanguageDetector knows languages of the embedded samples, currently english and polish
+func DefaultLanguageDetector() *LanguageDetector {
+	defaultLanguageDetectorOnce.Do(func() {
+		samples := make(map[string]string)
+		files, err := languagesFS.ReadDir("languages")
+		if err != nil {
+			panic(err)
+		}
+		for _, file := range files {
+			by, err := languagesFS.ReadFile(path.Join("languages", file.Name()))
+			if err != nil {
+				panic(err)
+			}
+			samples[strings.TrimSuffix(file.Name(), ".txt")] = string(by)
+		}
+		defaultLanguageDetector = NewLanguageDetector(samples)
+	})
+	return defaultLanguageDetector
+}
+
+func (d *LanguageDetector) Languages() []string {
+	var languages []string
+	for language := range d.profiles {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// detectionWords returns lowercase words without section headers like "[Chorus]"
+func detectionWords(text string) []string {
+	var words []string
+	for _, line := range strings.Split(removeParentheses(text), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), commentPrefix) {
+			continue
+		}
+		words = append(words, strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+			return unicode.IsLetter(r) == false && r != '\''
+		})...)
+	}
+	return words
+}
+
+func ngrams(words []string) []string {
+	var output []string
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+languageNgramSize <= len(runes); i++ {
+			output = append(output, string(runes[i:i+languageNgramSize]))
+		}
+	}
+	return output
+}
+
+// detectChunk returns the most probable language of words and its probability compared to the other languages
+func (d *LanguageDetector) detectChunk(words []string) (string, float64) {
+	chunkNgrams := ngrams(words)
+	if len(chunkNgrams) == 0 {
+		return "", 0
+	}
+
+	languages := d.Languages()
+	scores := make([]float64, len(languages))
+	for i, language := range languages {
+		profile := d.profiles[language]
+		for _, ngram := range chunkNgrams {
+			scores[i] += math.Log(float64(profile.ngrams[ngram]+1) / float64(profile.total+d.vocabulary))
+		}
+	}
+
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	sum := 0.0
+	for i := range scores {
+		sum += math.Exp(scores[i] - scores[best])
+	}
+	return languages[best], 1 / sum
+}
+
+// Detect returns languages used in the lyrics, the most used one first, Confidence is the part of lyrics
+// written in the language weighted by certainty of detection
+func (d *LanguageDetector) Detect(lyrics Lyrics) []LanguageScore {
+	words := detectionWords(string(lyrics))
+	if len(words) == 0 || len(d.profiles) == 0 {
+		return nil
+	}
+
+	weights := make(map[string]float64)
+	for start := 0; start < len(words); start += languageChunkWords {
+		end := start + languageChunkWords
+		if end > len(words) {
+			end = len(words)
+		}
+
+		language, probability := d.detectChunk(words[start:end])
+		if language != "" {
+			weights[language] += float64(end-start) * probability
+		}
+	}
+
+	return languageScores(weights, float64(len(words)))
+}
+
+// languageScores turns weights into shares of the total, languages with too small share are skipped
+func languageScores(weights map[string]float64, total float64) []LanguageScore {
+	if total == 0 {
+		return nil
+	}
+
+	var scores []LanguageScore
+	for language, weight := range weights {
+		share := weight / total
+		if share >= minLanguageShare {
+			scores = append(scores, LanguageScore{Language: language, Confidence: math.Round(share*100) / 100})
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Confidence != scores[j].Confidence {
+			return scores[i].Confidence > scores[j].Confidence
+		}
+		return scores[i].Language < scores[j].Language
+	})
+	return scores
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	SongNotFoundError = errors.New("song not found")
+
+	lyricsFileExtensions = []string{".txt", ".lrc", ".md"}
+	// lrcTimestampRegexp matches line timestamps `[01:23.45]` and word timestamps `<01:23.45>` of LRC files
+	lrcTimestampRegexp = regexp.MustCompile(`[\[<]\d+:\d+(?:[.:]\d+)?[\]>]`)
+	lrcTagRegexp       = regexp.MustCompile(`^\[([a-z]+):(.*)\]$`)
+)
+
+type lyricsFrontMatter struct {
+	Artist string `yaml:"artist"`
+	Title  string `yaml:"title"`
+}
+
+// splitFrontMatter cuts `---` yaml block from the beginning of the text, ok is false when there is no such block
+func splitFrontMatter(text string) (frontMatter string, rest string, ok bool) {
+	if strings.HasPrefix(text, "---\n") == false {
+		return "", text, false
+	}
+	end := strings.Index(text[4:], "\n---")
+	if end < 0 {
+		return "", text, false
+	}
+	return text[4 : 4+end], strings.TrimPrefix(text[4+end+4:], "\n"), true
+}
+
+// ParseLyricsFile reads lyrics with artist and title from front-matter (`---` yaml block) or LRC tags (`[ar:]`, `[ti:]`),
+// they are empty when the file doesn't have them. Timestamps of LRC files are removed
+func ParseLyricsFile(name string, data []byte) (artist string, title string, lyrics Lyrics) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	if front, rest, ok := splitFrontMatter(text); ok {
+		var frontMatter lyricsFrontMatter
+		if yaml.Unmarshal([]byte(front), &frontMatter) == nil {
+			artist, title = frontMatter.Artist, frontMatter.Title
+			text = rest
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(name), ".lrc") {
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if match := lrcTagRegexp.FindStringSubmatch(line); match != nil {
+				switch match[1] {
+				case "ar":
+					artist = strings.TrimSpace(match[2])
+				case "ti":
+					title = strings.TrimSpace(match[2])
+				}
+				continue
+			}
+			lines = append(lines, strings.TrimSpace(lrcTimestampRegexp.ReplaceAllString(line, "")))
+		}
+		text = strings.Join(lines, "\n")
+	}
+
+	return artist, title, Lyrics(strings.TrimSpace(text))
+}
+
+// LoadLyricsDir reads songs from files like `<dir>/<artist>/<title>.txt`, deeper folders (ex. albums) are allowed,
+// front-matter of files overrides artist and title taken from the path. Every song gets an ID, like the ones from genius,
+// it's a hash of the path of the file, so it doesn't change when other files are added or removed
+func LoadLyricsDir(dir string) ([]Song, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() == false && isOneOf(strings.ToLower(filepath.Ext(path)), lyricsFileExtensions) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var songs []Song
+	ids := make(map[int]struct{})
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		artist, title, lyrics := ParseLyricsFile(path, bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+		relative, _ := filepath.Rel(dir, path)
+		if parts := strings.Split(filepath.ToSlash(relative), "/"); artist == "" && len(parts) > 1 {
+			artist = parts[0]
+		}
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		id := lyricsFileID(filepath.ToSlash(relative))
+		// paths are sorted, so the same files always get the same IDs even when hashes collide
+		for _, ok := ids[id]; ok; _, ok = ids[id] {
+			id = id%math.MaxInt32 + 1
+		}
+		ids[id] = struct{}{}
+
+		songs = append(songs, newSong(SongInfo{
+			AuthorName:   artist,
+			Title:        title,
+			PageEndpoint: "/" + filepath.ToSlash(relative),
+			GeniusID:     id,
+		}, lyrics))
+	}
+	return songs, nil
+}
+
+// lyricsFileID is positive, zero means that the song hasn't been found
+func lyricsFileID(relativePath string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(relativePath))
+	return int(hash.Sum32()%math.MaxInt32) + 1
+}
+
+var _ LyricsService = &LocalLyricsService{}
+
+// LocalLyricsService works on songs loaded up front, ex. from LYRICS_DIR, so nothing is downloaded
+type LocalLyricsService struct {
+	songs   []Song
+	artists []Artist
+	cfg     *config.Config
+	logger  *log.Entry
+}
+
+func NewLocalLyricsService(cfg *config.Config, songs []Song, logger *log.Entry) *LocalLyricsService {
+	service := &LocalLyricsService{songs: songs, cfg: cfg, logger: logger}
+	seen := make(map[string]struct{})
+	for _, song := range songs {
+		key := strings.ToLower(song.Info.AuthorName)
+		if _, ok := seen[key]; ok || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		service.artists = append(service.artists, Artist{GeniusID: len(service.artists) + 1, Name: song.Info.AuthorName})
+	}
+	return service
+}
+
+// GetArtist finds artist with the same name, or the first one which name contains it - like search of genius does
+func (s *LocalLyricsService) GetArtist(artistName string) (Artist, error) {
+	for _, artist := range s.artists {
+		if strings.EqualFold(artist.Name, artistName) {
+			return artist, nil
+		}
+	}
+	for _, artist := range s.artists {
+		if strings.Contains(strings.ToLower(artist.Name), strings.ToLower(artistName)) {
+			return artist, nil
+		}
+	}
+	return Artist{}, fmt.Errorf("%w: %q", ArtistNotFoundError, artistName)
+}
+
+func (s *LocalLyricsService) GetSongsByArtist(artistName string) ([]Song, error) {
+	artist, err := s.GetArtist(artistName)
+	if err != nil {
+		return nil, err
+	}
+
+	var songs []Song
+	for _, song := range s.songs {
+		if strings.EqualFold(song.Info.AuthorName, artist.Name) {
+			songs = append(songs, song)
+		}
+	}
+	return songs, nil
+}
+
+func (s *LocalLyricsService) GetSongsInfosByArtist(artistName string) ([]SongInfo, error) {
+	songs, err := s.GetSongsByArtist(artistName)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []SongInfo
+	for _, song := range songs {
+		infos = append(infos, song.Info)
+	}
+	return infos, nil
+}
+
+func (s *LocalLyricsService) songByID(id int) (Song, error) {
+	for _, song := range s.songs {
+		if song.Info.GeniusID == id {
+			return song, nil
+		}
+	}
+	return Song{}, fmt.Errorf("%w: %d", SongNotFoundError, id)
+}
+
+// GetSongByName finds song with the same title, or the first one which title contains the name
+func (s *LocalLyricsService) GetSongByName(name string) (Song, error) {
+	for _, song := range s.songs {
+		if strings.EqualFold(song.Info.Title, name) {
+			return song, nil
+		}
+	}
+	for _, song := range s.songs {
+		if strings.Contains(strings.ToLower(song.Info.Title), strings.ToLower(name)) {
+			return song, nil
+		}
+	}
+	return Song{}, fmt.Errorf("%w: %q", SongNotFoundError, name)
+}
+
+func (s *LocalLyricsService) GetSongInfoByName(name string) (SongInfo, error) {
+	song, err := s.GetSongByName(name)
+	return song.Info, err
+}
+
+func (s *LocalLyricsService) GetSongInfoByID(id int) (SongInfo, error) {
+	song, err := s.songByID(id)
+	return song.Info, err
+}
+
+func (s *LocalLyricsService) GetSongFromInfo(songInfo SongInfo) (Song, error) {
+	return s.songByID(songInfo.GeniusID)
+}
+
+func (s *LocalLyricsService) GetSongsFromInfos(songInfos []SongInfo) ([]Song, error) {
+	var songs []Song
+	for _, songInfo := range songInfos {
+		song, err := s.songByID(songInfo.GeniusID)
+		if err != nil {
+			return []Song{}, err
+		}
+		songs = append(songs, song)
+	}
+	return songs, nil
+}
+
+// GetSongsFromSongInfos skips unknown songs, like the genius one skips songs which couldn't be downloaded
+func (s *LocalLyricsService) GetSongsFromSongInfos(songInfos []SongInfo) ([]Song, error) {
+	var songs []Song
+	for _, songInfo := range songInfos {
+		if song, err := s.songByID(songInfo.GeniusID); err == nil {
+			songs = append(songs, song)
+		}
+	}
+	return songs, nil
+}
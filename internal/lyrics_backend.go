@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var OfflineWithoutLyricsError = errors.New("offline mode needs LYRICS_DIR or DATABASE_DRIVER with stored lyrics")
+
+// LyricsBackend is the lyrics service picked by config with everything it's built from:
+// files of LYRICS_DIR, or genius with optional cache and database, or only the database when OFFLINE is set
+type LyricsBackend struct {
+	LyricsService LyricsService
+	// SyncService is nil without database and offline
+	SyncService   SyncService
+	ExportService ExportService
+	// ImportService is nil without database
+	ImportService ImportService
+	// SearchService searches the stored lyrics or files of LYRICS_DIR, it's nil without both of them
+	SearchService SearchService
+	// Repository is nil without database
+	Repository LyricsRepository
+	// JobRepository keeps jobs in the database, jobs are kept in memory without it
+	JobRepository JobRepository
+}
+
+func NewLyricsBackend(cfg *config.Config, logger *log.Entry) (*LyricsBackend, error) {
+	if cfg.LyricsDir != "" {
+		songs, err := LoadLyricsDir(cfg.LyricsDir)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("%d songs loaded from %s", len(songs), cfg.LyricsDir)
+		lyricsService := NewLocalLyricsService(cfg, songs, logger)
+		index := NewInvertedIndex()
+		for _, song := range songs {
+			index.Add(IndexedSong{GeniusID: song.Info.GeniusID, Title: song.Info.Title, Artist: song.Info.AuthorName, PagePath: song.Info.PageEndpoint}, song.Lyrics)
+		}
+		return &LyricsBackend{LyricsService: lyricsService, ExportService: NewExportService(cfg, lyricsService, nil, logger), SearchService: index,
+			JobRepository: NewMemoryJobRepository()}, nil
+	}
+
+	backend := &LyricsBackend{JobRepository: NewMemoryJobRepository()}
+	var geniusProvider GeniusProvider
+	var internalGeniusProvider *InternalGeniusProvider
+	if cfg.Offline {
+		if cfg.DatabaseDriver == "" {
+			return nil, OfflineWithoutLyricsError
+		}
+		geniusProvider = &OfflineGeniusProvider{}
+	} else {
+		internalGeniusProvider = NewGeniusProvider(utils.CreateHttpClient(cfg), cfg, logger)
+		geniusProvider = internalGeniusProvider
+
+		cache, err := NewCache(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			geniusProvider = NewCachedGeniusProvider(cfg, geniusProvider, cache, logger)
+		}
+	}
+
+	if cfg.DatabaseDriver != "" {
+		sqlRepository, err := NewLyricsRepository(cfg.DatabaseDriver, cfg.DatabaseDSN)
+		if err != nil {
+			return nil, err
+		}
+		// stored lyrics are indexed by the first search, everything saved by sync, downloads and imports goes through the index
+		repository := NewIndexedLyricsRepository(sqlRepository, logger)
+		backend.Repository, backend.SearchService, backend.JobRepository = repository, repository, sqlRepository
+		backend.ImportService = NewImportService(cfg, repository, logger)
+		// sync has to see the current listing of genius, not the stored one
+		if internalGeniusProvider != nil {
+			backend.SyncService = NewSyncService(cfg, internalGeniusProvider, repository, logger)
+		}
+		geniusProvider = NewStoredGeniusProvider(cfg, geniusProvider, repository, logger)
+	}
+
+	backend.LyricsService = NewLyricsService(cfg, geniusProvider, logger)
+	backend.ExportService = NewExportService(cfg, backend.LyricsService, backend.Repository, logger)
+	return backend, nil
+}
+
+func (b *LyricsBackend) Close() error {
+	if b.Repository == nil {
+		return nil
+	}
+	return b.Repository.Close()
+}
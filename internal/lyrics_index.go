@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var EmptyQueryError = errors.New("empty query")
+
+const maxSnippets = 3
+
+type IndexedSong struct {
+	GeniusID int    `json:"genius_id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	PagePath string `json:"page_path"`
+}
+
+func indexedSongFromGenius(songInfo GeniusSongInfo) IndexedSong {
+	return IndexedSong{GeniusID: songInfo.ID, Title: songInfo.FullTitle, Artist: songInfo.PrimaryArtist.Name, PagePath: songInfo.PagePath}
+}
+
+type LyricsSearchHit struct {
+	Song IndexedSong `json:"song"`
+	// Matches is the number of occurrences of the word or phrase in the song
+	Matches  int      `json:"matches"`
+	Snippets []string `json:"snippets"`
+}
+
+type LyricsSearchResult struct {
+	Query string `json:"query"`
+	// Total is the number of found songs, Hits are limited
+	Total int               `json:"total"`
+	Hits  []LyricsSearchHit `json:"hits"`
+}
+
+type SearchService interface {
+	SearchLyrics(query string, limit int) (LyricsSearchResult, error)
+}
+
+// indexedDocument keeps lines of the song, so snippets can be shown without reading lyrics again
+type indexedDocument struct {
+	song  IndexedSong
+	lines []string
+	// tokenLines is the line of every token, by position
+	tokenLines []int
+	terms      []string
+}
+
+var _ SearchService = &InvertedIndex{}
+
+// InvertedIndex maps every word to songs and positions of it, positions are numbers of tokens (the same ones as
+// Lyrics.Tokens returns), so phrases are words on consecutive positions
+type InvertedIndex struct {
+	mutex     sync.RWMutex
+	documents map[int]*indexedDocument
+	postings  map[string]map[int][]int
+}
+
+func NewInvertedIndex() *InvertedIndex {
+	return &InvertedIndex{documents: make(map[int]*indexedDocument), postings: make(map[string]map[int][]int)}
+}
+
+// LoadInvertedIndex indexes all of the stored lyrics
+func LoadInvertedIndex(repository LyricsRepository) (*InvertedIndex, error) {
+	index := NewInvertedIndex()
+	err := repository.ForEachLyrics(func(songInfo GeniusSongInfo, lyrics Lyrics) error {
+		index.Add(indexedSongFromGenius(songInfo), lyrics)
+		return nil
+	})
+	return index, err
+}
+
+// Add indexes the song, the previous version of it is replaced
+func (i *InvertedIndex) Add(song IndexedSong, lyrics Lyrics) {
+	document := &indexedDocument{song: song}
+	positions := make(map[string][]int)
+	for _, line := range lyrics.Lines() {
+		for _, token := range lineTokens(line) {
+			if _, ok := positions[token]; ok == false {
+				document.terms = append(document.terms, token)
+			}
+			positions[token] = append(positions[token], len(document.tokenLines))
+			document.tokenLines = append(document.tokenLines, len(document.lines))
+		}
+		document.lines = append(document.lines, line)
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.remove(song.GeniusID)
+	i.documents[song.GeniusID] = document
+	for term, termPositions := range positions {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[int][]int)
+		}
+		i.postings[term][song.GeniusID] = termPositions
+	}
+}
+
+// UpdateSong changes title, artist etc. of already indexed song, false is returned when the song isn't indexed
+func (i *InvertedIndex) UpdateSong(song IndexedSong) bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	document, ok := i.documents[song.GeniusID]
+	if ok {
+		document.song = song
+	}
+	return ok
+}
+
+func (i *InvertedIndex) Remove(songIDs []int) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, id := range songIDs {
+		i.remove(id)
+	}
+}
+
+func (i *InvertedIndex) remove(songID int) {
+	document, ok := i.documents[songID]
+	if ok == false {
+		return
+	}
+	for _, term := range document.terms {
+		delete(i.postings[term], songID)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.documents, songID)
+}
+
+func (i *InvertedIndex) Len() int {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return len(i.documents)
+}
+
+// SearchLyrics finds songs with the word or phrase, the query is tokenized like lyrics, so case and punctuation don't matter.
+// Songs with the most matches go first, limit 0 returns all of them
+func (i *InvertedIndex) SearchLyrics(query string, limit int) (LyricsSearchResult, error) {
+	result := LyricsSearchResult{Query: query, Hits: []LyricsSearchHit{}}
+	terms := lineTokens(query)
+	if len(terms) == 0 {
+		return result, EmptyQueryError
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	for songID, firstPositions := range i.postings[terms[0]] {
+		var starts []int
+		for _, start := range firstPositions {
+			if i.isPhraseAt(songID, terms, start) {
+				starts = append(starts, start)
+			}
+		}
+		if len(starts) > 0 {
+			result.Hits = append(result.Hits, i.hit(songID, starts, len(terms)))
+		}
+	}
+
+	sort.Slice(result.Hits, func(a, b int) bool {
+		if result.Hits[a].Matches != result.Hits[b].Matches {
+			return result.Hits[a].Matches > result.Hits[b].Matches
+		}
+		return result.Hits[a].Song.GeniusID < result.Hits[b].Song.GeniusID
+	})
+	result.Total = len(result.Hits)
+	if limit > 0 && len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+	}
+	return result, nil
+}
+
+func (i *InvertedIndex) isPhraseAt(songID int, terms []string, start int) bool {
+	for offset, term := range terms[1:] {
+		positions := i.postings[term][songID]
+		// positions are sorted, they are appended in order by Add
+		j := sort.SearchInts(positions, start+offset+1)
+		if j == len(positions) || positions[j] != start+offset+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// hit shows lines of the first matches as snippets, phrase which goes over lines shows all of them
+func (i *InvertedIndex) hit(songID int, starts []int, length int) LyricsSearchHit {
+	document := i.documents[songID]
+	hit := LyricsSearchHit{Song: document.song, Matches: len(starts), Snippets: []string{}}
+	lastLine := -1
+	for _, start := range starts {
+		first, last := document.tokenLines[start], document.tokenLines[start+length-1]
+		if first <= lastLine {
+			continue
+		}
+		hit.Snippets = append(hit.Snippets, strings.Join(document.lines[first:last+1], " / "))
+		lastLine = last
+		if len(hit.Snippets) == maxSnippets {
+			break
+		}
+	}
+	return hit
+}
+
+var _ LyricsRepository = &IndexedLyricsRepository{}
+var _ SearchService = &IndexedLyricsRepository{}
+
+// IndexedLyricsRepository searches the stored lyrics, they are indexed by the first search and the index is kept up to date
+// with everything saved to the repository - by sync, downloads and imports of this process. Other processes (ex. CLI sync
+// or import while the API is running) don't reach the index, they are seen after restart
+type IndexedLyricsRepository struct {
+	LyricsRepository
+	// mutex guards index, which is nil until the first search, writes wait for it when it's being loaded
+	mutex  sync.Mutex
+	index  *InvertedIndex
+	logger *log.Entry
+}
+
+func NewIndexedLyricsRepository(repository LyricsRepository, logger *log.Entry) *IndexedLyricsRepository {
+	return &IndexedLyricsRepository{LyricsRepository: repository, logger: logger}
+}
+
+func (r *IndexedLyricsRepository) SearchLyrics(query string, limit int) (LyricsSearchResult, error) {
+	index, err := r.loadedIndex()
+	if err != nil {
+		return LyricsSearchResult{Query: query, Hits: []LyricsSearchHit{}}, err
+	}
+	return index.SearchLyrics(query, limit)
+}
+
+// loadedIndex indexes the stored lyrics when it hasn't been done yet, the failed load is retried by the next search
+func (r *IndexedLyricsRepository) loadedIndex() (*InvertedIndex, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.index == nil {
+		index, err := LoadInvertedIndex(r.LyricsRepository)
+		if err != nil {
+			return nil, err
+		}
+		r.logger.Infof("%d stored songs indexed", index.Len())
+		r.index = index
+	}
+	return r.index, nil
+}
+
+// withIndex runs fn only when the index has been loaded, otherwise the change is read from the repository by loading
+func (r *IndexedLyricsRepository) withIndex(fn func(index *InvertedIndex)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.index != nil {
+		fn(r.index)
+	}
+}
+
+func (r *IndexedLyricsRepository) SaveLyrics(songID int, lyrics Lyrics) error {
+	if err := r.LyricsRepository.SaveLyrics(songID, lyrics); err != nil {
+		return err
+	}
+	r.indexLyrics(songID, lyrics)
+	return nil
+}
+
+func (r *IndexedLyricsRepository) SaveStoredLyrics(songID int, stored StoredLyrics) error {
+	if err := r.LyricsRepository.SaveStoredLyrics(songID, stored); err != nil {
+		return err
+	}
+	r.indexLyrics(songID, stored.Lyrics)
+	return nil
+}
+
+// indexLyrics indexes song with empty info when it isn't stored yet, the info is filled when songs are saved
+func (r *IndexedLyricsRepository) indexLyrics(songID int, lyrics Lyrics) {
+	songInfo, err := r.LyricsRepository.GetSongInfo(songID)
+	if err != nil {
+		songInfo = GeniusSongInfo{ID: songID}
+	}
+	r.withIndex(func(index *InvertedIndex) {
+		index.Add(indexedSongFromGenius(songInfo), lyrics)
+	})
+}
+
+func (r *IndexedLyricsRepository) SaveSongInfos(songs []GeniusSongInfo) error {
+	if err := r.LyricsRepository.SaveSongInfos(songs); err != nil {
+		return err
+	}
+	r.indexSongs(songs)
+	return nil
+}
+
+func (r *IndexedLyricsRepository) SaveArtistSongInfos(artistID int, songs []GeniusSongInfo) error {
+	if err := r.LyricsRepository.SaveArtistSongInfos(artistID, songs); err != nil {
+		return err
+	}
+	r.indexSongs(songs)
+	return nil
+}
+
+// indexSongs updates indexed songs, songs which have been removed and are listed again are indexed again
+func (r *IndexedLyricsRepository) indexSongs(songs []GeniusSongInfo) {
+	r.withIndex(func(index *InvertedIndex) {
+		for _, songInfo := range songs {
+			if index.UpdateSong(indexedSongFromGenius(songInfo)) {
+				continue
+			}
+			if stored, err := r.LyricsRepository.GetLyrics(songInfo.ID); err == nil {
+				index.Add(indexedSongFromGenius(songInfo), stored.Lyrics)
+			}
+		}
+	})
+}
+
+func (r *IndexedLyricsRepository) MarkSongsRemoved(songIDs []int) error {
+	if err := r.LyricsRepository.MarkSongsRemoved(songIDs); err != nil {
+		return err
+	}
+	r.withIndex(func(index *InvertedIndex) {
+		index.Remove(songIDs)
+	})
+	return nil
+}
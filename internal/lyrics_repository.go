@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DatabaseSQLite   = "sqlite"
+	DatabasePostgres = "postgres"
+)
+
+var (
+	NotStoredError             = errors.New("not stored")
+	UnknownDatabaseDriverError = errors.New("unknown database driver")
+)
+
+// migrations are applied in order, the number of applied ones is kept in schema_migrations,
+// so never change already released migration - add the next one. New tables have to be dropped by tests of Postgres too
+var migrations = []string{
+	`CREATE TABLE artists (
+		query TEXT PRIMARY KEY,
+		id BIGINT NOT NULL,
+		api_path TEXT NOT NULL,
+		name TEXT NOT NULL,
+		fetched_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE songs (
+		id BIGINT PRIMARY KEY,
+		path TEXT NOT NULL,
+		full_title TEXT NOT NULL,
+		lyrics_state TEXT NOT NULL,
+		artist_id BIGINT NOT NULL,
+		artist_api_path TEXT NOT NULL,
+		artist_name TEXT NOT NULL,
+		fetched_at BIGINT NOT NULL
+	)`,
+	`CREATE INDEX songs_artist_id ON songs (artist_id)`,
+	`CREATE TABLE artist_songs (
+		artist_id BIGINT PRIMARY KEY,
+		fetched_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE lyrics (
+		song_id BIGINT PRIMARY KEY,
+		lyrics TEXT NOT NULL,
+		fetched_at BIGINT NOT NULL
+	)`,
+	`ALTER TABLE songs ADD COLUMN removed_at BIGINT`,
+	`CREATE TABLE jobs (
+		id TEXT PRIMARY KEY,
+		spec TEXT NOT NULL,
+		status TEXT NOT NULL,
+		progress TEXT NOT NULL,
+		error TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		started_at BIGINT,
+		finished_at BIGINT,
+		result TEXT
+	)`,
+	`CREATE INDEX jobs_status ON jobs (status)`,
+}
+
+type StoredArtist struct {
+	Artist    GeniusArtist
+	FetchedAt time.Time
+}
+
+type StoredSongInfos struct {
+	Songs     []GeniusSongInfo
+	FetchedAt time.Time
+}
+
+type StoredLyrics struct {
+	Lyrics    Lyrics
+	FetchedAt time.Time
+}
+
+// LyricsRepository keeps everything which has been downloaded from genius, getters return NotStoredError
+// when there is nothing saved yet
+type LyricsRepository interface {
+	// GetArtist finds artist by the name it has been searched with
+	GetArtist(query string) (StoredArtist, error)
+	SaveArtist(query string, artist GeniusArtist) error
+
+	GetSongInfo(songID int) (GeniusSongInfo, error)
+	// GetArtistSongInfos returns the whole listing of songs of the artist, without removed songs. FetchedAt is zero when
+	// songs of the artist have been saved without the listing (ex. imported), so it's only a part of it
+	GetArtistSongInfos(artistID int) (StoredSongInfos, error)
+	SaveSongInfos(songs []GeniusSongInfo) error
+	SaveArtistSongInfos(artistID int, songs []GeniusSongInfo) error
+	// MarkSongsRemoved keeps songs which have disappeared from genius, but they aren't listed anymore
+	MarkSongsRemoved(songIDs []int) error
+
+	GetLyrics(songID int) (StoredLyrics, error)
+	SaveLyrics(songID int, lyrics Lyrics) error
+	// SaveStoredLyrics keeps the given fetch date, ex. of imported lyrics
+	SaveStoredLyrics(songID int, stored StoredLyrics) error
+	// ForEachLyrics walks all of the stored lyrics of songs which haven't been removed, ordered by ID. Song info is empty
+	// (apart from ID) when it hasn't been stored, fn mustn't use the repository
+	ForEachLyrics(fn func(songInfo GeniusSongInfo, lyrics Lyrics) error) error
+
+	Close() error
+}
+
+var _ LyricsRepository = &SQLLyricsRepository{}
+
+// SQLLyricsRepository works with SQLite and Postgres, queries are written with `?` placeholders and rebound
+// to `$1` for Postgres, upserts use `ON CONFLICT` which both of them support
+type SQLLyricsRepository struct {
+	db     *sql.DB
+	driver string
+	now    func() time.Time
+}
+
+// NewLyricsRepository opens database of the driver and migrates it, dsn of SQLite is path of the file
+func NewLyricsRepository(driver string, dsn string) (*SQLLyricsRepository, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case DatabaseSQLite:
+		db, err = sql.Open("sqlite", dsn)
+		if err == nil {
+			// SQLite allows only one writer, concurrent downloads would fail with "database is locked" otherwise
+			db.SetMaxOpenConns(1)
+		}
+	case DatabasePostgres:
+		db, err = sql.Open("postgres", dsn)
+	default:
+		return nil, fmt.Errorf("%w: %q", UnknownDatabaseDriverError, driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	repository := &SQLLyricsRepository{db: db, driver: driver, now: time.Now}
+	if err := repository.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return repository, nil
+}
+
+func (r *SQLLyricsRepository) Close() error {
+	return r.db.Close()
+}
+
+// rebind replaces `?` placeholders with `$1`, `$2`... for Postgres
+func (r *SQLLyricsRepository) rebind(query string) string {
+	if r.driver != DatabasePostgres {
+		return query
+	}
+	var builder strings.Builder
+	n := 0
+	for _, char := range query {
+		if char == '?' {
+			n++
+			builder.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+func (r *SQLLyricsRepository) migrate() error {
+	if _, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL)`); err != nil {
+		return err
+	}
+
+	var version int
+	if err := r.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := r.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(r.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notStored(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotStoredError
+	}
+	return err
+}
+
+func (r *SQLLyricsRepository) GetArtist(query string) (StoredArtist, error) {
+	var stored StoredArtist
+	var fetchedAt int64
+	err := r.db.QueryRow(r.rebind(`SELECT id, api_path, name, fetched_at FROM artists WHERE query = ?`), strings.ToLower(query)).
+		Scan(&stored.Artist.ID, &stored.Artist.ApiPath, &stored.Artist.Name, &fetchedAt)
+	if err != nil {
+		return StoredArtist{}, notStored(err)
+	}
+	stored.FetchedAt = time.Unix(fetchedAt, 0)
+	return stored, nil
+}
+
+func (r *SQLLyricsRepository) SaveArtist(query string, artist GeniusArtist) error {
+	_, err := r.db.Exec(r.rebind(`INSERT INTO artists (query, id, api_path, name, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (query) DO UPDATE SET id = excluded.id, api_path = excluded.api_path, name = excluded.name, fetched_at = excluded.fetched_at`),
+		strings.ToLower(query), artist.ID, artist.ApiPath, artist.Name, r.now().Unix())
+	return err
+}
+
+const songColumns = `id, path, full_title, lyrics_state, artist_id, artist_api_path, artist_name`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSongInfo(row rowScanner) (GeniusSongInfo, error) {
+	var song GeniusSongInfo
+	var state string
+	err := row.Scan(&song.ID, &song.PagePath, &song.FullTitle, &state,
+		&song.PrimaryArtist.ID, &song.PrimaryArtist.ApiPath, &song.PrimaryArtist.Name)
+	song.LyricsState = LyricsState(state)
+	return song, err
+}
+
+func (r *SQLLyricsRepository) GetSongInfo(songID int) (GeniusSongInfo, error) {
+	song, err := scanSongInfo(r.db.QueryRow(r.rebind(`SELECT `+songColumns+` FROM songs WHERE id = ?`), songID))
+	if err != nil {
+		return GeniusSongInfo{}, notStored(err)
+	}
+	return song, nil
+}
+
+func (r *SQLLyricsRepository) GetArtistSongInfos(artistID int) (StoredSongInfos, error) {
+	var fetchedAt int64
+	err := r.db.QueryRow(r.rebind(`SELECT fetched_at FROM artist_songs WHERE artist_id = ?`), artistID).Scan(&fetchedAt)
+	listed := err == nil
+	if err != nil && errors.Is(err, sql.ErrNoRows) == false {
+		return StoredSongInfos{}, err
+	}
+
+	rows, err := r.db.Query(r.rebind(`SELECT `+songColumns+` FROM songs WHERE artist_id = ? AND removed_at IS NULL ORDER BY id`), artistID)
+	if err != nil {
+		return StoredSongInfos{}, err
+	}
+	defer rows.Close()
+
+	stored := StoredSongInfos{}
+	if listed {
+		stored.FetchedAt = time.Unix(fetchedAt, 0)
+	}
+	for rows.Next() {
+		song, err := scanSongInfo(rows)
+		if err != nil {
+			return StoredSongInfos{}, err
+		}
+		stored.Songs = append(stored.Songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return StoredSongInfos{}, err
+	}
+	if listed == false && stored.Songs == nil {
+		return StoredSongInfos{}, NotStoredError
+	}
+	return stored, nil
+}
+
+func (r *SQLLyricsRepository) saveSongInfos(tx *sql.Tx, songs []GeniusSongInfo) error {
+	statement, err := tx.Prepare(r.rebind(`INSERT INTO songs (` + songColumns + `, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET path = excluded.path, full_title = excluded.full_title, lyrics_state = excluded.lyrics_state,
+		artist_id = excluded.artist_id, artist_api_path = excluded.artist_api_path, artist_name = excluded.artist_name, fetched_at = excluded.fetched_at,
+		removed_at = NULL`))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	now := r.now().Unix()
+	for _, song := range songs {
+		_, err := statement.Exec(song.ID, song.PagePath, song.FullTitle, string(song.LyricsState),
+			song.PrimaryArtist.ID, song.PrimaryArtist.ApiPath, song.PrimaryArtist.Name, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLLyricsRepository) SaveSongInfos(songs []GeniusSongInfo) error {
+	return r.inTransaction(func(tx *sql.Tx) error {
+		return r.saveSongInfos(tx, songs)
+	})
+}
+
+// SaveArtistSongInfos saves songs and marks the listing of the artist as fetched
+func (r *SQLLyricsRepository) SaveArtistSongInfos(artistID int, songs []GeniusSongInfo) error {
+	return r.inTransaction(func(tx *sql.Tx) error {
+		if err := r.saveSongInfos(tx, songs); err != nil {
+			return err
+		}
+		_, err := tx.Exec(r.rebind(`INSERT INTO artist_songs (artist_id, fetched_at) VALUES (?, ?)
+			ON CONFLICT (artist_id) DO UPDATE SET fetched_at = excluded.fetched_at`), artistID, r.now().Unix())
+		return err
+	})
+}
+
+func (r *SQLLyricsRepository) MarkSongsRemoved(songIDs []int) error {
+	return r.inTransaction(func(tx *sql.Tx) error {
+		now := r.now().Unix()
+		for _, id := range songIDs {
+			if _, err := tx.Exec(r.rebind(`UPDATE songs SET removed_at = ? WHERE id = ?`), now, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLLyricsRepository) GetLyrics(songID int) (StoredLyrics, error) {
+	var stored StoredLyrics
+	var fetchedAt int64
+	err := r.db.QueryRow(r.rebind(`SELECT lyrics, fetched_at FROM lyrics WHERE song_id = ?`), songID).Scan(&stored.Lyrics, &fetchedAt)
+	if err != nil {
+		return StoredLyrics{}, notStored(err)
+	}
+	stored.FetchedAt = time.Unix(fetchedAt, 0)
+	return stored, nil
+}
+
+func (r *SQLLyricsRepository) SaveLyrics(songID int, lyrics Lyrics) error {
+	return r.SaveStoredLyrics(songID, StoredLyrics{Lyrics: lyrics, FetchedAt: r.now()})
+}
+
+func (r *SQLLyricsRepository) SaveStoredLyrics(songID int, stored StoredLyrics) error {
+	_, err := r.db.Exec(r.rebind(`INSERT INTO lyrics (song_id, lyrics, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT (song_id) DO UPDATE SET lyrics = excluded.lyrics, fetched_at = excluded.fetched_at`),
+		songID, string(stored.Lyrics), stored.FetchedAt.Unix())
+	return err
+}
+
+func (r *SQLLyricsRepository) ForEachLyrics(fn func(songInfo GeniusSongInfo, lyrics Lyrics) error) error {
+	rows, err := r.db.Query(`SELECT lyrics.song_id, COALESCE(songs.path, ''), COALESCE(songs.full_title, ''), COALESCE(songs.lyrics_state, ''),
+		COALESCE(songs.artist_id, 0), COALESCE(songs.artist_api_path, ''), COALESCE(songs.artist_name, ''), lyrics.lyrics
+		FROM lyrics LEFT JOIN songs ON songs.id = lyrics.song_id WHERE songs.removed_at IS NULL ORDER BY lyrics.song_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var song GeniusSongInfo
+		var state string
+		var lyrics Lyrics
+		err := rows.Scan(&song.ID, &song.PagePath, &song.FullTitle, &state,
+			&song.PrimaryArtist.ID, &song.PrimaryArtist.ApiPath, &song.PrimaryArtist.Name, &lyrics)
+		if err != nil {
+			return err
+		}
+		song.LyricsState = LyricsState(state)
+		if err := fn(song, lyrics); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *SQLLyricsRepository) inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"github.com/marosiak/WordFinder/config"
 	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
 type SongInfo struct {
@@ -15,6 +16,30 @@ type SongInfo struct {
 type Song struct {
 	Info   SongInfo
 	Lyrics Lyrics
+	// Languages are detected from lyrics, the main language first
+	Languages []LanguageScore
+}
+
+func newSong(info SongInfo, lyrics Lyrics) Song {
+	return Song{Info: info, Lyrics: lyrics, Languages: DefaultLanguageDetector().Detect(lyrics)}
+}
+
+// Language returns the main language of the song, empty when it's unknown
+func (s Song) Language() string {
+	if len(s.Languages) == 0 {
+		return ""
+	}
+	return s.Languages[0].Language
+}
+
+// HasLanguage tells if any part of the song is written in the language
+func (s Song) HasLanguage(language string) bool {
+	for _, score := range s.Languages {
+		if strings.EqualFold(score.Language, language) {
+			return true
+		}
+	}
+	return false
 }
 
 type Artist struct {
@@ -83,15 +108,12 @@ func (s *InternalLyricsService) GetSongByName(songName string) (Song, error) {
 		return Song{}, err
 	}
 
-	return Song{
-		Lyrics: geniusSong.Lyrics,
-		Info: SongInfo{
-			GeniusID:     geniusSong.Info.ID,
-			AuthorName:   geniusSong.Info.PrimaryArtist.Name,
-			Title:        geniusSong.Info.FullTitle,
-			PageEndpoint: geniusSong.Info.PagePath,
-		},
-	}, nil
+	return newSong(SongInfo{
+		GeniusID:     geniusSong.Info.ID,
+		AuthorName:   geniusSong.Info.PrimaryArtist.Name,
+		Title:        geniusSong.Info.FullTitle,
+		PageEndpoint: geniusSong.Info.PagePath,
+	}, geniusSong.Lyrics), nil
 }
 
 func (s *InternalLyricsService) GetSongFromInfo(songInfo SongInfo) (Song, error) {
@@ -100,10 +122,7 @@ func (s *InternalLyricsService) GetSongFromInfo(songInfo SongInfo) (Song, error)
 		return Song{}, err
 	}
 
-	return Song{
-		Info:   songInfo,
-		Lyrics: song.Lyrics,
-	}, nil
+	return newSong(songInfo, song.Lyrics), nil
 }
 
 func (s *InternalLyricsService) GetSongsFromInfos(songInfos []SongInfo) ([]Song, error) {
@@ -115,10 +134,7 @@ func (s *InternalLyricsService) GetSongsFromInfos(songInfos []SongInfo) ([]Song,
 			return []Song{}, err
 		}
 
-		songs = append(songs, Song{
-			Info:   songInfo,
-			Lyrics: song.Lyrics,
-		})
+		songs = append(songs, newSong(songInfo, song.Lyrics))
 	}
 
 	return songs, nil
@@ -152,6 +168,7 @@ func (s *InternalLyricsService) GetSongsInfosByArtist(artistName string) ([]Song
 			AuthorName:   song.PrimaryArtist.Name,
 			Title:        song.FullTitle,
 			PageEndpoint: song.PagePath,
+			GeniusID:     song.ID,
 		})
 	}
 
@@ -171,15 +188,12 @@ func (s *InternalLyricsService) GetSongsFromSongInfos(songInfos []SongInfo) ([]S
 
 	var songs []Song
 	for _, geniusSong := range geniusSongs {
-		songs = append(songs, Song{
-			Info: SongInfo{
-				AuthorName:   geniusSong.Info.PrimaryArtist.Name,
-				Title:        geniusSong.Info.FullTitle,
-				PageEndpoint: geniusSong.Info.PagePath,
-				GeniusID:     geniusSong.Info.ID,
-			},
-			Lyrics: geniusSong.Lyrics,
-		})
+		songs = append(songs, newSong(SongInfo{
+			AuthorName:   geniusSong.Info.PrimaryArtist.Name,
+			Title:        geniusSong.Info.FullTitle,
+			PageEndpoint: geniusSong.Info.PagePath,
+			GeniusID:     geniusSong.Info.ID,
+		}, geniusSong.Lyrics))
 	}
 	return songs, nil
 }
@@ -197,14 +211,12 @@ func (s *InternalLyricsService) GetSongsByArtist(artistName string) ([]Song, err
 
 	var songs []Song
 	for _, geniusSong := range geniusSongs {
-		songs = append(songs, Song{
-			Info: SongInfo{
-				AuthorName:   geniusSong.Info.PrimaryArtist.Name,
-				Title:        geniusSong.Info.FullTitle,
-				PageEndpoint: geniusSong.Info.PagePath,
-			},
-			Lyrics: geniusSong.Lyrics,
-		})
+		songs = append(songs, newSong(SongInfo{
+			AuthorName:   geniusSong.Info.PrimaryArtist.Name,
+			Title:        geniusSong.Info.FullTitle,
+			PageEndpoint: geniusSong.Info.PagePath,
+			GeniusID:     geniusSong.Info.ID,
+		}, geniusSong.Lyrics))
 	}
 	return songs, nil
 }
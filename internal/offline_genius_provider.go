@@ -0,0 +1,46 @@
+package internal
+
+import "errors"
+
+var OfflineError = errors.New("genius is not available offline")
+
+var _ GeniusProvider = &OfflineGeniusProvider{}
+
+// OfflineGeniusProvider never downloads anything, put StoredGeniusProvider in front of it to use only stored lyrics
+type OfflineGeniusProvider struct{}
+
+func (p *OfflineGeniusProvider) Search(query string) ([]GeniusSearchResult, error) {
+	return nil, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongInfoByID(id int) (GeniusSongInfo, error) {
+	return GeniusSongInfo{}, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongByID(id int) (GeniusSong, error) {
+	return GeniusSong{}, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongsByIDs(id []int) ([]GeniusSong, error) {
+	return nil, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongByName(name string) (GeniusSong, error) {
+	return GeniusSong{}, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetArtist(artistName string) (GeniusArtist, error) {
+	return GeniusArtist{}, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusSongInfo, error) {
+	return nil, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetSongsByArtistID(artistID int) ([]GeniusSong, error) {
+	return nil, OfflineError
+}
+
+func (p *OfflineGeniusProvider) GetLyrics(songInfo GeniusSongInfo) (Lyrics, error) {
+	return "", OfflineError
+}
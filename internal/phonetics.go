@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed pronunciations/*.txt
+var pronunciationsFS embed.FS
+
+type phone struct {
+	Sound    string
+	Vowel    bool
+	Stressed bool
+}
+
+type pronunciation []phone
+
+// vowels returns sounds of vowels only, one per syllable
+func (p pronunciation) vowels() []string {
+	var output []string
+	for _, phone := range p {
+		if phone.Vowel {
+			output = append(output, phone.Sound)
+		}
+	}
+	return output
+}
+
+// coda returns class of the last consonant after the last vowel, empty when the word ends with vowel
+func (p pronunciation) coda() string {
+	if len(p) == 0 || p[len(p)-1].Vowel {
+		return ""
+	}
+	return consonantClass(p[len(p)-1].Sound)
+}
+
+// tailLength returns how many syllables from the end belong to the rhyme, for english it starts on the last stressed
+// vowel, for polish on the penultimate one, because polish words are stressed there
+func (p pronunciation) tailLength(language string) int {
+	vowels := 0
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Vowel == false {
+			continue
+		}
+		vowels++
+		if language == "pl" && vowels == 2 {
+			return vowels
+		}
+		if language != "pl" && p[i].Stressed {
+			return vowels
+		}
+	}
+	if language != "pl" && vowels > 0 {
+		return 1
+	}
+	return vowels
+}
+
+var (
+	defaultPronunciationsOnce sync.Once
+	defaultPronunciations     map[string]pronunciation
+)
+
+// englishPronunciations returns bundled dictionary in CMU format, it's small, the rest of words is guessed from spelling
+func englishPronunciations() map[string]pronunciation {
+	defaultPronunciationsOnce.Do(func() {
+		defaultPronunciations = make(map[string]pronunciation)
+		file, err := pronunciationsFS.Open("pronunciations/en.txt")
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			var p pronunciation
+			for _, symbol := range fields[1:] {
+				sound := strings.TrimRight(symbol, "012")
+				p = append(p, phone{
+					Sound:    sound,
+					Vowel:    sound != symbol,
+					Stressed: strings.HasSuffix(symbol, "1") || strings.HasSuffix(symbol, "2"),
+				})
+			}
+			defaultPronunciations[strings.ToLower(fields[0])] = p
+		}
+	})
+	return defaultPronunciations
+}
+
+// pronounce returns phonemes of word, polish words are read from their spelling which is nearly phonetic
+func pronounce(word string, language string) pronunciation {
+	word = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+	if word == "" {
+		return nil
+	}
+
+	if language == "pl" {
+		return polishPronunciation(word)
+	}
+	if p, ok := englishPronunciations()[word]; ok {
+		return p
+	}
+	return englishSpelling(word)
+}
+
+// Syllables counts vowel sounds of the word
+func Syllables(word string, language string) int {
+	return len(pronounce(word, language).vowels())
+}
+
+// consonantClass groups consonants which sound alike at the end of a rhyme, like "t" and "d"
+func consonantClass(sound string) string {
+	switch sound {
+	case "P", "B":
+		return "P"
+	case "T", "D":
+		return "T"
+	case "K", "G", "X":
+		return "K"
+	case "F", "V", "W", "TH", "DH":
+		return "F"
+	case "S", "Z", "SH", "ZH", "CH", "JH", "C", "CZ", "SZ", "Ż", "Ź", "Ś", "Ć", "DZ", "DŻ", "DŹ":
+		return "S"
+	case "M", "N", "NG", "Ń":
+		return "N"
+	case "L", "Ł":
+		return "L"
+	case "H", "HH":
+		return "H"
+	case "J", "Y":
+		return "J"
+	}
+	return sound
+}
+
+var (
+	polishVowels       = map[rune]string{'a': "A", 'e': "E", 'i': "I", 'o': "O", 'u': "U", 'ó': "U", 'y': "Y", 'ą': "Ą", 'ę': "Ę"}
+	polishDigraphs     = map[string]string{"ch": "H", "cz": "CZ", "sz": "SZ", "rz": "Ż", "dz": "DZ", "dź": "DŹ", "dż": "DŻ"}
+	polishSoftened     = map[rune]string{'c': "Ć", 's': "Ś", 'z': "Ź", 'n': "Ń"}
+	englishVowels      = "aeiouy"
+	englishGroups      = []string{"eigh", "igh", "ough", "ear", "eer", "air", "oo", "ou", "ow", "oa", "ee", "ea", "ai", "ay", "ey", "ei", "oi", "oy", "au", "aw", "ue", "ew", "er", "ir", "ur"}
+	englishGroupSounds = map[string]string{
+		"eigh": "EY", "igh": "AY", "ough": "AO", "ear": "IH", "eer": "IH", "air": "EH", "oo": "UW", "ou": "AW", "ow": "OW",
+		"oa": "OW", "ee": "IY", "ea": "IY", "ai": "EY", "ay": "EY", "ey": "EY", "ei": "EY", "oi": "OY", "oy": "OY",
+		"au": "AO", "aw": "AO", "ue": "UW", "ew": "UW", "er": "ER", "ir": "ER", "ur": "ER",
+	}
+	englishShortVowels = map[byte]string{'a': "AE", 'e': "EH", 'i': "IH", 'o': "AA", 'u': "AH", 'y': "IH"}
+	englishLongVowels  = map[byte]string{'a': "EY", 'e': "IY", 'i': "AY", 'o': "OW", 'u': "UW", 'y': "AY"}
+	englishDigraphs    = map[string]string{"sh": "SH", "ch": "CH", "th": "TH", "ng": "NG", "ck": "K", "ph": "F", "gh": "G", "wh": "W", "qu": "K"}
+)
+
+func polishPronunciation(word string) pronunciation {
+	runes := []rune(word)
+	isVowel := func(i int) bool {
+		if i >= len(runes) {
+			return false
+		}
+		_, ok := polishVowels[runes[i]]
+		return ok
+	}
+
+	var p pronunciation
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		if i+1 < len(runes) {
+			if sound, ok := polishDigraphs[string(runes[i:i+2])]; ok {
+				p = append(p, phone{Sound: sound})
+				i++
+				continue
+			}
+			// "ci", "si", "zi", "ni" before vowel are soft consonants, the "i" isn't a syllable
+			if sound, ok := polishSoftened[char]; ok && runes[i+1] == 'i' && isVowel(i+2) {
+				p = append(p, phone{Sound: sound})
+				i++
+				continue
+			}
+		}
+		if sound, ok := polishVowels[char]; ok {
+			// "i" before vowel only softens previous consonant, like in "nie" or "się"
+			if char == 'i' && i > 0 && isVowel(i+1) {
+				continue
+			}
+			if char == 'ę' && i == len(runes)-1 {
+				sound = "E"
+			}
+			p = append(p, phone{Sound: sound, Vowel: true})
+			continue
+		}
+		p = append(p, phone{Sound: strings.ToUpper(string(char))})
+	}
+	return p
+}
+
+// englishSpelling guesses pronunciation of words missing in the dictionary, it's rough but vowels are usually right
+func englishSpelling(word string) pronunciation {
+	magicE := false
+	if len(word) > 3 && word[len(word)-1] == 'e' && strings.IndexByte(englishVowels, word[len(word)-2]) == -1 &&
+		strings.IndexByte(englishVowels, word[len(word)-3]) != -1 {
+		word = word[:len(word)-1]
+		magicE = true
+	}
+
+	var p pronunciation
+	for i := 0; i < len(word); i++ {
+		rest := word[i:]
+		matched := false
+		for _, group := range englishGroups {
+			if strings.HasPrefix(rest, group) {
+				// "er" in "very" isn't a single sound, the "r" starts the next syllable
+				if strings.HasSuffix(group, "r") && len(rest) > len(group) && strings.IndexByte(englishVowels, rest[len(group)]) != -1 {
+					continue
+				}
+				p = append(p, phone{Sound: englishGroupSounds[group], Vowel: true})
+				if strings.HasSuffix(group, "r") && group != "er" && group != "ir" && group != "ur" {
+					p = append(p, phone{Sound: "R"})
+				}
+				i += len(group) - 1
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		char := word[i]
+		if char >= unicode.MaxASCII {
+			continue
+		}
+		if char == 'y' && i == 0 {
+			p = append(p, phone{Sound: "Y"})
+			continue
+		}
+		if strings.IndexByte(englishVowels, char) != -1 {
+			sound := englishShortVowels[char]
+			last := i == len(word)-1
+			switch {
+			case last && char == 'y' && len(p.vowels()) == 0:
+				sound = englishLongVowels[char]
+			case last && (char == 'y' || char == 'i'):
+				sound = "IY"
+			case last && (char == 'o' || char == 'e') && len(p.vowels()) == 0:
+				sound = englishLongVowels[char]
+			case magicE && i == len(word)-2:
+				sound = englishLongVowels[char]
+			}
+			p = append(p, phone{Sound: sound, Vowel: true})
+			continue
+		}
+
+		if i+1 < len(word) {
+			if sound, ok := englishDigraphs[word[i:i+2]]; ok {
+				p = append(p, phone{Sound: sound})
+				i++
+				continue
+			}
+		}
+		var sound string
+		switch char {
+		case 'c':
+			sound = "K"
+			if i+1 < len(word) && strings.IndexByte("eiy", word[i+1]) != -1 {
+				sound = "S"
+			}
+		case 'x':
+			p = append(p, phone{Sound: "K"})
+			sound = "S"
+		case 'q':
+			sound = "K"
+		case 'j':
+			sound = "JH"
+		default:
+			sound = strings.ToUpper(string(char))
+		}
+		// double consonants are one sound
+		if len(p) > 0 && p[len(p)-1].Sound == sound {
+			continue
+		}
+		p = append(p, phone{Sound: sound})
+	}
+	return p
+}
@@ -0,0 +1,714 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var InvalidQueryError = errors.New("invalid query")
+
+// QueryFields are the scopes which can be used as `section:chorus` or `performer:"Eminem"`
+var QueryFields = []string{"section", "performer"}
+
+var nearRegexp = regexp.MustCompile(`^NEAR/(\d+)$`)
+
+// QueryError tells where the query is wrong, Column counts characters from 1
+type QueryError struct {
+	Query   string
+	Column  int
+	Message string
+	// Err is set when the query is fine, but a dictionary it refers to can't be loaded
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+func (e *QueryError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	return InvalidQueryError
+}
+
+// Pointer returns the query with ^ below the wrong place, so it can be printed under the error
+func (e *QueryError) Pointer() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
+
+type queryTokenKind int
+
+const (
+	queryEOF queryTokenKind = iota
+	queryLeftParen
+	queryRightParen
+	queryAnd
+	queryOr
+	queryNot
+	queryNear
+	queryWord
+	queryPhrase
+	queryField
+	queryDictionary
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	// position is the offset of the token in runes
+	position int
+	// glued is true when there is no space before the token, `section:chorus(...)` is scoped only this way
+	glued bool
+
+	distance int
+	field    string
+	value    string
+	version  int
+}
+
+func (t queryToken) describe() string {
+	if t.kind == queryEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func isQuerySeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+func lexQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	newError := func(position int, format string, args ...interface{}) error {
+		return &QueryError{Query: query, Column: position + 1, Message: fmt.Sprintf(format, args...)}
+	}
+
+	// readPhrase reads `"..."` which starts at i, the end of it is returned too
+	readPhrase := func(i int) (string, int, error) {
+		for end := i + 1; end < len(runes); end++ {
+			if runes[end] == '"' {
+				return string(runes[i+1 : end]), end + 1, nil
+			}
+		}
+		return "", 0, newError(i, "phrase isn't closed, add \" at the end of it")
+	}
+
+	var tokens []queryToken
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		token := queryToken{position: i, glued: i > 0 && unicode.IsSpace(runes[i-1]) == false}
+
+		switch runes[i] {
+		case '(':
+			token.kind, token.text = queryLeftParen, "("
+			i++
+		case ')':
+			token.kind, token.text = queryRightParen, ")"
+			i++
+		case '"':
+			value, end, err := readPhrase(i)
+			if err != nil {
+				return nil, err
+			}
+			if len(lineTokens(value)) == 0 {
+				return nil, newError(i, "phrase %q has no words", string(runes[i:end]))
+			}
+			token.kind, token.text, token.value = queryPhrase, string(runes[i:end]), value
+			i = end
+		default:
+			end := i
+			for end < len(runes) && isQuerySeparator(runes[end]) == false {
+				end++
+			}
+			word := string(runes[i:end])
+			token.text = word
+
+			switch {
+			case word == "AND":
+				token.kind = queryAnd
+			case word == "OR":
+				token.kind = queryOr
+			case word == "NOT":
+				token.kind = queryNot
+			case word == "NEAR" || strings.HasPrefix(word, "NEAR/"):
+				match := nearRegexp.FindStringSubmatch(word)
+				if match == nil {
+					return nil, newError(i, "%q needs the distance in words, ex. NEAR/5", word)
+				}
+				token.kind = queryNear
+				token.distance, _ = strconv.Atoi(match[1])
+			case strings.HasPrefix(word, "@"):
+				token.kind = queryDictionary
+				token.value = word[1:]
+				if separator := strings.LastIndex(token.value, ":"); separator > 0 {
+					version, err := strconv.Atoi(token.value[separator+1:])
+					if err != nil || version <= 0 {
+						return nil, newError(i, "version of dictionary %q has to be a positive number", word)
+					}
+					token.value, token.version = token.value[:separator], version
+				}
+				if token.value == "" {
+					return nil, newError(i, "missing name of dictionary after @, ex. @en/profanity")
+				}
+			case strings.Index(word, ":") > 0:
+				token.kind = queryField
+				separator := strings.Index(word, ":")
+				token.field, token.value = strings.ToLower(word[:separator]), word[separator+1:]
+				if isOneOf(token.field, QueryFields) == false {
+					return nil, newError(i, "unknown field %q, use one of: %s", word[:separator], strings.Join(QueryFields, ", "))
+				}
+				if token.value == "" && end < len(runes) && runes[end] == '"' {
+					value, phraseEnd, err := readPhrase(end)
+					if err != nil {
+						return nil, err
+					}
+					token.value, token.text = value, string(runes[i:phraseEnd])
+					end = phraseEnd
+				}
+				if strings.TrimSpace(token.value) == "" {
+					return nil, newError(i, "missing value after %q, ex. %schorus", word[:separator+1], word[:separator+1])
+				}
+			default:
+				token.kind = queryWord
+				if len(lineTokens(strings.TrimSuffix(word, "*"))) == 0 {
+					return nil, newError(i, "%q has no letters or digits", word)
+				}
+				if strings.HasSuffix(word, "*") && len(lineTokens(strings.TrimSuffix(word, "*"))) > 1 {
+					return nil, newError(i, "* works only at the end of a single word, %q is more than one word", word)
+				}
+			}
+			i = end
+		}
+		tokens = append(tokens, token)
+	}
+	return append(tokens, queryToken{kind: queryEOF, position: len(runes)}), nil
+}
+
+// Query is parsed expression which picks songs, see ParseQuery for the syntax
+type Query struct {
+	root queryNode
+}
+
+// ParseQuery parses expressions like `money AND NOT (cash OR "dollar bills")`, `"mom's spaghetti" NEAR/5 sweater`,
+// `section:chorus(love) AND NOT @en/profanity` or `performer:"Eminem" NOT @profanity:3`.
+// NOT binds the strongest, then NEAR/n, AND (which can be skipped between terms) and OR. Words are compared
+// like lyrics are tokenized, so case and punctuation don't matter, `word*` matches words with the prefix.
+// `section:` and `performer:` match songs with such stanza, with `(...)` glued to them the expression is checked
+// only in those stanzas. Dictionaries are loaded from the service, nil service allows no dictionaries.
+func ParseQuery(query string, dictionaryService DictionaryService) (*Query, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, EmptyQueryError
+	}
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := queryParser{query: query, tokens: tokens, dictionaryService: dictionaryService}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != queryEOF {
+		if token.kind == queryRightParen {
+			return nil, parser.error(token, "%s doesn't close anything, remove it or add ( before", token.describe())
+		}
+		return nil, parser.error(token, "unexpected %s", token.describe())
+	}
+	return &Query{root: root}, nil
+}
+
+type queryParser struct {
+	query             string
+	tokens            []queryToken
+	current           int
+	dictionaryService DictionaryService
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.current]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.current]
+	if token.kind != queryEOF {
+		p.current++
+	}
+	return token
+}
+
+func (p *queryParser) error(token queryToken, format string, args ...interface{}) error {
+	return &QueryError{Query: p.query, Column: token.position + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == queryOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNear()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case queryAnd:
+			p.next()
+		case queryNot, queryLeftParen, queryWord, queryPhrase, queryField, queryDictionary:
+			// terms next to each other have to be all in the song
+		default:
+			return left, nil
+		}
+		right, err := p.parseNear()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseNear() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == queryNear {
+		near := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		leftTerm, leftOk := left.(*termNode)
+		rightTerm, rightOk := right.(*termNode)
+		if leftOk == false || rightOk == false {
+			return nil, p.error(near, "%s works only between two words or phrases", near.describe())
+		}
+		left = &nearNode{left: leftTerm, right: rightTerm, distance: near.distance}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().kind != queryNot {
+		return p.parsePrimary()
+	}
+	p.next()
+	node, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &notNode{node: node}, nil
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	token := p.next()
+	switch token.kind {
+	case queryLeftParen:
+		return p.parseGroup(token)
+	case queryWord:
+		word := strings.TrimSuffix(token.text, "*")
+		return &termNode{words: lineTokens(word), prefix: word != token.text}, nil
+	case queryPhrase:
+		return &termNode{words: lineTokens(token.value), phrase: true}, nil
+	case queryField:
+		node := &fieldNode{field: token.field, value: token.value}
+		if next := p.peek(); next.kind == queryLeftParen && next.glued {
+			scoped, err := p.parseGroup(p.next())
+			if err != nil {
+				return nil, err
+			}
+			node.scoped = scoped
+		}
+		return node, nil
+	case queryDictionary:
+		return p.dictionary(token)
+	case queryEOF:
+		if p.current == 0 {
+			return nil, p.error(token, "query is empty")
+		}
+		previous := p.tokens[p.current-1]
+		return nil, p.error(token, "query ends after %s, expected a word, phrase, field or @dictionary", previous.describe())
+	default:
+		return nil, p.error(token, "unexpected %s, expected a word, phrase, field, @dictionary or (", token.describe())
+	}
+}
+
+func (p *queryParser) parseGroup(leftParen queryToken) (queryNode, error) {
+	if p.peek().kind == queryRightParen {
+		return nil, p.error(p.peek(), "() is empty, put words inside")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != queryRightParen {
+		return nil, p.error(p.peek(), "expected ) to close ( from column %d, found %s", leftParen.position+1, p.peek().describe())
+	}
+	p.next()
+	return node, nil
+}
+
+func (p *queryParser) dictionary(token queryToken) (queryNode, error) {
+	if p.dictionaryService == nil {
+		return nil, p.error(token, "dictionaries aren't available here, remove %s", token.describe())
+	}
+	songDictionary, err := GetSongDictionary(p.dictionaryService, token.value, token.version)
+	if err != nil {
+		queryError := p.error(token, "cannot load dictionary %s: %v", token.describe(), err).(*QueryError)
+		queryError.Err = err
+		return nil, queryError
+	}
+	return &dictionaryNode{dictionary: songDictionary, version: token.version}, nil
+}
+
+// Matches tells if the song is picked by the query
+func (q *Query) Matches(song Song) bool {
+	return q.root.matches(newQueryScope(&song, song.Lyrics.Stanzas()))
+}
+
+// String returns the query with all of the parentheses, so it shows how the query has been understood
+func (q *Query) String() string {
+	return q.root.String()
+}
+
+// DictionaryVersions returns versions of all stored dictionaries used by the query, so the result can be reproduced,
+// the ones which aren't stored (like keywords) have no version
+func (q *Query) DictionaryVersions() []DictionaryVersion {
+	var versions []DictionaryVersion
+	q.root.walk(func(node queryNode) {
+		dictionary, ok := node.(*dictionaryNode)
+		if ok == false {
+			return
+		}
+		for _, version := range dictionary.dictionary.Versions {
+			if version.Version != 0 {
+				versions = append(versions, version)
+			}
+		}
+	})
+	return versions
+}
+
+// WithoutDictionaries is the query which matches songs with no words of any of the dictionaries, it's what
+// banned words and keywords have always been
+func WithoutDictionaries(dictionaries ...SongDictionary) *Query {
+	var anyOf queryNode
+	for _, dictionary := range dictionaries {
+		var node queryNode = &dictionaryNode{dictionary: dictionary}
+		if anyOf != nil {
+			node = &orNode{left: anyOf, right: node}
+		}
+		anyOf = node
+	}
+	if anyOf == nil {
+		return nil
+	}
+	return &Query{root: &notNode{node: anyOf}}
+}
+
+// AllOf joins queries with AND, nil queries are skipped, so nil is returned when there is no query at all
+func AllOf(queries ...*Query) *Query {
+	var output *Query
+	for _, query := range queries {
+		if query == nil {
+			continue
+		}
+		if output == nil {
+			output = query
+			continue
+		}
+		output = &Query{root: &andNode{left: output.root, right: query.root}}
+	}
+	return output
+}
+
+// FilterSongsByQuery keeps songs matching the query, nil query keeps all of them
+func FilterSongsByQuery(songs []Song, query *Query) []Song {
+	if query == nil {
+		return songs
+	}
+	var output []Song
+	for _, song := range songs {
+		if query.Matches(song) {
+			output = append(output, song)
+		}
+	}
+	return output
+}
+
+// queryScope is the part of song which is checked, the whole song or stanzas picked by a field
+type queryScope struct {
+	song    *Song
+	stanzas []Stanza
+	tokens  []string
+	words   WordsOccurrences
+}
+
+func newQueryScope(song *Song, stanzas []Stanza) *queryScope {
+	return &queryScope{song: song, stanzas: stanzas}
+}
+
+func (s *queryScope) getTokens() []string {
+	if s.tokens == nil {
+		s.tokens = []string{}
+		for _, stanza := range s.stanzas {
+			for _, line := range stanza.Lines {
+				s.tokens = append(s.tokens, lineTokens(line)...)
+			}
+		}
+	}
+	return s.tokens
+}
+
+func (s *queryScope) getWords() WordsOccurrences {
+	if s.words == nil {
+		var lines []string
+		for _, stanza := range s.stanzas {
+			lines = append(lines, stanza.Lines...)
+		}
+		s.words = Lyrics(strings.Join(lines, "\n")).FindWords()
+	}
+	return s.words
+}
+
+type queryNode interface {
+	matches(scope *queryScope) bool
+	walk(fn func(node queryNode))
+	String() string
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+func (n *andNode) matches(scope *queryScope) bool {
+	return n.left.matches(scope) && n.right.matches(scope)
+}
+
+func (n *andNode) walk(fn func(node queryNode)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+func (n *andNode) String() string {
+	return "(" + n.left.String() + " AND " + n.right.String() + ")"
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+func (n *orNode) matches(scope *queryScope) bool {
+	return n.left.matches(scope) || n.right.matches(scope)
+}
+
+func (n *orNode) walk(fn func(node queryNode)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+func (n *orNode) String() string {
+	return "(" + n.left.String() + " OR " + n.right.String() + ")"
+}
+
+type notNode struct {
+	node queryNode
+}
+
+func (n *notNode) matches(scope *queryScope) bool {
+	return n.node.matches(scope) == false
+}
+
+func (n *notNode) walk(fn func(node queryNode)) {
+	fn(n)
+	n.node.walk(fn)
+}
+
+func (n *notNode) String() string {
+	return "NOT " + n.node.String()
+}
+
+// termNode is a word or phrase, a word with punctuation inside like "hip-hop" is a phrase too
+type termNode struct {
+	words  []string
+	prefix bool
+	phrase bool
+}
+
+// positions returns positions of the first word of all occurrences
+func (n *termNode) positions(tokens []string) []int {
+	var positions []int
+	for start := 0; start+len(n.words) <= len(tokens); start++ {
+		if n.isAt(tokens, start) {
+			positions = append(positions, start)
+		}
+	}
+	return positions
+}
+
+func (n *termNode) isAt(tokens []string, start int) bool {
+	for offset, word := range n.words {
+		token := tokens[start+offset]
+		if n.prefix && offset == len(n.words)-1 {
+			if strings.HasPrefix(token, word) == false {
+				return false
+			}
+		} else if token != word {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *termNode) matches(scope *queryScope) bool {
+	return len(n.positions(scope.getTokens())) > 0
+}
+
+func (n *termNode) walk(fn func(node queryNode)) {
+	fn(n)
+}
+
+func (n *termNode) String() string {
+	text := strings.Join(n.words, " ")
+	if n.prefix {
+		text += "*"
+	}
+	if n.phrase || len(n.words) > 1 {
+		return `"` + text + `"`
+	}
+	return text
+}
+
+// nearNode matches when there are at most `distance` words between the terms, in any order
+type nearNode struct {
+	left, right *termNode
+	distance    int
+}
+
+func (n *nearNode) matches(scope *queryScope) bool {
+	tokens := scope.getTokens()
+	rightPositions := n.right.positions(tokens)
+	for _, left := range n.left.positions(tokens) {
+		for _, right := range rightPositions {
+			var between int
+			switch {
+			case left+len(n.left.words) <= right:
+				between = right - left - len(n.left.words)
+			case right+len(n.right.words) <= left:
+				between = left - right - len(n.right.words)
+			default:
+				// the same words can't be near to themselves
+				continue
+			}
+			if between <= n.distance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *nearNode) walk(fn func(node queryNode)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+func (n *nearNode) String() string {
+	return fmt.Sprintf("(%s NEAR/%d %s)", n.left.String(), n.distance, n.right.String())
+}
+
+// fieldNode matches stanzas by their header, performer of stanza without performers in the header is the author of song
+type fieldNode struct {
+	field  string
+	value  string
+	scoped queryNode
+}
+
+func (n *fieldNode) matchesStanza(song *Song, stanza Stanza) bool {
+	switch n.field {
+	case "section":
+		return strings.Contains(strings.ToLower(stanza.Section), strings.ToLower(n.value))
+	case "performer":
+		if len(stanza.Performers) == 0 {
+			return strings.EqualFold(song.Info.AuthorName, n.value)
+		}
+		for _, performer := range stanza.Performers {
+			if strings.EqualFold(performer, n.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *fieldNode) matches(scope *queryScope) bool {
+	var stanzas []Stanza
+	for _, stanza := range scope.stanzas {
+		if n.matchesStanza(scope.song, stanza) {
+			stanzas = append(stanzas, stanza)
+		}
+	}
+	if len(stanzas) == 0 {
+		return false
+	}
+	return n.scoped == nil || n.scoped.matches(newQueryScope(scope.song, stanzas))
+}
+
+func (n *fieldNode) walk(fn func(node queryNode)) {
+	fn(n)
+	if n.scoped != nil {
+		n.scoped.walk(fn)
+	}
+}
+
+func (n *fieldNode) String() string {
+	text := fmt.Sprintf("%s:%q", n.field, n.value)
+	if n.scoped != nil {
+		text += "(" + n.scoped.String() + ")"
+	}
+	return text
+}
+
+// dictionaryNode matches when any word of the scope is in the dictionary of song's language
+type dictionaryNode struct {
+	dictionary SongDictionary
+	version    int
+}
+
+func (n *dictionaryNode) matches(scope *queryScope) bool {
+	return n.dictionary.MatchesSong(*scope.song, scope.getWords())
+}
+
+func (n *dictionaryNode) walk(fn func(node queryNode)) {
+	fn(n)
+}
+
+func (n *dictionaryNode) String() string {
+	if n.version != 0 {
+		return fmt.Sprintf("@%s:%d", n.dictionary.Name, n.version)
+	}
+	return "@" + n.dictionary.Name
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+type SongWordsRank struct {
+	Song        Song
+	Occurrences WordsOccurrences
+	Count       int
+	TotalWords  int
+	PerThousand float64
+}
+
+// WordsRanking contains songs which use at least one of the words, ordered by number of occurrences
+type WordsRanking struct {
+	Words       []Word
+	Songs       []SongWordsRank
+	Totals      WordsOccurrences
+	TotalCount  int
+	TotalWords  int
+	PerThousand float64
+	SongsCount  int
+}
+
+func (w WordsOccurrences) Total() int {
+	total := 0
+	for _, occ := range w {
+		total += occ
+	}
+	return total
+}
+
+// Only returns occurrences of the given words, words which aren't used are present with 0
+func (w WordsOccurrences) Only(words []Word) WordsOccurrences {
+	output := make(WordsOccurrences)
+	for _, word := range words {
+		output[word] = w[word]
+	}
+	return output
+}
+
+func perThousand(count int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) * 1000 / float64(total)
+}
+
+func NormaliseWords(words []string) []Word {
+	var output []Word
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			output = append(output, Word(word))
+		}
+	}
+	return output
+}
+
+func RankSongsByWords(songs []Song, words []Word) WordsRanking {
+	ranking := WordsRanking{
+		Words:      words,
+		Songs:      []SongWordsRank{},
+		Totals:     make(WordsOccurrences).Only(words),
+		SongsCount: len(songs),
+	}
+
+	for _, song := range songs {
+		songWords := song.Lyrics.FindWords()
+		occurrences := songWords.Only(words)
+
+		ranking.Totals.Append(occurrences)
+		ranking.TotalWords += songWords.Total()
+
+		count := occurrences.Total()
+		if count == 0 {
+			continue
+		}
+
+		ranking.Songs = append(ranking.Songs, SongWordsRank{
+			Song:        song,
+			Occurrences: occurrences,
+			Count:       count,
+			TotalWords:  songWords.Total(),
+			PerThousand: perThousand(count, songWords.Total()),
+		})
+	}
+
+	sort.SliceStable(ranking.Songs, func(i, j int) bool {
+		a, b := ranking.Songs[i], ranking.Songs[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.PerThousand != b.PerThousand {
+			return a.PerThousand > b.PerThousand
+		}
+		return a.Song.Info.Title < b.Song.Info.Title
+	})
+
+	ranking.TotalCount = ranking.Totals.Total()
+	ranking.PerThousand = perThousand(ranking.TotalCount, ranking.TotalWords)
+	return ranking
+}
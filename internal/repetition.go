@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"bytes"
+	"compress/flate"
+	"math"
+	"sort"
+	"strings"
+)
+
+const mostRepeatedLinesLimit = 5
+
+// chorusSections are parts of section names which mark chorus
+var chorusSections = []string{"chorus", "hook", "refren"}
+
+type RepeatedLine struct {
+	Line  string `json:"line"`
+	Count int    `json:"count"`
+}
+
+type Repetition struct {
+	Lines         int `json:"lines"`
+	DistinctLines int `json:"distinct_lines"`
+	// DuplicateLines are lines which have already appeared earlier in the song
+	DuplicateLines   int            `json:"duplicate_lines"`
+	DuplicateShare   float64        `json:"duplicate_share"`
+	MostRepeated     []RepeatedLine `json:"most_repeated"`
+	ChorusWords      int            `json:"chorus_words"`
+	ChorusShare      float64        `json:"chorus_share"`
+	CompressionRatio float64        `json:"compression_ratio"`
+	// Repetitiveness is 1 - compression ratio, repetitive lyrics compress better
+	Repetitiveness float64 `json:"repetitiveness"`
+}
+
+// lineKey is used to compare lines, case and punctuation don't matter
+func lineKey(line string) string {
+	return strings.Join(lineTokens(line), " ")
+}
+
+func isChorus(section string) bool {
+	section = strings.ToLower(section)
+	for _, chorus := range chorusSections {
+		if strings.Contains(section, chorus) {
+			return true
+		}
+	}
+	return false
+}
+
+// chorusStanzas marks stanzas with chorus header, when lyrics have no headers at all the stanzas repeated whole are chorus
+func chorusStanzas(stanzas []Stanza) []bool {
+	output := make([]bool, len(stanzas))
+	hasHeaders := false
+	for i, stanza := range stanzas {
+		output[i] = isChorus(stanza.Section)
+		hasHeaders = hasHeaders || stanza.Section != ""
+	}
+	if hasHeaders {
+		return output
+	}
+
+	counts := make(map[string]int)
+	keys := make([]string, len(stanzas))
+	for i, stanza := range stanzas {
+		var lines []string
+		for _, line := range stanza.Lines {
+			lines = append(lines, lineKey(line))
+		}
+		keys[i] = strings.Join(lines, "\n")
+		counts[keys[i]]++
+	}
+	for i := range stanzas {
+		output[i] = counts[keys[i]] > 1
+	}
+	return output
+}
+
+// AnalyseRepetition measures how much of lyrics is repeated - duplicated lines, chorus and how well lyrics compress
+func AnalyseRepetition(lyrics Lyrics) Repetition {
+	repetition := Repetition{MostRepeated: []RepeatedLine{}}
+	counts := make(map[string]int)
+	var order, keys []string
+	words := 0
+
+	stanzas := lyrics.Stanzas()
+	chorus := chorusStanzas(stanzas)
+	for i, stanza := range stanzas {
+		for _, line := range stanza.Lines {
+			key := lineKey(line)
+			if key == "" {
+				continue
+			}
+			if counts[key] == 0 {
+				order = append(order, line)
+			} else {
+				repetition.DuplicateLines++
+			}
+			counts[key]++
+			keys = append(keys, key)
+
+			lineWords := len(lineTokens(line))
+			words += lineWords
+			if chorus[i] {
+				repetition.ChorusWords += lineWords
+			}
+		}
+	}
+
+	repetition.Lines = len(keys)
+	repetition.DistinctLines = len(order)
+	if repetition.Lines == 0 {
+		return repetition
+	}
+	repetition.DuplicateShare = roundRepetition(float64(repetition.DuplicateLines) / float64(repetition.Lines))
+	repetition.ChorusShare = roundRepetition(float64(repetition.ChorusWords) / float64(words))
+
+	for _, line := range order {
+		if count := counts[lineKey(line)]; count > 1 {
+			repetition.MostRepeated = append(repetition.MostRepeated, RepeatedLine{Line: line, Count: count})
+		}
+	}
+	sort.SliceStable(repetition.MostRepeated, func(i, j int) bool {
+		return repetition.MostRepeated[i].Count > repetition.MostRepeated[j].Count
+	})
+	if len(repetition.MostRepeated) > mostRepeatedLinesLimit {
+		repetition.MostRepeated = repetition.MostRepeated[:mostRepeatedLinesLimit]
+	}
+
+	repetition.CompressionRatio = roundRepetition(compressionRatio(strings.Join(keys, "\n")))
+	repetition.Repetitiveness = roundRepetition(1 - repetition.CompressionRatio)
+	return repetition
+}
+
+// compressionRatio is size of deflated text divided by its size, it can be above 1 for very short texts
+func compressionRatio(text string) float64 {
+	if text == "" {
+		return 0
+	}
+	var buf bytes.Buffer
+	writer, _ := flate.NewWriter(&buf, flate.BestCompression)
+	writer.Write([]byte(text))
+	writer.Close()
+	return float64(buf.Len()) / float64(len(text))
+}
+
+func roundRepetition(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// AverageRepetition averages shares and scores of songs, counts are summed
+func AverageRepetition(repetitions []Repetition) Repetition {
+	output := Repetition{MostRepeated: []RepeatedLine{}}
+	if len(repetitions) == 0 {
+		return output
+	}
+
+	var duplicateShare, chorusShare, compression float64
+	for _, repetition := range repetitions {
+		output.Lines += repetition.Lines
+		output.DistinctLines += repetition.DistinctLines
+		output.DuplicateLines += repetition.DuplicateLines
+		output.ChorusWords += repetition.ChorusWords
+		duplicateShare += repetition.DuplicateShare
+		chorusShare += repetition.ChorusShare
+		compression += repetition.CompressionRatio
+	}
+	count := float64(len(repetitions))
+	output.DuplicateShare = roundRepetition(duplicateShare / count)
+	output.ChorusShare = roundRepetition(chorusShare / count)
+	output.CompressionRatio = roundRepetition(compression / count)
+	output.Repetitiveness = roundRepetition(1 - output.CompressionRatio)
+	return output
+}
+
+// DistinctLines keeps only the first occurrence of every line, repeated choruses don't count many times then,
+// stanzas are still separated with empty lines
+func (l Lyrics) DistinctLines() Lyrics {
+	seen := make(map[string]struct{})
+	var stanzas []string
+	for _, stanza := range l.Stanzas() {
+		var lines []string
+		for _, line := range stanza.Lines {
+			key := lineKey(line)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			lines = append(lines, line)
+		}
+		if len(lines) > 0 {
+			stanzas = append(stanzas, strings.Join(lines, "\n"))
+		}
+	}
+	return Lyrics(strings.Join(stanzas, "\n\n"))
+}
+
+// WithDistinctLines returns copies of songs which lyrics have every line only once
+func WithDistinctLines(songs []Song) []Song {
+	output := make([]Song, len(songs))
+	for i, song := range songs {
+		song.Lyrics = song.Lyrics.DistinctLines()
+		output[i] = song
+	}
+	return output
+}
+
+func ArtistsWithDistinctLines(artists map[string][]Song) map[string][]Song {
+	output := make(map[string][]Song)
+	for name, songs := range artists {
+		output[name] = WithDistinctLines(songs)
+	}
+	return output
+}
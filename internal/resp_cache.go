@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	RESPError = errors.New("redis error")
+	// RESPReplyError is error replied by the server, the connection is still in step after it, unlike after other errors
+	RESPReplyError = fmt.Errorf("%w: reply", RESPError)
+)
+
+var _ Cache = &RESPCache{}
+
+// RESPCache talks with Redis (or anything speaking its protocol) over one connection, which is opened again
+// after network and protocol errors, only errors replied by the server keep it. Expiration is done by Redis with `SET ... PX`
+type RESPCache struct {
+	addr     string
+	password string
+	timeout  time.Duration
+	conn     net.Conn
+	reader   *bufio.Reader
+	mutex    sync.Mutex
+}
+
+func NewRESPCache(addr string, password string, timeout time.Duration) *RESPCache {
+	return &RESPCache{addr: addr, password: password, timeout: timeout}
+}
+
+func (c *RESPCache) Get(key string) (CacheEntry, error) {
+	reply, err := c.do("GET", key)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	if reply == nil {
+		return CacheEntry{}, CacheMissError
+	}
+	data, ok := reply.(string)
+	if ok == false {
+		// the reply may belong to other command, so the next replies can't be trusted
+		c.Close()
+		return CacheEntry{}, fmt.Errorf("%w: unexpected reply to GET", RESPError)
+	}
+
+	entry, _, err := decodeCacheEntry([]byte(data))
+	return entry, err
+}
+
+func (c *RESPCache) Set(key string, value []byte, expiration time.Duration) error {
+	data := string(encodeCacheEntry(value, time.Now(), time.Time{}))
+	var err error
+	if expiration > 0 {
+		milliseconds := expiration.Milliseconds()
+		if milliseconds == 0 {
+			milliseconds = 1
+		}
+		_, err = c.do("SET", key, data, "PX", strconv.FormatInt(milliseconds, 10))
+	} else {
+		_, err = c.do("SET", key, data)
+	}
+	return err
+}
+
+func (c *RESPCache) Delete(key string) error {
+	_, err := c.do("DEL", key)
+	return err
+}
+
+func (c *RESPCache) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.disconnect()
+}
+
+func (c *RESPCache) disconnect() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.reader = nil, nil
+	return err
+}
+
+func (c *RESPCache) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return err
+	}
+	c.conn, c.reader = conn, bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", c.password}); err != nil {
+			c.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends the command, it's retried once on new connection when the old one has been broken
+func (c *RESPCache) do(args ...string) (interface{}, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if c.conn == nil {
+			if err := c.connect(); err != nil {
+				return nil, err
+			}
+		}
+
+		reply, err := c.roundTrip(args)
+		if err == nil || errors.Is(err, RESPReplyError) {
+			return reply, err
+		}
+		c.disconnect()
+		if attempt > 0 {
+			return nil, err
+		}
+	}
+}
+
+func (c *RESPCache) roundTrip(args []string) (interface{}, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&builder, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, builder.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(c.reader)
+}
+
+// readRESP reads one reply - simple string, error, integer, bulk string (nil when it's missing) or array.
+// Only the error reply is RESPReplyError, the other errors leave the reader out of step with the server
+func readRESP(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("%w: empty reply", RESPError)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("%w: %s", RESPReplyError, line[1:])
+	case ':':
+		number, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer %q", RESPError, line[1:])
+		}
+		return number, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid size %q", RESPError, line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid size %q", RESPError, line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			// the rest of the array isn't read, so even error reply inside it breaks the connection
+			if items[i], err = readRESP(reader); err != nil {
+				return nil, fmt.Errorf("%w: %v", RESPError, err)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%w: unknown reply type %q", RESPError, line[0])
+}
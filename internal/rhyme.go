@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// rhymeWindow is how many lines apart can two lines be to count as rhyming
+	rhymeWindow = 4
+	// longestRhymesLimit is how many of the longest multisyllabic rhymes are reported
+	longestRhymesLimit = 5
+	// commonSchemesLimit is how many of the most common stanza schemes are reported for artist
+	commonSchemesLimit = 5
+)
+
+type RhymePair struct {
+	Lines     []string `json:"lines"`
+	Syllables int      `json:"syllables"`
+}
+
+type StanzaRhymes struct {
+	Section         string   `json:"section,omitempty"`
+	Performers      []string `json:"performers,omitempty"`
+	Scheme          string   `json:"scheme"`
+	Syllables       int      `json:"syllables"`
+	RhymedSyllables int      `json:"rhymed_syllables"`
+	Density         float64  `json:"density"`
+}
+
+type RhymeAnalysis struct {
+	Language            string         `json:"language"`
+	Lines               int            `json:"lines"`
+	Syllables           int            `json:"syllables"`
+	RhymedSyllables     int            `json:"rhymed_syllables"`
+	Density             float64        `json:"density"`
+	EndRhymes           int            `json:"end_rhymes"`
+	MultisyllabicRhymes int            `json:"multisyllabic_rhymes"`
+	InternalRhymes      int            `json:"internal_rhymes"`
+	LongestRhymes       []RhymePair    `json:"longest_rhymes"`
+	Stanzas             []StanzaRhymes `json:"stanzas"`
+}
+
+type rhymeWord struct {
+	text   string
+	vowels []string
+	tail   int
+	coda   string
+	// offset is the index of the first vowel of word in vowels of the whole line
+	offset int
+}
+
+type rhymeLine struct {
+	text   string
+	words  []rhymeWord
+	vowels []string
+}
+
+func newRhymeLine(text string, language string) rhymeLine {
+	line := rhymeLine{text: text}
+	for _, token := range lineTokens(text) {
+		p := pronounce(token, language)
+		vowels := p.vowels()
+		if len(vowels) == 0 {
+			continue
+		}
+		line.words = append(line.words, rhymeWord{
+			text:   token,
+			vowels: vowels,
+			tail:   p.tailLength(language),
+			coda:   p.coda(),
+			offset: len(line.vowels),
+		})
+		line.vowels = append(line.vowels, vowels...)
+	}
+	return line
+}
+
+func (l rhymeLine) lastWord() (rhymeWord, bool) {
+	if len(l.words) == 0 {
+		return rhymeWord{}, false
+	}
+	return l.words[len(l.words)-1], true
+}
+
+// ending returns the last words of line which cover given number of syllables
+func (l rhymeLine) ending(syllables int) string {
+	for i := len(l.words) - 1; i >= 0; i-- {
+		if len(l.vowels)-l.words[i].offset >= syllables {
+			var words []string
+			for _, word := range l.words[i:] {
+				words = append(words, word.text)
+			}
+			return strings.Join(words, " ")
+		}
+	}
+	return l.text
+}
+
+// endRhyme returns how many syllables at the end of both lines rhyme, 0 when they don't,
+// repeating the same last word isn't a rhyme
+func endRhyme(a rhymeLine, b rhymeLine) int {
+	lastA, okA := a.lastWord()
+	lastB, okB := b.lastWord()
+	if okA == false || okB == false || lastA.text == lastB.text || lastA.coda != lastB.coda {
+		return 0
+	}
+
+	matching := 0
+	for matching < len(a.vowels) && matching < len(b.vowels) &&
+		a.vowels[len(a.vowels)-1-matching] == b.vowels[len(b.vowels)-1-matching] {
+		matching++
+	}
+
+	required := lastA.tail
+	if lastB.tail > required {
+		required = lastB.tail
+	}
+	if matching < required {
+		return 0
+	}
+	return matching
+}
+
+// wordsRhyme tells if two different words have the same rhyming part
+func wordsRhyme(a rhymeWord, b rhymeWord) bool {
+	if a.text == b.text || a.tail != b.tail || a.coda != b.coda {
+		return false
+	}
+	tailA := a.vowels[len(a.vowels)-a.tail:]
+	tailB := b.vowels[len(b.vowels)-b.tail:]
+	for i := range tailA {
+		if tailA[i] != tailB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// schemeLetter names n-th rhyme of stanza, after "Z" letters are doubled
+func schemeLetter(n int) string {
+	return strings.Repeat(string(rune('A'+n%26)), n/26+1)
+}
+
+// AnalyseRhymes finds end, multisyllabic and internal rhymes of lyrics, rhyme density is the share of syllables
+// which belong to any rhyme. Polish lyrics are read from spelling, other languages are treated as english
+func AnalyseRhymes(lyrics Lyrics, language string) RhymeAnalysis {
+	if language != "pl" {
+		language = "en"
+	}
+	analysis := RhymeAnalysis{Language: language, LongestRhymes: []RhymePair{}, Stanzas: []StanzaRhymes{}}
+	longest := make(map[string]RhymePair)
+
+	for _, stanza := range lyrics.Stanzas() {
+		var lines []rhymeLine
+		for _, text := range stanza.Lines {
+			line := newRhymeLine(text, language)
+			if len(line.vowels) > 0 {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		rhymed := make([]map[int]bool, len(lines))
+		for i := range lines {
+			rhymed[i] = make(map[int]bool)
+		}
+		letters := make([]string, len(lines))
+		nextLetter := 0
+
+		for i, line := range lines {
+			best := 0
+			for j := i - 1; j >= 0; j-- {
+				syllables := endRhyme(lines[j], line)
+				if syllables == 0 {
+					continue
+				}
+				if letters[i] == "" {
+					letters[i] = letters[j]
+				}
+				if i-j > rhymeWindow {
+					continue
+				}
+				if syllables > best {
+					best = syllables
+				}
+				for k := 0; k < syllables; k++ {
+					rhymed[i][len(line.vowels)-1-k] = true
+					rhymed[j][len(lines[j].vowels)-1-k] = true
+				}
+				if syllables >= 2 {
+					pair := RhymePair{Lines: []string{lines[j].ending(syllables), line.ending(syllables)}, Syllables: syllables}
+					longest[strings.Join(pair.Lines, "\n")] = pair
+				}
+			}
+			if letters[i] == "" {
+				letters[i] = schemeLetter(nextLetter)
+				nextLetter++
+			}
+			if best > 0 {
+				analysis.EndRhymes++
+			}
+			if best >= 2 {
+				analysis.MultisyllabicRhymes++
+			}
+
+			for a := 0; a < len(line.words); a++ {
+				for b := a + 1; b < len(line.words); b++ {
+					wordA, wordB := line.words[a], line.words[b]
+					if utf8.RuneCountInString(wordA.text) < 3 || utf8.RuneCountInString(wordB.text) < 3 || wordsRhyme(wordA, wordB) == false {
+						continue
+					}
+					analysis.InternalRhymes++
+					for _, word := range []rhymeWord{wordA, wordB} {
+						for k := len(word.vowels) - word.tail; k < len(word.vowels); k++ {
+							rhymed[i][word.offset+k] = true
+						}
+					}
+				}
+			}
+		}
+
+		stanzaRhymes := StanzaRhymes{
+			Section:    stanza.Section,
+			Performers: stanza.Performers,
+			Scheme:     strings.Join(letters, ""),
+		}
+		for i, line := range lines {
+			stanzaRhymes.Syllables += len(line.vowels)
+			stanzaRhymes.RhymedSyllables += len(rhymed[i])
+		}
+		stanzaRhymes.Density = rhymeDensity(stanzaRhymes.RhymedSyllables, stanzaRhymes.Syllables)
+
+		analysis.Lines += len(lines)
+		analysis.Syllables += stanzaRhymes.Syllables
+		analysis.RhymedSyllables += stanzaRhymes.RhymedSyllables
+		analysis.Stanzas = append(analysis.Stanzas, stanzaRhymes)
+	}
+
+	analysis.Density = rhymeDensity(analysis.RhymedSyllables, analysis.Syllables)
+	for _, pair := range longest {
+		analysis.LongestRhymes = append(analysis.LongestRhymes, pair)
+	}
+	sort.Slice(analysis.LongestRhymes, func(i, j int) bool {
+		a, b := analysis.LongestRhymes[i], analysis.LongestRhymes[j]
+		if a.Syllables != b.Syllables {
+			return a.Syllables > b.Syllables
+		}
+		return strings.Join(a.Lines, "\n") < strings.Join(b.Lines, "\n")
+	})
+	if len(analysis.LongestRhymes) > longestRhymesLimit {
+		analysis.LongestRhymes = analysis.LongestRhymes[:longestRhymesLimit]
+	}
+	return analysis
+}
+
+func rhymeDensity(rhymed int, syllables int) float64 {
+	if syllables == 0 {
+		return 0
+	}
+	return math.Round(float64(rhymed)/float64(syllables)*1000) / 1000
+}
+
+type SchemeCount struct {
+	Scheme string `json:"scheme"`
+	Count  int    `json:"count"`
+}
+
+type RhymeSummary struct {
+	Songs               int           `json:"songs"`
+	Syllables           int           `json:"syllables"`
+	RhymedSyllables     int           `json:"rhymed_syllables"`
+	Density             float64       `json:"density"`
+	AverageDensity      float64       `json:"average_density"`
+	EndRhymes           int           `json:"end_rhymes"`
+	MultisyllabicRhymes int           `json:"multisyllabic_rhymes"`
+	InternalRhymes      int           `json:"internal_rhymes"`
+	CommonSchemes       []SchemeCount `json:"common_schemes"`
+}
+
+// NewRhymeSummary sums rhymes of songs, density is calculated from all syllables while average density treats
+// every song equally
+func NewRhymeSummary(analyses []RhymeAnalysis) RhymeSummary {
+	summary := RhymeSummary{Songs: len(analyses), CommonSchemes: []SchemeCount{}}
+	schemes := make(map[string]int)
+	densities := 0.0
+
+	for _, analysis := range analyses {
+		summary.Syllables += analysis.Syllables
+		summary.RhymedSyllables += analysis.RhymedSyllables
+		summary.EndRhymes += analysis.EndRhymes
+		summary.MultisyllabicRhymes += analysis.MultisyllabicRhymes
+		summary.InternalRhymes += analysis.InternalRhymes
+		densities += analysis.Density
+		for _, stanza := range analysis.Stanzas {
+			if len(stanza.Scheme) > 1 {
+				schemes[stanza.Scheme]++
+			}
+		}
+	}
+
+	summary.Density = rhymeDensity(summary.RhymedSyllables, summary.Syllables)
+	if len(analyses) > 0 {
+		summary.AverageDensity = math.Round(densities/float64(len(analyses))*1000) / 1000
+	}
+
+	for scheme, count := range schemes {
+		summary.CommonSchemes = append(summary.CommonSchemes, SchemeCount{Scheme: scheme, Count: count})
+	}
+	sort.Slice(summary.CommonSchemes, func(i, j int) bool {
+		a, b := summary.CommonSchemes[i], summary.CommonSchemes[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Scheme < b.Scheme
+	})
+	if len(summary.CommonSchemes) > commonSchemesLimit {
+		summary.CommonSchemes = summary.CommonSchemes[:commonSchemesLimit]
+	}
+	return summary
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxScrapeErrors is the number of failures which are described in the progress, the rest is only counted
+const maxScrapeErrors = 10
+
+// ScrapeProgress tells how far downloading of songs of the artist is
+type ScrapeProgress struct {
+	// SongsListed is the number of songs of the artist, it's known after the listing has been fetched
+	SongsListed int `json:"songs_listed"`
+	// PagesFetched counts lyrics pages which have been requested, successfully or not
+	PagesFetched int `json:"pages_fetched"`
+	SongsScraped int `json:"songs_scraped"`
+	Failures     int `json:"failures"`
+	// Errors describe the first failures
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Add counts the downloaded song, or its failure when err isn't nil
+func (p *ScrapeProgress) Add(info SongInfo, err error) {
+	p.PagesFetched++
+	if err == nil {
+		p.SongsScraped++
+		return
+	}
+	p.Failures++
+	if len(p.Errors) < maxScrapeErrors {
+		p.Errors = append(p.Errors, fmt.Sprintf("%s: %v", info.Title, err))
+	}
+}
+
+// ScrapeSongs downloads lyrics of songs by `concurrency` at once and calls fn with every song as soon as it's downloaded,
+// or with the error when it fails. fn is never called concurrently. No more songs are started when ctx is done,
+// but the ones which are being downloaded are finished
+func ScrapeSongs(ctx context.Context, lyricsService LyricsService, infos []SongInfo, concurrency int, fn func(index int, song Song, err error)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	indexes := make(chan int)
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				song, err := lyricsService.GetSongFromInfo(infos[index])
+				mutex.Lock()
+				fn(index, song, err)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+FEED:
+	for i := range infos {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break FEED
+		}
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed lexicons/*.txt
+var lexiconsFS embed.FS
+
+const (
+	MoodUpbeat  = "upbeat"
+	MoodNeutral = "neutral"
+	MoodDark    = "dark"
+
+	// moodThreshold is comparative valence (per word) above which song is upbeat, or below minus which it's dark
+	moodThreshold = 0.05
+	// negationWindow is how many following words are flipped by "not" or "nie"
+	negationWindow = 3
+)
+
+var (
+	InvalidLexiconEntryError = errors.New("invalid lexicon entry")
+	UnknownEmotionError      = errors.New("unknown emotion")
+	UnknownMoodError         = errors.New("unknown mood")
+
+	// Emotions are categories of sentiment lexicons
+	Emotions  = []string{"anger", "fear", "joy", "sadness", "disgust", "trust"}
+	negations = map[string]struct{}{
+		"not": {}, "no": {}, "never": {}, "don't": {}, "dont": {}, "ain't": {}, "can't": {}, "won't": {}, "didn't": {},
+		"isn't": {}, "nie": {}, "nigdy": {}, "bez": {},
+	}
+)
+
+// ValidateMood accepts empty mood too, which means any
+func ValidateMood(mood string) error {
+	if mood == "" || isOneOf(mood, []string{MoodUpbeat, MoodNeutral, MoodDark}) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", UnknownMoodError, mood)
+}
+
+type lexiconEntry struct {
+	Valence  float64
+	Emotions []string
+}
+
+type SentimentLexicon struct {
+	language string
+	entries  map[string]lexiconEntry
+}
+
+// ReadSentimentLexicon reads lines of "word valence emotion...", valence is from -5 to 5
+func ReadSentimentLexicon(language string, r io.Reader) (*SentimentLexicon, error) {
+	lexicon := &SentimentLexicon{language: language, entries: make(map[string]lexiconEntry)}
+
+	sc := bufio.NewScanner(r)
+	lineNumber := 0
+	for sc.Scan() {
+		lineNumber++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], commentPrefix) {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: %w: missing valence", lineNumber, InvalidLexiconEntryError)
+		}
+
+		valence, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || valence < -5 || valence > 5 {
+			return nil, fmt.Errorf("line %d: %w: valence %q", lineNumber, InvalidLexiconEntryError, fields[1])
+		}
+		for _, emotion := range fields[2:] {
+			if isOneOf(emotion, Emotions) == false {
+				return nil, fmt.Errorf("line %d: %w: %q", lineNumber, UnknownEmotionError, emotion)
+			}
+		}
+		lexicon.entries[strings.ToLower(fields[0])] = lexiconEntry{Valence: valence, Emotions: fields[2:]}
+	}
+	return lexicon, sc.Err()
+}
+
+var (
+	defaultLexiconsOnce sync.Once
+	defaultLexicons     map[string]*SentimentLexicon
+)
+
+// SentimentLexiconFor returns bundled lexicon of the language, english one is used for other languages
+func SentimentLexiconFor(language string) *SentimentLexicon {
+	defaultLexiconsOnce.Do(func() {
+		defaultLexicons = make(map[string]*SentimentLexicon)
+		for _, language := range []string{"en", "pl"} {
+			file, err := lexiconsFS.Open("lexicons/" + language + ".txt")
+			if err != nil {
+				panic(err)
+			}
+			lexicon, err := ReadSentimentLexicon(language, file)
+			file.Close()
+			if err != nil {
+				panic(fmt.Errorf("bundled %s lexicon: %w", language, err))
+			}
+			defaultLexicons[language] = lexicon
+		}
+	})
+
+	if lexicon, ok := defaultLexicons[language]; ok {
+		return lexicon
+	}
+	return defaultLexicons["en"]
+}
+
+// lookup finds the word itself, then its stem, "lovin'" is read as "loving"
+func (l *SentimentLexicon) lookup(token string) (lexiconEntry, bool) {
+	token = strings.Trim(token, "'")
+	if l.language == "en" && strings.HasSuffix(token, "in") {
+		if entry, ok := l.lookup(token + "g"); ok {
+			return entry, true
+		}
+	}
+	if entry, ok := l.entries[token]; ok {
+		return entry, true
+	}
+
+	stem := string(Stem(Word(token), l.language))
+	for _, candidate := range []string{stem, stem + "e"} {
+		if entry, ok := l.entries[candidate]; ok {
+			return entry, true
+		}
+	}
+	return lexiconEntry{}, false
+}
+
+// Score sums valence of words, word after negation has opposite valence and doesn't count for emotions
+func (l *SentimentLexicon) Score(tokens []string) Sentiment {
+	sentiment := newSentiment()
+	negated := 0
+	for _, token := range tokens {
+		sentiment.Words++
+		if _, ok := negations[token]; ok {
+			negated = negationWindow
+			continue
+		}
+
+		entry, ok := l.lookup(token)
+		if ok == false {
+			if negated > 0 {
+				negated--
+			}
+			continue
+		}
+
+		valence := entry.Valence
+		if negated > 0 {
+			valence = -valence
+			negated = 0
+		} else {
+			for _, emotion := range entry.Emotions {
+				sentiment.Emotions[emotion]++
+			}
+		}
+
+		sentiment.Matched++
+		sentiment.sum += valence
+		if valence > 0 {
+			sentiment.Positive++
+		}
+		if valence < 0 {
+			sentiment.Negative++
+		}
+	}
+	return sentiment.finish()
+}
+
+type Sentiment struct {
+	Words    int `json:"words"`
+	Matched  int `json:"matched"`
+	Positive int `json:"positive"`
+	Negative int `json:"negative"`
+	// Valence is the average valence of matched words, from -5 to 5
+	Valence float64 `json:"valence"`
+	// Comparative is the sum of valences per word, it's used for mood
+	Comparative     float64        `json:"comparative"`
+	Emotions        map[string]int `json:"emotions"`
+	DominantEmotion string         `json:"dominant_emotion,omitempty"`
+	Mood            string         `json:"mood"`
+	sum             float64
+}
+
+func newSentiment() Sentiment {
+	sentiment := Sentiment{Emotions: make(map[string]int)}
+	for _, emotion := range Emotions {
+		sentiment.Emotions[emotion] = 0
+	}
+	return sentiment
+}
+
+// Add combines sentiments of two texts like they were one
+func (s Sentiment) Add(other Sentiment) Sentiment {
+	output := newSentiment()
+	output.Words = s.Words + other.Words
+	output.Matched = s.Matched + other.Matched
+	output.Positive = s.Positive + other.Positive
+	output.Negative = s.Negative + other.Negative
+	output.sum = s.sum + other.sum
+	for _, emotion := range Emotions {
+		output.Emotions[emotion] = s.Emotions[emotion] + other.Emotions[emotion]
+	}
+	return output.finish()
+}
+
+func (s Sentiment) finish() Sentiment {
+	s.Valence, s.Comparative, s.DominantEmotion = 0, 0, ""
+	if s.Matched > 0 {
+		s.Valence = math.Round(s.sum/float64(s.Matched)*100) / 100
+	}
+	if s.Words > 0 {
+		s.Comparative = math.Round(s.sum/float64(s.Words)*1000) / 1000
+	}
+
+	best := 0
+	for _, emotion := range Emotions {
+		if s.Emotions[emotion] > best {
+			best = s.Emotions[emotion]
+			s.DominantEmotion = emotion
+		}
+	}
+
+	s.Mood = MoodNeutral
+	if s.Comparative >= moodThreshold {
+		s.Mood = MoodUpbeat
+	}
+	if s.Comparative <= -moodThreshold {
+		s.Mood = MoodDark
+	}
+	return s
+}
+
+type LineSentiment struct {
+	Line      string    `json:"line"`
+	Sentiment Sentiment `json:"sentiment"`
+}
+
+type StanzaSentiment struct {
+	Section    string          `json:"section,omitempty"`
+	Performers []string        `json:"performers,omitempty"`
+	Sentiment  Sentiment       `json:"sentiment"`
+	Lines      []LineSentiment `json:"lines,omitempty"`
+}
+
+type SentimentAnalysis struct {
+	Total   Sentiment         `json:"total"`
+	Stanzas []StanzaSentiment `json:"stanzas"`
+}
+
+// AnalyseSentiment scores every line, the stanzas and whole lyrics are sums of their lines
+func AnalyseSentiment(lyrics Lyrics, language string) SentimentAnalysis {
+	lexicon := SentimentLexiconFor(language)
+	analysis := SentimentAnalysis{Total: newSentiment().finish(), Stanzas: []StanzaSentiment{}}
+
+	for _, stanza := range lyrics.Stanzas() {
+		stanzaSentiment := StanzaSentiment{Section: stanza.Section, Performers: stanza.Performers, Sentiment: newSentiment().finish()}
+		for _, line := range stanza.Lines {
+			sentiment := lexicon.Score(lineTokens(line))
+			stanzaSentiment.Lines = append(stanzaSentiment.Lines, LineSentiment{Line: line, Sentiment: sentiment})
+			stanzaSentiment.Sentiment = stanzaSentiment.Sentiment.Add(sentiment)
+		}
+		analysis.Total = analysis.Total.Add(stanzaSentiment.Sentiment)
+		analysis.Stanzas = append(analysis.Stanzas, stanzaSentiment)
+	}
+	return analysis
+}
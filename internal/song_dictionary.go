@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+)
+
+// SongDictionary is one dictionary or set of dictionaries with the same name in many languages, ex. "en/profanity"
+// and "pl/profanity" for "profanity", in that case every song is checked with dictionaries of its languages
+type SongDictionary struct {
+	Name       string
+	Versions   []DictionaryVersion
+	ByLanguage map[string]DictionaryVersion
+}
+
+// GetSongDictionary returns the dictionary with the name, when there is no such dictionary, the "<language>/<name>"
+// dictionaries are used instead, version can be pinned only for a single dictionary
+func GetSongDictionary(service DictionaryService, name string, version int) (SongDictionary, error) {
+	dictionaryVersion, err := service.GetDictionaryVersion(name, version)
+	if err == nil {
+		return SongDictionary{Name: name, Versions: []DictionaryVersion{dictionaryVersion}}, nil
+	}
+	if errors.Is(err, DictionaryNotFoundError) == false || version != 0 {
+		return SongDictionary{}, err
+	}
+
+	output := SongDictionary{Name: name, ByLanguage: make(map[string]DictionaryVersion)}
+	for _, dictionaryName := range service.GetDictionariesNames() {
+		language := strings.TrimSuffix(dictionaryName, "/"+name)
+		if language == dictionaryName || strings.Contains(language, "/") {
+			continue
+		}
+
+		languageVersion, err := service.GetDictionaryVersion(dictionaryName, 0)
+		if err != nil {
+			return SongDictionary{}, err
+		}
+		output.ByLanguage[language] = languageVersion
+		output.Versions = append(output.Versions, languageVersion)
+	}
+
+	if len(output.Versions) == 0 {
+		return SongDictionary{}, err
+	}
+	return output, nil
+}
+
+// NewSongDictionary wraps a dictionary which isn't stored, like keywords from flags
+func NewSongDictionary(dictionary Dictionary) SongDictionary {
+	return SongDictionary{Name: dictionary.Name, Versions: []DictionaryVersion{{Name: dictionary.Name, Dictionary: dictionary}}}
+}
+
+// Dictionary returns entries used for the song, songs with unknown language are checked with all of the dictionaries
+func (d SongDictionary) Dictionary(song Song) Dictionary {
+	output := Dictionary{Name: d.Name}
+	if d.ByLanguage == nil || len(song.Languages) == 0 {
+		for _, version := range d.Versions {
+			output = output.Merge(version.Dictionary)
+		}
+		return output
+	}
+
+	for _, language := range LanguagesOf(song.Languages) {
+		if version, ok := d.ByLanguage[language]; ok {
+			output = output.Merge(version.Dictionary)
+		}
+	}
+	return output
+}
+
+func (d SongDictionary) MatchesSong(song Song, words WordsOccurrences) bool {
+	return d.Dictionary(song).MatchesAnyIn(words, LanguagesOf(song.Languages))
+}
+
+// FilterSongsByLanguage keeps songs which are at least partly written in the language, empty language keeps all of them
+func FilterSongsByLanguage(songs []Song, language string) []Song {
+	if language == "" {
+		return songs
+	}
+
+	var output []Song
+	for _, song := range songs {
+		if song.HasLanguage(language) {
+			output = append(output, song)
+		}
+	}
+	return output
+}
+
+// ArtistLanguages returns languages of all of the songs, weighted by number of words of the songs
+func ArtistLanguages(songs []Song) []LanguageScore {
+	weights := make(map[string]float64)
+	total := 0.0
+	for _, song := range songs {
+		words := float64(song.Lyrics.FindWords().Total())
+		total += words
+		for _, score := range song.Languages {
+			weights[score.Language] += score.Confidence * words
+		}
+	}
+	return languageScores(weights, total)
+}
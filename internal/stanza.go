@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+var performersSeparatorRegexp = regexp.MustCompile(`\s*(?:,|&|\bx\b|\band\b|\boraz\b|\bi\b)\s*`)
+
+type Stanza struct {
+	// Section is the name from header like "Verse 1" or "Chorus", empty when lyrics have no headers
+	Section string `json:"section,omitempty"`
+	// Performers are taken from the header, "[Chorus: Eminem & Rihanna]" has two performers
+	Performers []string `json:"performers,omitempty"`
+	Lines      []string `json:"lines"`
+}
+
+// parseSectionHeader splits "[Verse 2: Eminem & Rihanna]" into the section and its performers
+func parseSectionHeader(header string) (string, []string) {
+	header = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(header, "["), "]"))
+	parts := strings.SplitN(header, ":", 2)
+	section := strings.TrimSpace(parts[0])
+	if len(parts) == 1 {
+		return section, nil
+	}
+
+	var performers []string
+	for _, performer := range performersSeparatorRegexp.Split(parts[1], -1) {
+		performer = strings.TrimSpace(performer)
+		if performer != "" {
+			performers = append(performers, performer)
+		}
+	}
+	return section, performers
+}
+
+// Stanzas splits lyrics on empty lines and section headers, stanza without a header inherits the previous one,
+// because the providers separate long verses with empty lines too
+func (l Lyrics) Stanzas() []Stanza {
+	var stanzas []Stanza
+	current := Stanza{}
+
+	flush := func() {
+		if len(current.Lines) > 0 {
+			stanzas = append(stanzas, current)
+		}
+		current = Stanza{Section: current.Section, Performers: current.Performers}
+	}
+
+	for _, line := range strings.Split(string(l), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		for _, part := range splitGluedLines(line) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if isSectionHeader(part) {
+				flush()
+				current.Section, current.Performers = parseSectionHeader(part)
+				continue
+			}
+			current.Lines = append(current.Lines, part)
+		}
+	}
+	flush()
+	return stanzas
+}
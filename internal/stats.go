@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// mtldThreshold is the type-token ratio which ends a factor in MTLD
+	mtldThreshold = 0.72
+	mattrWindow   = 50
+)
+
+// isSectionHeader tells if the line is a header like "[Chorus: Eminem]" instead of lyrics
+func isSectionHeader(line string) bool {
+	return strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")
+}
+
+// Lines returns non empty lines of lyrics without section headers, lines glued together by the provider
+// ("last wordNext line") are split again
+func (l Lyrics) Lines() []string {
+	var lines []string
+	for _, line := range strings.Split(string(l), "\n") {
+		for _, part := range splitGluedLines(line) {
+			part = strings.TrimSpace(part)
+			if part == "" || isSectionHeader(part) {
+				continue
+			}
+			lines = append(lines, part)
+		}
+	}
+	return lines
+}
+
+// splitGluedLines splits text where lowercase letter is directly followed by uppercase one, or before "["
+func splitGluedLines(text string) []string {
+	var output []string
+	start := 0
+	previous := ' '
+	for i, char := range text {
+		if (unicode.IsLower(previous) && unicode.IsUpper(char)) || (char == '[' && i > 0) || (previous == ']' && char != ' ') {
+			output = append(output, text[start:i])
+			start = i
+		}
+		previous = char
+	}
+	return append(output, text[start:])
+}
+
+// lineTokens returns lowercase words of a single line, apostrophes are kept, so "don't" is one word
+func lineTokens(line string) []string {
+	return strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+		return unicode.IsLetter(r) == false && unicode.IsDigit(r) == false && r != '\''
+	})
+}
+
+// Tokens returns all words of lyrics in order, unlike FindWords the short words are kept
+func (l Lyrics) Tokens() []string {
+	var tokens []string
+	for _, line := range l.Lines() {
+		tokens = append(tokens, lineTokens(line)...)
+	}
+	return tokens
+}
+
+type LexicalStats struct {
+	Words             int     `json:"words"`
+	UniqueWords       int     `json:"unique_words"`
+	TypeTokenRatio    float64 `json:"type_token_ratio"`
+	MTLD              float64 `json:"mtld"`
+	MATTR             float64 `json:"mattr"`
+	AverageWordLength float64 `json:"average_word_length"`
+	Lines             int     `json:"lines"`
+	WordsPerLine      float64 `json:"words_per_line"`
+	HapaxLegomena     int     `json:"hapax_legomena"`
+	HapaxRatio        float64 `json:"hapax_ratio"`
+}
+
+func (l Lyrics) Stats() LexicalStats {
+	return NewLexicalStats(l.Tokens(), len(l.Lines()))
+}
+
+// NewLexicalStats calculates the stats from words in order, the order matters only for MTLD and MATTR
+func NewLexicalStats(tokens []string, lines int) LexicalStats {
+	stats := LexicalStats{Words: len(tokens), Lines: lines}
+	if len(tokens) == 0 {
+		return stats
+	}
+
+	counts := make(map[string]int)
+	length := 0
+	for _, token := range tokens {
+		counts[token]++
+		length += utf8.RuneCountInString(token)
+	}
+	for _, count := range counts {
+		if count == 1 {
+			stats.HapaxLegomena++
+		}
+	}
+
+	stats.UniqueWords = len(counts)
+	stats.TypeTokenRatio = float64(stats.UniqueWords) / float64(stats.Words)
+	stats.AverageWordLength = float64(length) / float64(stats.Words)
+	stats.HapaxRatio = float64(stats.HapaxLegomena) / float64(stats.UniqueWords)
+	stats.MTLD = mtld(tokens)
+	stats.MATTR = mattr(tokens, mattrWindow)
+	if lines > 0 {
+		stats.WordsPerLine = float64(stats.Words) / float64(lines)
+	}
+	return stats
+}
+
+// mtld is the mean length of sequences keeping type-token ratio above the threshold, average of both directions
+func mtld(tokens []string) float64 {
+	reversed := make([]string, len(tokens))
+	for i, token := range tokens {
+		reversed[len(tokens)-1-i] = token
+	}
+	return (mtldFactors(tokens) + mtldFactors(reversed)) / 2
+}
+
+func mtldFactors(tokens []string) float64 {
+	factors := 0.0
+	types := make(map[string]struct{})
+	count := 0
+	ttr := 1.0
+
+	for _, token := range tokens {
+		count++
+		types[token] = struct{}{}
+		ttr = float64(len(types)) / float64(count)
+		if ttr <= mtldThreshold {
+			factors++
+			types = make(map[string]struct{})
+			count = 0
+			ttr = 1
+		}
+	}
+	// the last, unfinished factor counts partially
+	if count > 0 {
+		factors += (1 - ttr) / (1 - mtldThreshold)
+	}
+	if factors == 0 {
+		return float64(len(tokens))
+	}
+	return float64(len(tokens)) / factors
+}
+
+// mattr is the average type-token ratio of all windows of the size, texts shorter than window return plain ratio
+func mattr(tokens []string, window int) float64 {
+	if len(tokens) < window {
+		return typeTokenRatio(tokens)
+	}
+
+	counts := make(map[string]int)
+	for _, token := range tokens[:window] {
+		counts[token]++
+	}
+	sum := float64(len(counts)) / float64(window)
+
+	for i := window; i < len(tokens); i++ {
+		counts[tokens[i]]++
+		out := tokens[i-window]
+		counts[out]--
+		if counts[out] == 0 {
+			delete(counts, out)
+		}
+		sum += float64(len(counts)) / float64(window)
+	}
+	return sum / float64(len(tokens)-window+1)
+}
+
+func typeTokenRatio(tokens []string) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	types := make(map[string]struct{})
+	for _, token := range tokens {
+		types[token] = struct{}{}
+	}
+	return float64(len(types)) / float64(len(tokens))
+}
+
+type SongStats struct {
+	Song  Song
+	Stats LexicalStats
+}
+
+type ArtistStats struct {
+	Songs []SongStats
+	// Total treats the whole catalogue as one text
+	Total LexicalStats
+	// Average is the mean of stats of songs
+	Average LexicalStats
+}
+
+func NewArtistStats(songs []Song) ArtistStats {
+	var output ArtistStats
+	var tokens []string
+	lines := 0
+
+	for _, song := range songs {
+		songTokens := song.Lyrics.Tokens()
+		songLines := len(song.Lyrics.Lines())
+		tokens = append(tokens, songTokens...)
+		lines += songLines
+
+		output.Songs = append(output.Songs, SongStats{Song: song, Stats: NewLexicalStats(songTokens, songLines)})
+	}
+
+	output.Total = NewLexicalStats(tokens, lines)
+	output.Average = averageStats(output.Songs)
+	return output
+}
+
+func averageStats(songs []SongStats) LexicalStats {
+	var average LexicalStats
+	if len(songs) == 0 {
+		return average
+	}
+
+	for _, song := range songs {
+		average.TypeTokenRatio += song.Stats.TypeTokenRatio
+		average.MTLD += song.Stats.MTLD
+		average.MATTR += song.Stats.MATTR
+		average.AverageWordLength += song.Stats.AverageWordLength
+		average.WordsPerLine += song.Stats.WordsPerLine
+		average.HapaxRatio += song.Stats.HapaxRatio
+		average.Words += song.Stats.Words
+		average.UniqueWords += song.Stats.UniqueWords
+		average.Lines += song.Stats.Lines
+		average.HapaxLegomena += song.Stats.HapaxLegomena
+	}
+
+	n := len(songs)
+	average.TypeTokenRatio /= float64(n)
+	average.MTLD /= float64(n)
+	average.MATTR /= float64(n)
+	average.AverageWordLength /= float64(n)
+	average.WordsPerLine /= float64(n)
+	average.HapaxRatio /= float64(n)
+	average.Words /= n
+	average.UniqueWords /= n
+	average.Lines /= n
+	average.HapaxLegomena /= n
+	return average
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const minStemLength = 3
+
+// stemSuffixes are removed from the end of words, the longest matching suffix wins,
+// it's light stemming - good enough to match "money" with "moneys" or "kasa" with "kasy", not a dictionary
+var stemSuffixes = map[string][]string{
+	"en": {"ingly", "ings", "ing", "edly", "ied", "ies", "ers", "ed", "er", "es", "ly", "'s", "s"},
+	"pl": {
+		"owiach", "ami", "ach", "ów", "om", "owie", "em", "ie", "ą", "ę", "y", "i", "a", "o", "u", "e",
+		"ego", "emu", "ych", "ymi", "ym", "ej", "ich", "imi", "im",
+		"łem", "łam", "łeś", "łaś", "liśmy", "łyśmy", "li", "ły", "ła", "ło", "ł",
+		"ać", "eć", "ić", "yć", "ować", "uje", "ujesz", "ujemy", "esz", "emy", "cie",
+	},
+}
+
+// StemLanguages returns languages which Stem supports
+func StemLanguages() []string {
+	return []string{"en", "pl"}
+}
+
+// Stem removes the inflection suffix of the language from the word, unknown languages return the word unchanged
+func Stem(word Word, language string) Word {
+	lower := strings.ToLower(string(word))
+
+	best := ""
+	for _, suffix := range stemSuffixes[language] {
+		if len(suffix) <= len(best) || strings.HasSuffix(lower, suffix) == false {
+			continue
+		}
+		if utf8.RuneCountInString(strings.TrimSuffix(lower, suffix)) >= minStemLength {
+			best = suffix
+		}
+	}
+	return Word(strings.TrimSuffix(lower, best))
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed stopwords/*.txt
+var stopWordsFS embed.FS
+
+const (
+	StopWordsAuto = "auto"
+	StopWordsNone = "none"
+)
+
+var UnknownLanguageError = errors.New("unknown language")
+
+type StopWords map[Word]struct{}
+
+func NewStopWords(words []Word) StopWords {
+	stopWords := make(StopWords)
+	for _, word := range words {
+		stopWords[Word(strings.ToLower(string(word)))] = struct{}{}
+	}
+	return stopWords
+}
+
+func (s StopWords) Contains(word Word) bool {
+	_, ok := s[word]
+	return ok
+}
+
+// Merge returns stop words of both sets, neither of the sets is modified
+func (s StopWords) Merge(other StopWords) StopWords {
+	output := make(StopWords)
+	for word := range s {
+		output[word] = struct{}{}
+	}
+	for word := range other {
+		output[word] = struct{}{}
+	}
+	return output
+}
+
+func (w WordsOccurrences) WithoutStopWords(stopWords StopWords) WordsOccurrences {
+	output := make(WordsOccurrences)
+	for word, occ := range w {
+		if stopWords.Contains(word) == false {
+			output[word] = occ
+		}
+	}
+	return output
+}
+
+// readStopWords reads words separated by whitespaces, lines starting with # are comments
+func readStopWords(r io.Reader) (StopWords, error) {
+	var words []Word
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, commentPrefix) {
+			continue
+		}
+		for _, word := range strings.Fields(line) {
+			words = append(words, Word(word))
+		}
+	}
+	return NewStopWords(words), sc.Err()
+}
+
+// StopWordsRegistry keeps stop words by language code, ex. "en" or "pl"
+type StopWordsRegistry struct {
+	languages map[string]StopWords
+}
+
+func NewStopWordsRegistry(languages map[string]StopWords) *StopWordsRegistry {
+	return &StopWordsRegistry{languages: languages}
+}
+
+// LoadStopWordsRegistry reads the embedded lists, then "<dir>/<language>.txt" files which extend them
+// or add new languages, dir is optional
+func LoadStopWordsRegistry(dir string) (*StopWordsRegistry, error) {
+	r := NewStopWordsRegistry(make(map[string]StopWords))
+	if err := r.load(stopWordsFS, "stopwords"); err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		return r, nil
+	}
+	if err := r.load(os.DirFS(dir), "."); err != nil && errors.Is(err, os.ErrNotExist) == false {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *StopWordsRegistry) load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if _, err := fs.Stat(fsys, dir); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range files {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		stopWords, err := readStopWords(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading stop words %q: %w", name, err)
+		}
+
+		language := strings.ToLower(strings.TrimSuffix(path.Base(name), ".txt"))
+		r.languages[language] = stopWords.Merge(r.languages[language])
+	}
+	return nil
+}
+
+func (r *StopWordsRegistry) Languages() []string {
+	var languages []string
+	for language := range r.languages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func (r *StopWordsRegistry) Get(language string) (StopWords, error) {
+	stopWords, ok := r.languages[strings.ToLower(language)]
+	if ok == false {
+		return nil, fmt.Errorf("%w: %q", UnknownLanguageError, language)
+	}
+	return stopWords, nil
+}
+
+// Detect returns the language which stop words cover the biggest part of words, empty string when none of them matches
+func (r *StopWordsRegistry) Detect(words WordsOccurrences) string {
+	bestLanguage, bestCount := "", 0
+	for _, language := range r.Languages() {
+		count := 0
+		for word, occ := range words {
+			if r.languages[language].Contains(word) {
+				count += occ
+			}
+		}
+		if count > bestCount {
+			bestLanguage, bestCount = language, count
+		}
+	}
+	return bestLanguage
+}
+
+// ForLanguages merges stop words of the languages, the unknown languages are skipped
+func (r *StopWordsRegistry) ForLanguages(languages []string) StopWords {
+	output := StopWords{}
+	for _, language := range languages {
+		output = output.Merge(r.languages[language])
+	}
+	return output
+}
+
+// Resolve returns stop words for the language, which may be also StopWordsNone or StopWordsAuto - then stop words
+// of detected `languages` are used or, when they are unknown, the language is guessed from words,
+// the returned language is the one that has been used, ex. "pl,en" for mixed songs
+func (r *StopWordsRegistry) Resolve(language string, languages []string, words WordsOccurrences) (StopWords, string, error) {
+	switch strings.ToLower(language) {
+	case "", StopWordsNone:
+		return StopWords{}, StopWordsNone, nil
+	case StopWordsAuto:
+		var known []string
+		for _, v := range languages {
+			if _, ok := r.languages[v]; ok {
+				known = append(known, v)
+			}
+		}
+		if len(known) > 0 {
+			return r.ForLanguages(known), strings.Join(known, ","), nil
+		}
+		language = r.Detect(words)
+		if language == "" {
+			return StopWords{}, StopWordsNone, nil
+		}
+	}
+
+	stopWords, err := r.Get(language)
+	if err != nil {
+		return nil, "", err
+	}
+	return stopWords, strings.ToLower(language), nil
+}
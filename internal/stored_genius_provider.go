@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var _ GeniusProvider = &StoredGeniusProvider{}
+
+// StoredGeniusProvider reads through the repository, genius is asked only for things which haven't been stored yet
+// or are older than LYRICS_MAX_AGE and ARTIST_SONGS_MAX_AGE. Search is never stored
+type StoredGeniusProvider struct {
+	provider   GeniusProvider
+	repository LyricsRepository
+	cfg        *config.Config
+	logger     *log.Entry
+}
+
+func NewStoredGeniusProvider(cfg *config.Config, provider GeniusProvider, repository LyricsRepository, logger *log.Entry) *StoredGeniusProvider {
+	return &StoredGeniusProvider{provider: provider, repository: repository, cfg: cfg, logger: logger}
+}
+
+// isFresh tells if stored record can be used, zero max age means that it never gets old. Record without fetch date
+// (ex. imported songs of the artist without the listing) is never fresh
+func isFresh(fetchedAt time.Time, maxAge time.Duration) bool {
+	return fetchedAt.IsZero() == false && (maxAge == 0 || time.Since(fetchedAt) < maxAge)
+}
+
+// stored logs errors of the repository, the data has been downloaded anyway so the request doesn't fail
+func (s *StoredGeniusProvider) stored(err error, what string) {
+	if err != nil {
+		s.logger.WithError(err).Errorf("cannot store %s", what)
+	}
+}
+
+func (s *StoredGeniusProvider) Search(query string) ([]GeniusSearchResult, error) {
+	return s.provider.Search(query)
+}
+
+func (s *StoredGeniusProvider) GetArtist(artistName string) (GeniusArtist, error) {
+	stored, err := s.repository.GetArtist(artistName)
+	if err == nil {
+		return stored.Artist, nil
+	}
+	if errors.Is(err, NotStoredError) == false {
+		s.logger.WithError(err).Error("cannot read stored artist")
+	}
+
+	artist, err := s.provider.GetArtist(artistName)
+	if err != nil {
+		return GeniusArtist{}, err
+	}
+	// artist which hasn't been found is empty, it's not stored so it can be found later
+	if artist.ID != 0 {
+		s.stored(s.repository.SaveArtist(artistName, artist), "artist")
+	}
+	return artist, nil
+}
+
+func (s *StoredGeniusProvider) GetSongInfoByID(id int) (GeniusSongInfo, error) {
+	songInfo, err := s.repository.GetSongInfo(id)
+	if err == nil {
+		return songInfo, nil
+	}
+	if errors.Is(err, NotStoredError) == false {
+		s.logger.WithError(err).Error("cannot read stored song info")
+	}
+
+	songInfo, err = s.provider.GetSongInfoByID(id)
+	if err != nil {
+		return GeniusSongInfo{}, err
+	}
+	s.stored(s.repository.SaveSongInfos([]GeniusSongInfo{songInfo}), "song info")
+	return songInfo, nil
+}
+
+func (s *StoredGeniusProvider) GetLyrics(songInfo GeniusSongInfo) (Lyrics, error) {
+	stored, err := s.repository.GetLyrics(songInfo.ID)
+	if err == nil && isFresh(stored.FetchedAt, s.cfg.LyricsMaxAge) {
+		return stored.Lyrics, nil
+	}
+	if err != nil && errors.Is(err, NotStoredError) == false {
+		s.logger.WithError(err).Error("cannot read stored lyrics")
+	}
+
+	lyrics, err := s.provider.GetLyrics(songInfo)
+	if err != nil {
+		// old lyrics are better than nothing, ex. offline
+		if stored.Lyrics != "" {
+			s.logger.WithError(err).Warn("cannot refresh lyrics, the stored ones are used")
+			return stored.Lyrics, nil
+		}
+		return "", err
+	}
+	s.stored(s.repository.SaveLyrics(songInfo.ID, lyrics), "lyrics")
+	return lyrics, nil
+}
+
+func (s *StoredGeniusProvider) GetSongByID(id int) (GeniusSong, error) {
+	return getSongByID(s, id)
+}
+
+func (s *StoredGeniusProvider) GetSongsByIDs(ids []int) ([]GeniusSong, error) {
+	return getSongsByIDs(s, ids, s.logger), nil
+}
+
+func (s *StoredGeniusProvider) GetSongByName(name string) (GeniusSong, error) {
+	song, err := s.provider.GetSongByName(name)
+	if err != nil {
+		return song, err
+	}
+	s.stored(s.repository.SaveSongInfos([]GeniusSongInfo{song.Info}), "song info")
+	s.stored(s.repository.SaveLyrics(song.Info.ID, song.Lyrics), "lyrics")
+	return song, nil
+}
+
+func (s *StoredGeniusProvider) GetSongInfosByArtistID(artistID int) ([]GeniusSongInfo, error) {
+	stored, err := s.repository.GetArtistSongInfos(artistID)
+	if err == nil && isFresh(stored.FetchedAt, s.cfg.ArtistSongsMaxAge) {
+		return stored.Songs, nil
+	}
+	if err != nil && errors.Is(err, NotStoredError) == false {
+		s.logger.WithError(err).Error("cannot read stored songs of artist")
+	}
+
+	songInfos, err := s.provider.GetSongInfosByArtistID(artistID)
+	if err != nil {
+		if stored.Songs != nil {
+			s.logger.WithError(err).Warn("cannot refresh songs of artist, the stored ones are used")
+			return stored.Songs, nil
+		}
+		return nil, err
+	}
+	s.stored(s.repository.SaveArtistSongInfos(artistID, songInfos), "songs of artist")
+	return songInfos, nil
+}
+
+// GetSongsByArtistID downloads only lyrics which aren't stored, songs which lyrics can't be downloaded are skipped
+func (s *StoredGeniusProvider) GetSongsByArtistID(artistID int) ([]GeniusSong, error) {
+	return getSongsByArtistID(s, artistID)
+}
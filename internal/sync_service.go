@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/config"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ArtistNotFoundError = errors.New("artist not found")
+
+type SyncedSong struct {
+	GeniusID int    `json:"genius_id"`
+	Title    string `json:"title"`
+}
+
+type SyncResult struct {
+	Artist GeniusArtist `json:"artist"`
+	// Added are songs which haven't been stored before
+	Added []SyncedSong `json:"added"`
+	// Updated are songs which lyrics have been downloaded again, because they have been completed or weren't stored
+	Updated []SyncedSong `json:"updated"`
+	// Removed are stored songs which aren't listed by genius anymore
+	Removed []SyncedSong `json:"removed"`
+	// Restored are removed songs which are listed by genius again, their lyrics are downloaded only when they have been
+	// completed since or weren't stored
+	Restored  []SyncedSong `json:"restored"`
+	Unchanged int          `json:"unchanged"`
+	// Failed are songs which lyrics couldn't be downloaded, they are retried by the next sync and aren't reported
+	// as added, updated or restored
+	Failed   []SyncedSong  `json:"failed"`
+	Duration time.Duration `json:"-"`
+}
+
+type SyncService interface {
+	SyncArtist(artistName string) (SyncResult, error)
+}
+
+var _ SyncService = &InternalSyncService{}
+
+// InternalSyncService compares the current listing of songs of the artist with the stored one and downloads lyrics
+// only of new songs and songs which have been completed since the last sync
+type InternalSyncService struct {
+	geniusProvider GeniusProvider
+	repository     LyricsRepository
+	cfg            *config.Config
+	logger         *log.Entry
+}
+
+// NewSyncService needs provider which doesn't read from the repository, otherwise the listing would be the stored one
+func NewSyncService(cfg *config.Config, geniusProvider GeniusProvider, repository LyricsRepository, logger *log.Entry) *InternalSyncService {
+	return &InternalSyncService{geniusProvider: geniusProvider, repository: repository, cfg: cfg, logger: logger}
+}
+
+func newSyncedSong(song GeniusSongInfo) SyncedSong {
+	return SyncedSong{GeniusID: song.ID, Title: song.FullTitle}
+}
+
+func (s *InternalSyncService) SyncArtist(artistName string) (SyncResult, error) {
+	started := time.Now()
+	result := SyncResult{Added: []SyncedSong{}, Updated: []SyncedSong{}, Removed: []SyncedSong{}, Restored: []SyncedSong{}, Failed: []SyncedSong{}}
+
+	artist, err := s.geniusProvider.GetArtist(artistName)
+	if err != nil {
+		return result, err
+	}
+	if artist.ID == 0 {
+		return result, ArtistNotFoundError
+	}
+	result.Artist = artist
+	if err := s.repository.SaveArtist(artistName, artist); err != nil {
+		return result, err
+	}
+
+	stored, err := s.repository.GetArtistSongInfos(artist.ID)
+	if err != nil && errors.Is(err, NotStoredError) == false {
+		return result, err
+	}
+	storedSongs := make(map[int]GeniusSongInfo)
+	for _, song := range stored.Songs {
+		storedSongs[song.ID] = song
+	}
+
+	current, err := s.geniusProvider.GetSongInfosByArtistID(artist.ID)
+	if err != nil {
+		return result, err
+	}
+
+	var added, updated, restored, download []GeniusSongInfo
+	currentIDs := make(map[int]struct{})
+	for _, song := range current {
+		currentIDs[song.ID] = struct{}{}
+		storedSong, ok := storedSongs[song.ID]
+		if ok == false {
+			// removed songs aren't in the stored listing, but they are still kept
+			removedSong, err := s.repository.GetSongInfo(song.ID)
+			if err != nil {
+				if errors.Is(err, NotStoredError) == false {
+					return result, err
+				}
+				added = append(added, song)
+				download = append(download, song)
+				continue
+			}
+			restored = append(restored, song)
+			if s.needsLyrics(song, removedSong) {
+				download = append(download, song)
+			}
+			continue
+		}
+
+		if s.needsLyrics(song, storedSong) {
+			updated = append(updated, song)
+			download = append(download, song)
+			continue
+		}
+		result.Unchanged++
+	}
+
+	var removedIDs []int
+	for _, song := range stored.Songs {
+		if _, ok := currentIDs[song.ID]; ok == false {
+			removedIDs = append(removedIDs, song.ID)
+			result.Removed = append(result.Removed, newSyncedSong(song))
+		}
+	}
+
+	failed := s.downloadLyrics(download)
+	failedIDs := make(map[int]struct{})
+	for _, song := range failed {
+		failedIDs[song.ID] = struct{}{}
+		result.Failed = append(result.Failed, newSyncedSong(song))
+	}
+	// new and restored songs which have failed aren't listed yet, so the next sync reports them the same way
+	unlisted := make(map[int]struct{})
+	for _, change := range []struct {
+		songs    []GeniusSongInfo
+		synced   *[]SyncedSong
+		unlisted bool
+	}{{added, &result.Added, true}, {updated, &result.Updated, false}, {restored, &result.Restored, true}} {
+		for _, song := range change.songs {
+			if _, ok := failedIDs[song.ID]; ok == false {
+				*change.synced = append(*change.synced, newSyncedSong(song))
+			} else if change.unlisted {
+				unlisted[song.ID] = struct{}{}
+			}
+		}
+	}
+
+	var listed []GeniusSongInfo
+	for _, song := range current {
+		if _, ok := unlisted[song.ID]; ok == false {
+			listed = append(listed, song)
+		}
+	}
+	if err := s.repository.SaveArtistSongInfos(artist.ID, listed); err != nil {
+		return result, err
+	}
+	if err := s.repository.MarkSongsRemoved(removedIDs); err != nil {
+		return result, err
+	}
+	result.Duration = time.Since(started)
+	return result, nil
+}
+
+// needsLyrics tells if lyrics of the stored song have to be downloaded again, because it has been completed since
+// or they weren't stored
+func (s *InternalSyncService) needsLyrics(song GeniusSongInfo, stored GeniusSongInfo) bool {
+	return song.LyricsState == LyricsComplete && (stored.LyricsState != LyricsComplete || s.isMissingLyrics(song.ID))
+}
+
+func (s *InternalSyncService) isMissingLyrics(songID int) bool {
+	_, err := s.repository.GetLyrics(songID)
+	return err != nil
+}
+
+// downloadLyrics stores lyrics of complete songs, songs which lyrics couldn't be downloaded are returned
+func (s *InternalSyncService) downloadLyrics(songs []GeniusSongInfo) []GeniusSongInfo {
+	var failed []GeniusSongInfo
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, song := range songs {
+		if song.LyricsState != LyricsComplete {
+			continue
+		}
+
+		wg.Add(1)
+		song := song
+		go func() {
+			defer wg.Done()
+			lyrics, err := s.geniusProvider.GetLyrics(song)
+			if err == nil {
+				err = s.repository.SaveLyrics(song.ID, lyrics)
+			}
+			if err != nil {
+				s.logger.WithError(err).WithField("song_id", song.ID).Error("cannot sync lyrics")
+				mutex.Lock()
+				failed = append(failed, song)
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].ID < failed[j].ID
+	})
+	return failed
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"math"
+	"sort"
+)
+
+type WordScore struct {
+	Word  Word    `json:"word"`
+	Count int     `json:"count"`
+	Score float64 `json:"score"`
+}
+
+// Corpus knows in how many documents every word appears, document may be a song or the whole catalogue of an artist
+type Corpus struct {
+	documents int
+	frequency map[Word]int
+}
+
+func NewCorpus(documents []WordsOccurrences) *Corpus {
+	c := &Corpus{documents: len(documents), frequency: make(map[Word]int)}
+	for _, document := range documents {
+		for word, count := range document {
+			if count > 0 {
+				c.frequency[word]++
+			}
+		}
+	}
+	return c
+}
+
+func (c *Corpus) Documents() int {
+	return c.documents
+}
+
+// IDF is smoothed, so words present in every document have score 1 instead of 0
+func (c *Corpus) IDF(word Word) float64 {
+	return math.Log(float64(1+c.documents)/float64(1+c.frequency[word])) + 1
+}
+
+// TFIDF scores words of the document, term frequency is the part of the document's words, the best words are first
+func (c *Corpus) TFIDF(document WordsOccurrences) []WordScore {
+	total := document.Total()
+	if total == 0 {
+		return []WordScore{}
+	}
+
+	scores := make([]WordScore, 0, len(document))
+	for word, count := range document {
+		if count <= 0 {
+			continue
+		}
+		tf := float64(count) / float64(total)
+		scores = append(scores, WordScore{Word: word, Count: count, Score: tf * c.IDF(word)})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Word < scores[j].Word
+	})
+	return scores
+}
+
+// TopWords returns `limit` words with the best scores, limit 0 returns all of them
+func (c *Corpus) TopWords(document WordsOccurrences, limit int) []WordScore {
+	scores := c.TFIDF(document)
+	if limit > 0 && len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores
+}
+
+type SongDistinctiveWords struct {
+	Song  Song
+	Words []WordScore
+}
+
+// SongsDistinctiveWords compares every song with all of the corpus songs, ex. the other songs of the same artist
+// or songs of several artists, the songs themselves should be a part of the corpus
+func SongsDistinctiveWords(songs []Song, corpusSongs []Song, stopWords StopWords, limit int) []SongDistinctiveWords {
+	var documents []WordsOccurrences
+	for _, song := range corpusSongs {
+		documents = append(documents, song.Lyrics.FindWords().WithoutStopWords(stopWords))
+	}
+	corpus := NewCorpus(documents)
+
+	var output []SongDistinctiveWords
+	for _, song := range songs {
+		output = append(output, SongDistinctiveWords{
+			Song:  song,
+			Words: corpus.TopWords(song.Lyrics.FindWords().WithoutStopWords(stopWords), limit),
+		})
+	}
+	return output
+}
+
+// ArtistsDistinctiveWords treats catalogue of each artist as one document, so the words of an artist
+// are the ones which other artists don't use
+func ArtistsDistinctiveWords(artists map[string][]Song, stopWords StopWords, limit int) map[string][]WordScore {
+	vocabularies := make(map[string]WordsOccurrences)
+	var documents []WordsOccurrences
+	for artist, songs := range artists {
+		vocabularies[artist] = ArtistVocabulary(songs).WithoutStopWords(stopWords)
+		documents = append(documents, vocabularies[artist])
+	}
+	corpus := NewCorpus(documents)
+
+	output := make(map[string][]WordScore)
+	for artist, vocabulary := range vocabularies {
+		output[artist] = corpus.TopWords(vocabulary, limit)
+	}
+	return output
+}
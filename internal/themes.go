@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// ThemesPrefix is the prefix of dictionaries of themes, ex. "themes/money" or "pl/themes/money" for polish songs
+const ThemesPrefix = "themes/"
+
+type ThemeScore struct {
+	Theme   string `json:"theme"`
+	Matches int    `json:"matches"`
+	// Share is matches per 1000 words
+	Share float64 `json:"share"`
+	// Proportion is the part of matches of all themes
+	Proportion float64 `json:"proportion"`
+}
+
+// ThemesNames returns names of themes from dictionaries "themes/<name>" and "<language>/themes/<name>"
+func ThemesNames(service DictionaryService) []string {
+	var names []string
+	for _, dictionaryName := range service.GetDictionariesNames() {
+		index := strings.Index(dictionaryName, ThemesPrefix)
+		if index == -1 || (index > 0 && dictionaryName[index-1] != '/') {
+			continue
+		}
+		name := dictionaryName[index+len(ThemesPrefix):]
+		if name != "" && isOneOf(name, names) == false {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetThemes returns dictionaries of the themes, empty names mean all of the themes
+func GetThemes(service DictionaryService, names []string) ([]SongDictionary, error) {
+	if len(names) == 0 {
+		names = ThemesNames(service)
+	}
+
+	var themes []SongDictionary
+	for _, name := range names {
+		theme, err := GetSongDictionary(service, ThemesPrefix+name, 0)
+		if err != nil {
+			return nil, err
+		}
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+// SongsThemes counts words of themes in all of the songs, themes without matches are reported too
+func SongsThemes(songs []Song, themes []SongDictionary) []ThemeScore {
+	output := []ThemeScore{}
+	words := 0
+	songsWords := make([]WordsOccurrences, len(songs))
+	for i, song := range songs {
+		songsWords[i] = song.Lyrics.FindWords()
+		words += songsWords[i].Total()
+	}
+
+	all := 0
+	for _, theme := range themes {
+		score := ThemeScore{Theme: strings.TrimPrefix(theme.Name, ThemesPrefix)}
+		for i, song := range songs {
+			for _, match := range theme.Dictionary(song).FindMatchesIn(songsWords[i], LanguagesOf(song.Languages)) {
+				score.Matches += match.Occurrences
+			}
+		}
+		if words > 0 {
+			score.Share = math.Round(float64(score.Matches)*1000/float64(words)*100) / 100
+		}
+		all += score.Matches
+		output = append(output, score)
+	}
+
+	for i := range output {
+		if all > 0 {
+			output[i].Proportion = math.Round(float64(output[i].Matches)/float64(all)*1000) / 1000
+		}
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Matches > output[j].Matches
+	})
+	return output
+}
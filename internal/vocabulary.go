@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var UnknownVocabularyOrderError = errors.New("unknown vocabulary order")
+
+type WordCount struct {
+	Word  Word `json:"word"`
+	Count int  `json:"count"`
+}
+
+type VocabularyOrder string
+
+const (
+	OrderCountDesc VocabularyOrder = "count_desc"
+	OrderCountAsc  VocabularyOrder = "count_asc"
+	OrderWordAsc   VocabularyOrder = "word_asc"
+	OrderWordDesc  VocabularyOrder = "word_desc"
+)
+
+var vocabularyOrders = []VocabularyOrder{OrderCountDesc, OrderCountAsc, OrderWordAsc, OrderWordDesc}
+
+func ParseVocabularyOrder(s string) (VocabularyOrder, error) {
+	if s == "" {
+		return OrderCountDesc, nil
+	}
+	for _, order := range vocabularyOrders {
+		if string(order) == strings.ToLower(s) {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", UnknownVocabularyOrderError, s)
+}
+
+func (w WordsOccurrences) Sorted(order VocabularyOrder) []WordCount {
+	output := make([]WordCount, 0, len(w))
+	for word, occ := range w {
+		output = append(output, WordCount{Word: word, Count: occ})
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		a, b := output[i], output[j]
+		switch order {
+		case OrderWordAsc:
+			return a.Word < b.Word
+		case OrderWordDesc:
+			return a.Word > b.Word
+		case OrderCountAsc:
+			if a.Count != b.Count {
+				return a.Count < b.Count
+			}
+		default:
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+		}
+		return a.Word < b.Word
+	})
+	return output
+}
+
+// ArtistVocabulary merges words of all of the songs into one map
+func ArtistVocabulary(songs []Song) WordsOccurrences {
+	vocabulary := make(WordsOccurrences)
+	for _, song := range songs {
+		vocabulary.Append(song.Lyrics.FindWords())
+	}
+	return vocabulary
+}
+
+type VocabularyQuery struct {
+	Order     VocabularyOrder
+	MinCount  int
+	StopWords StopWords
+	Limit     int
+	Page      int
+}
+
+type VocabularyPage struct {
+	Words         []WordCount
+	TotalWords    int
+	UniqueWords   int
+	MatchingWords int
+	Page          int
+	Pages         int
+	Limit         int
+}
+
+// QueryVocabulary filters and sorts words, then returns one page of them, Limit 0 means there is only one page with every word
+func QueryVocabulary(vocabulary WordsOccurrences, query VocabularyQuery) VocabularyPage {
+	filtered := vocabulary.WithoutStopWords(query.StopWords)
+	for word, occ := range filtered {
+		if occ < query.MinCount {
+			delete(filtered, word)
+		}
+	}
+	words := filtered.Sorted(query.Order)
+
+	page := VocabularyPage{
+		TotalWords:    vocabulary.Total(),
+		UniqueWords:   len(vocabulary),
+		MatchingWords: len(words),
+		Page:          query.Page,
+		Pages:         1,
+		Limit:         query.Limit,
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	if query.Limit <= 0 {
+		if page.Page == 1 {
+			page.Words = words
+		}
+		return page
+	}
+
+	page.Pages = (len(words) + query.Limit - 1) / query.Limit
+	start := (page.Page - 1) * query.Limit
+	if start >= len(words) {
+		page.Words = []WordCount{}
+		return page
+	}
+
+	end := start + query.Limit
+	if end > len(words) {
+		end = len(words)
+	}
+	page.Words = words[start:end]
+	return page
+}
@@ -33,6 +33,27 @@ func (_m *GeniusProvider) GetArtist(artistName string) (internal.GeniusArtist, e
 	return r0, r1
 }
 
+// GetLyrics provides a mock function with given fields: songInfo
+func (_m *GeniusProvider) GetLyrics(songInfo internal.GeniusSongInfo) (internal.Lyrics, error) {
+	ret := _m.Called(songInfo)
+
+	var r0 internal.Lyrics
+	if rf, ok := ret.Get(0).(func(internal.GeniusSongInfo) internal.Lyrics); ok {
+		r0 = rf(songInfo)
+	} else {
+		r0 = ret.Get(0).(internal.Lyrics)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(internal.GeniusSongInfo) error); ok {
+		r1 = rf(songInfo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetSongByID provides a mock function with given fields: id
 func (_m *GeniusProvider) GetSongByID(id int) (internal.GeniusSong, error) {
 	ret := _m.Called(id)
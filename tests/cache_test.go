@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRESPServer understands just enough of redis protocol for the cache - AUTH, GET, SET with PX and DEL
+type fakeRESPServer struct {
+	listener net.Listener
+	password string
+	values   map[string]string
+	expires  map[string]time.Time
+	mutex    sync.Mutex
+}
+
+func startFakeRESPServer(t *testing.T, password string) *fakeRESPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &fakeRESPServer{listener: listener, password: password, values: make(map[string]string), expires: make(map[string]time.Time)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeRESPServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRESPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readFakeRESPCommand(reader)
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(args[0])
+		if command == "AUTH" {
+			authenticated = args[1] == s.password
+			if authenticated {
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+			continue
+		}
+		if authenticated == false {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		io.WriteString(conn, s.execute(command, args[1:]))
+	}
+}
+
+func (s *fakeRESPServer) execute(command string, args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch command {
+	case "GET":
+		// two replies put the connection out of step
+		if args[0] == "out-of-step" {
+			return ":1\r\n+OK\r\n"
+		}
+		value, ok := s.values[args[0]]
+		if expires, expiring := s.expires[args[0]]; ok == false || (expiring && time.Now().After(expires)) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		s.values[args[0]] = args[1]
+		delete(s.expires, args[0])
+		if len(args) == 4 && strings.ToUpper(args[2]) == "PX" {
+			milliseconds, _ := strconv.Atoi(args[3])
+			s.expires[args[0]] = time.Now().Add(time.Duration(milliseconds) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.values[args[0]]
+		delete(s.values, args[0])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func readFakeRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, count)
+	for i := range args {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// testCache is run against every backend, so they behave the same way
+func testCache(t *testing.T, cache internal.Cache) {
+	_, err := cache.Get("missing")
+	assert.True(t, errors.Is(err, internal.CacheMissError))
+
+	assert.NoError(t, cache.Set("key", []byte("value\r\nwith new line"), 0))
+	entry, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value\r\nwith new line"), entry.Value)
+	assert.True(t, entry.Age() < time.Minute)
+
+	assert.NoError(t, cache.Set("key", []byte("overwritten"), 0))
+	entry, _ = cache.Get("key")
+	assert.Equal(t, []byte("overwritten"), entry.Value)
+
+	assert.NoError(t, cache.Delete("key"))
+	assert.NoError(t, cache.Delete("key"))
+	_, err = cache.Get("key")
+	assert.True(t, errors.Is(err, internal.CacheMissError))
+
+	assert.NoError(t, cache.Set("expiring", []byte("value"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, err = cache.Get("expiring")
+	assert.True(t, errors.Is(err, internal.CacheMissError))
+}
+
+func TestLRUCache(t *testing.T) {
+	testCache(t, internal.NewLRUCache(10))
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	cache := internal.NewLRUCache(2)
+	cache.Set("first", []byte("1"), 0)
+	cache.Set("second", []byte("2"), 0)
+	cache.Get("first")
+	cache.Set("third", []byte("3"), 0)
+
+	assert.Equal(t, 2, cache.Len())
+	_, err := cache.Get("second")
+	assert.True(t, errors.Is(err, internal.CacheMissError))
+	_, err = cache.Get("first")
+	assert.NoError(t, err)
+}
+
+func TestDiskCache(t *testing.T) {
+	cache, err := internal.NewDiskCache(t.TempDir())
+	assert.NoError(t, err)
+	testCache(t, cache)
+}
+
+func TestRESPCache(t *testing.T) {
+	server := startFakeRESPServer(t, "secret")
+	cache := internal.NewRESPCache(server.addr(), "secret", time.Second)
+	defer cache.Close()
+	testCache(t, cache)
+
+	wrongPassword := internal.NewRESPCache(server.addr(), "wrong", time.Second)
+	_, err := wrongPassword.Get("key")
+	assert.True(t, errors.Is(err, internal.RESPError))
+}
+
+func TestRESPCacheReconnects(t *testing.T) {
+	server := startFakeRESPServer(t, "")
+	cache := internal.NewRESPCache(server.addr(), "", time.Second)
+	assert.NoError(t, cache.Set("key", []byte("value"), 0))
+
+	// broken connection is replaced by the new one
+	cache.Close()
+	entry, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), entry.Value)
+}
+
+func TestRESPCacheReconnectsAfterUnexpectedReply(t *testing.T) {
+	server := startFakeRESPServer(t, "")
+	cache := internal.NewRESPCache(server.addr(), "", time.Second)
+	defer cache.Close()
+	_, err := cache.Get("out-of-step")
+	assert.True(t, errors.Is(err, internal.RESPError))
+
+	// the extra reply isn't read as the reply of the next commands
+	assert.NoError(t, cache.Set("key", []byte("value"), 0))
+	entry, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), entry.Value)
+}
+
+func TestUnknownCacheBackend(t *testing.T) {
+	cfg := GetConfig()
+	cache, err := internal.NewCache(cfg)
+	assert.NoError(t, err)
+	assert.Nil(t, cache)
+
+	cfg.CacheBackend = "memcached"
+	_, err = internal.NewCache(cfg)
+	assert.True(t, errors.Is(err, internal.UnknownCacheBackendError))
+}
+
+func getCachedGeniusProvider(ttl time.Duration) (*mocks.GeniusProvider, *internal.CachedGeniusProvider) {
+	cfg := GetConfig()
+	cfg.CacheSearchTTL = ttl
+	cfg.CacheArtistSongsTTL = ttl
+	cfg.CacheLyricsTTL = ttl
+	cfg.CacheStaleTTL = time.Hour
+	geniusProvider := &mocks.GeniusProvider{}
+	return geniusProvider, internal.NewCachedGeniusProvider(cfg, geniusProvider, internal.NewLRUCache(100), log.NewEntry(log.New()))
+}
+
+func TestCachedGeniusProvider(t *testing.T) {
+	geniusProvider, provider := getCachedGeniusProvider(time.Hour)
+	songs := []internal.GeniusSongInfo{storedSongInfo(1, "Lose Yourself"), storedSongInfo(2, "Stan")}
+	geniusProvider.On("GetSongInfosByArtistID", storedArtist.ID).Return(songs, nil).Once()
+	geniusProvider.On("GetLyrics", songs[0]).Return(internal.Lyrics("first"), nil).Once()
+	geniusProvider.On("GetLyrics", songs[1]).Return(internal.Lyrics(""), anyError).Once()
+	geniusProvider.On("GetLyrics", songs[1]).Return(internal.Lyrics("second"), nil).Once()
+
+	found, err := provider.GetSongsByArtistID(storedArtist.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.GeniusSong{{Lyrics: "first", Info: songs[0]}}, found)
+
+	// errors aren't cached
+	found, err = provider.GetSongsByArtistID(storedArtist.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(found))
+	geniusProvider.AssertExpectations(t)
+}
+
+func TestCachedGeniusProviderStaleWhileRevalidate(t *testing.T) {
+	geniusProvider, provider := getCachedGeniusProvider(100 * time.Millisecond)
+	refreshed := make(chan struct{})
+	geniusProvider.On("GetArtist", "eminem").Return(internal.GeniusArtist{ID: 1, Name: "old"}, nil).Once()
+	geniusProvider.On("GetArtist", "eminem").Return(internal.GeniusArtist{ID: 1, Name: "new"}, nil).
+		Run(func(args mock.Arguments) { <-refreshed }).Once()
+
+	artist, _ := provider.GetArtist("eminem")
+	assert.Equal(t, "old", artist.Name)
+	time.Sleep(120 * time.Millisecond)
+
+	// stale artist is returned without waiting for genius, the refresh is running in the background
+	artist, _ = provider.GetArtist("eminem")
+	assert.Equal(t, "old", artist.Name)
+	assert.Equal(t, 1, provider.Refreshing())
+	artist, _ = provider.GetArtist("eminem")
+	assert.Equal(t, "old", artist.Name)
+
+	close(refreshed)
+	for provider.Refreshing() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	artist, _ = provider.GetArtist("eminem")
+	assert.Equal(t, "new", artist.Name)
+	geniusProvider.AssertExpectations(t)
+}
+
+func TestCachedGeniusProviderSkipsNotFoundArtist(t *testing.T) {
+	geniusProvider, provider := getCachedGeniusProvider(time.Hour)
+	geniusProvider.On("GetArtist", "eminem").Return(internal.GeniusArtist{}, nil).Once()
+	geniusProvider.On("GetArtist", "eminem").Return(internal.GeniusArtist{ID: 1, Name: "Eminem"}, nil).Once()
+
+	artist, err := provider.GetArtist("eminem")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, artist.ID)
+
+	// the artist which hasn't been found is asked again
+	artist, err = provider.GetArtist("eminem")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, artist.ID)
+	geniusProvider.AssertExpectations(t)
+}
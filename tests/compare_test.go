@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	"github.com/marosiak/WordFinder/utils"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompareArtists(t *testing.T) {
+	artists := map[string][]internal.Song{
+		"eminem": {song("a", "money slim shady the"), song("b", "slim money")},
+		"taco":   {song("c", "hajs money the"), song("d", "hajs hajs")},
+	}
+
+	comparison := internal.CompareArtists(artists, []string{"eminem", "taco"}, internal.CompareOptions{
+		StopWords: internal.NewStopWords([]internal.Word{"the"}),
+	})
+
+	assert.Equal(t, []internal.WordCount{{Word: "money", Count: 3}}, comparison.SharedWords)
+	assert.Equal(t, 1, comparison.SharedCount)
+	assert.Equal(t, 0.25, comparison.Jaccard)
+	assert.Equal(t, 1, len(comparison.Pairs))
+	assert.Equal(t, 0.25, comparison.Pairs[0].Jaccard)
+
+	assert.Equal(t, "eminem", comparison.Artists[0].Name)
+	assert.Equal(t, 2, comparison.Artists[0].ExclusiveWords)
+	assert.Equal(t, []internal.WordCount{{Word: "slim", Count: 2}, {Word: "shady", Count: 1}}, comparison.Artists[0].TopExclusiveWords)
+	assert.Equal(t, []internal.WordCount{{Word: "hajs", Count: 3}}, comparison.Artists[1].TopExclusiveWords)
+	assert.Nil(t, comparison.Artists[0].Explicitness)
+}
+
+func TestCompareArtistsExplicitness(t *testing.T) {
+	artists := map[string][]internal.Song{
+		"clean":    {song("a", "love love love love")},
+		"explicit": {song("b", "damn love damn love")},
+	}
+	dictionary := &internal.SongDictionary{Versions: []internal.DictionaryVersion{{Dictionary: dictionary("swears", "damn")}}}
+
+	comparison := internal.CompareArtists(artists, []string{"clean", "explicit"}, internal.CompareOptions{Dictionary: dictionary})
+	assert.Equal(t, float64(0), comparison.Artists[0].Explicitness.Score)
+	assert.Equal(t, float64(500), comparison.Artists[1].Explicitness.Score)
+	assert.Equal(t, float64(2), comparison.Artists[1].Explicitness.Relative)
+	assert.Equal(t, 1, comparison.Artists[1].Explicitness.SongsWithMatches)
+}
+
+func TestGetSongsOfArtists(t *testing.T) {
+	lyricsService := &mocks.LyricsService{}
+	lyricsService.On("GetSongsByArtist", "eminem").Return([]internal.Song{song("a", "")}, nil)
+	lyricsService.On("GetSongsByArtist", "taco").Return(nil, anyError)
+
+	artists, err := internal.GetSongsOfArtists(lyricsService, []string{"eminem"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(artists["eminem"]))
+
+	_, err = internal.GetSongsOfArtists(lyricsService, []string{"eminem", "taco"})
+	assert.Error(t, err)
+}
+
+type countingTransport struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *countingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	return &http.Response{StatusCode: 200, Body: &countingBody{Reader: strings.NewReader("ok"), transport: c}}, nil
+}
+
+// countingBody marks the request as finished when it's closed, before the budget slot is released
+type countingBody struct {
+	io.Reader
+	transport *countingTransport
+}
+
+func (b *countingBody) Close() error {
+	b.transport.mu.Lock()
+	b.transport.running--
+	b.transport.mu.Unlock()
+	return nil
+}
+
+func TestBudgetTransportLimitsConcurrentRequests(t *testing.T) {
+	base := &countingTransport{}
+	client := &http.Client{Transport: utils.NewBudgetTransport(base, 2)}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get("http://example.com")
+			if err != nil {
+				return
+			}
+			io.ReadAll(res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, base.max)
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getDictionaryService(t *testing.T, history internal.DictionaryHistory, definitions ...internal.Dictionary) *internal.InternalDictionaryService {
+	registry, err := internal.NewDictionaryRegistry(definitions...)
+	assert.NoError(t, err)
+
+	service, err := internal.NewDictionaryService(GetConfig(), registry, history, log.NewEntry(log.New()))
+	assert.NoError(t, err)
+	return service
+}
+
+func TestDiffDictionaries(t *testing.T) {
+	before := dictionary("a", "aaa", "bbb")
+	after := dictionary("a", "bbb", "ccc")
+	after.Entries[0].Weight = 3
+
+	diff := internal.DiffDictionaries(before, after)
+	assert.Equal(t, []internal.Word{"ccc"}, internal.Dictionary{Entries: diff.Added}.Words())
+	assert.Equal(t, []internal.Word{"aaa"}, internal.Dictionary{Entries: diff.Removed}.Words())
+	assert.Equal(t, 1, len(diff.Changed))
+	assert.Equal(t, float64(3), diff.Changed[0].After.Weight)
+}
+
+func TestDictionaryServiceCreatesVersions(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(), dictionary("base", "aaa"))
+
+	version, err := service.SaveDictionary(dictionary("base", "aaa", "bbb"), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version.Version)
+	assert.Equal(t, "alice", version.Author)
+	assert.Equal(t, []internal.Word{"bbb"}, internal.Dictionary{Entries: version.Diff.Added}.Words())
+
+	first, err := service.GetDictionaryVersion("base", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.FileDictionaryAuthor, first.Author)
+	assert.Equal(t, []internal.Word{"aaa"}, first.Dictionary.Words())
+
+	// Saving the same definition again shouldn't create a version
+	version, err = service.SaveDictionary(dictionary("base", "aaa", "bbb"), "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version.Version)
+}
+
+func TestDictionaryServiceVersionsDependentDictionaries(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(),
+		dictionary("base", "aaa"),
+		internal.Dictionary{Name: "team", Extends: []string{"base"}},
+	)
+
+	_, err := service.SaveDictionary(dictionary("base", "aaa", "bbb"), "alice")
+	assert.NoError(t, err)
+
+	team, err := service.GetDictionaryVersion("team", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, team.Version)
+	assert.Equal(t, "base", team.Cause)
+	assert.Equal(t, []internal.Word{"aaa", "bbb"}, team.Dictionary.Words())
+
+	diff, err := service.DiffDictionaryVersions("team", 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Word{"bbb"}, internal.Dictionary{Entries: diff.Added}.Words())
+}
+
+func TestDictionaryServiceRejectsCycle(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(),
+		dictionary("base", "aaa"),
+		internal.Dictionary{Name: "team", Extends: []string{"base"}},
+	)
+
+	_, err := service.SaveDictionary(internal.Dictionary{Name: "base", Extends: []string{"team"}}, "alice")
+	assert.True(t, internal.IsDictionaryValidationError(err))
+
+	_, err = service.GetDictionaryVersion("base", 2)
+	assert.True(t, errors.Is(err, internal.DictionaryVersionNotFoundError))
+}
+
+func TestDictionaryServiceKeepsRegisteredVersionsAfterRestart(t *testing.T) {
+	history := internal.NewFileDictionaryHistory(t.TempDir())
+	service := getDictionaryService(t, history, dictionary("en/profanity", "aaa"))
+
+	_, err := service.SaveDictionary(dictionary("en/profanity", "aaa", "bbb"), "alice")
+	assert.NoError(t, err)
+	_, err = service.SaveDictionary(dictionary("registered", "ccc"), "alice")
+	assert.NoError(t, err)
+
+	// The file hasn't been changed, so the version saved by alice is still the latest one
+	restarted := getDictionaryService(t, history, dictionary("en/profanity", "aaa"))
+	latest, err := restarted.GetDictionaryVersion("en/profanity", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, latest.Version)
+	assert.Equal(t, []string{"en/profanity", "registered"}, restarted.GetDictionariesNames())
+
+	// Editing the file creates new version
+	edited := getDictionaryService(t, history, dictionary("en/profanity", "ddd"))
+	latest, err = edited.GetDictionaryVersion("en/profanity", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, latest.Version)
+	assert.Equal(t, internal.FileDictionaryAuthor, latest.Author)
+}
+
+func TestValidateDictionaryName(t *testing.T) {
+	assert.NoError(t, internal.ValidateDictionaryName("en/profanity"))
+	assert.Error(t, internal.ValidateDictionaryName("en/versions"))
+	assert.Error(t, internal.ValidateDictionaryName("../secret"))
+	assert.Error(t, internal.ValidateDictionaryName("en//profanity"))
+}
+
+func TestSaveDictionaryKeepsDefinitionOfCaller(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory())
+
+	definition := internal.Dictionary{Name: "base", Entries: []internal.DictionaryEntry{{Word: " AAA "}}}
+	_, err := service.SaveDictionary(definition, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.DictionaryEntry{Word: " AAA "}, definition.Entries[0])
+}
+
+// failingHistory fails to record any version
+type failingHistory struct {
+	*internal.MemoryDictionaryHistory
+}
+
+func (h failingHistory) Append(versions ...internal.DictionaryVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	return errors.New("disk is full")
+}
+
+func TestSaveDictionaryRecordsAllVersionsOrNone(t *testing.T) {
+	definitions := []internal.Dictionary{dictionary("base", "aaa"), {Name: "team", Extends: []string{"base"}}}
+	history := internal.NewMemoryDictionaryHistory()
+	getDictionaryService(t, history, definitions...)
+
+	service := getDictionaryService(t, failingHistory{history}, definitions...)
+	_, err := service.SaveDictionary(dictionary("base", "aaa", "bbb"), "alice")
+	assert.Error(t, err)
+
+	for _, name := range []string{"base", "team"} {
+		versions, err := history.Versions(name)
+		assert.NoError(t, err)
+		assert.Len(t, versions, 1)
+	}
+	base, err := service.GetDictionary("base")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Word{"aaa"}, base.Words())
+}
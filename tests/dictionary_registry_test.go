@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func dictionary(name string, words ...string) internal.Dictionary {
+	return internal.NewDictionaryFromWords(name, words)
+}
+
+func TestRegistryCompose(t *testing.T) {
+	kids := internal.Dictionary{Name: "kids", Compose: "en/profanity ∪ en/drugs − allow/biblical"}
+
+	registry, err := internal.NewDictionaryRegistry(
+		dictionary("en/profanity", "aaa", "hell"),
+		dictionary("en/drugs", "weed", "coke"),
+		dictionary("allow/biblical", "hell", "weed"),
+		kids,
+	)
+	assert.NoError(t, err)
+
+	resolved, err := registry.Get("kids")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Word{"aaa", "coke"}, resolved.Words())
+}
+
+func TestRegistryComposeAsciiOperatorsAndParentheses(t *testing.T) {
+	registry, err := internal.NewDictionaryRegistry(
+		dictionary("a", "aaa", "bbb"),
+		dictionary("b", "bbb", "ccc"),
+		dictionary("c", "ccc"),
+		internal.Dictionary{Name: "out", Compose: "a - (b - c)"},
+	)
+	assert.NoError(t, err)
+
+	resolved, err := registry.Get("out")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Word{"aaa"}, resolved.Words())
+}
+
+func TestRegistryExtendsOverridesEntries(t *testing.T) {
+	team := internal.Dictionary{
+		Name:    "team",
+		Extends: []string{"base"},
+		Entries: []internal.DictionaryEntry{{Word: "aaa", Weight: 5, Match: internal.MatchPrefix}},
+	}
+
+	registry, err := internal.NewDictionaryRegistry(dictionary("base", "aaa", "bbb"), team)
+	assert.NoError(t, err)
+
+	resolved, err := registry.Get("team")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.DictionaryEntry{
+		{Word: "aaa", Weight: 5, Match: internal.MatchPrefix},
+		{Word: "bbb", Weight: 1, Match: internal.MatchExact},
+	}, resolved.Entries)
+}
+
+func TestRegistryCycle(t *testing.T) {
+	_, err := internal.NewDictionaryRegistry(
+		internal.Dictionary{Name: "a", Extends: []string{"b"}},
+		internal.Dictionary{Name: "b", Compose: "c ∪ a"},
+		dictionary("c", "ccc"),
+	)
+
+	var cycleErr *internal.DictionaryCycleError
+	assert.True(t, errors.As(err, &cycleErr))
+	assert.Equal(t, []string{"a", "b", "a"}, cycleErr.Cycle)
+}
+
+func TestRegistryMissingReference(t *testing.T) {
+	_, err := internal.NewDictionaryRegistry(internal.Dictionary{Name: "a", Compose: "b ∪ c"}, dictionary("b"))
+	assert.True(t, errors.Is(err, internal.DictionaryNotFoundError))
+}
+
+func TestRegistryInvalidCompose(t *testing.T) {
+	_, err := internal.NewDictionaryRegistry(internal.Dictionary{Name: "a", Compose: "b ∪"}, dictionary("b"))
+	assert.True(t, errors.Is(err, internal.InvalidComposeError))
+}
+
+func TestLoadDictionaryRegistry(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "en"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "en", "profanity.txt"), []byte("aaa\nbbb\n"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kids.txt"), []byte("@extends en/profanity\nccc\n"), os.ModePerm))
+
+	registry, err := internal.LoadDictionaryRegistry(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en/profanity", "kids"}, registry.Names())
+
+	resolved, err := registry.Get("kids")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Word{"aaa", "bbb", "ccc"}, resolved.Words())
+}
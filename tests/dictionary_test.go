@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTextDictionary(t *testing.T) {
+	dictionary, err := internal.ReadDictionary(strings.NewReader(
+		"# swears\nAAA, bbb*\n\n*ccc *ddd* # inline comment\n",
+	), internal.DictionaryText)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []internal.DictionaryEntry{
+		{Word: "aaa", Weight: 1, Match: internal.MatchExact},
+		{Word: "bbb", Weight: 1, Match: internal.MatchPrefix},
+		{Word: "ccc", Weight: 1, Match: internal.MatchSuffix},
+		{Word: "ddd", Weight: 1, Match: internal.MatchContains},
+	}, dictionary.Entries)
+}
+
+func TestReadCSVDictionary(t *testing.T) {
+	dictionary, err := internal.ReadDictionary(strings.NewReader(
+		"word,weight,category,match\n# comment\naaa,2.5,Drugs,prefix\nbbb,,,\n",
+	), internal.DictionaryCSV)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []internal.DictionaryEntry{
+		{Word: "aaa", Weight: 2.5, Category: "Drugs", Match: internal.MatchPrefix},
+		{Word: "bbb", Weight: 1, Match: internal.MatchExact},
+	}, dictionary.Entries)
+}
+
+func TestReadCSVDictionaryInvalidWeight(t *testing.T) {
+	_, err := internal.ReadDictionary(strings.NewReader(
+		"aaa,1\nbbb,heavy\n",
+	), internal.DictionaryCSV)
+
+	var dictErr *internal.DictionaryError
+	assert.True(t, errors.As(err, &dictErr))
+	assert.Equal(t, 2, dictErr.Line)
+	assert.True(t, errors.Is(err, internal.InvalidWeightError))
+}
+
+func TestReadJSONDictionaryUnknownMatchMode(t *testing.T) {
+	_, err := internal.ReadDictionary(strings.NewReader(`{
+  "name": "swears",
+  "entries": [
+    "aaa",
+    {"word": "bbb", "match": "fuzzy"}
+  ]
+}`), internal.DictionaryJSON)
+
+	var dictErr *internal.DictionaryError
+	assert.True(t, errors.As(err, &dictErr))
+	assert.Equal(t, 5, dictErr.Line)
+	assert.True(t, errors.Is(err, internal.UnknownMatchModeError))
+}
+
+func TestReadYAMLDictionaryEmptyWord(t *testing.T) {
+	_, err := internal.ReadDictionary(strings.NewReader(
+		"name: swears\n# comment\nentries:\n  - aaa*\n  - word: \"\"\n    weight: 2\n",
+	), internal.DictionaryYAML)
+
+	var dictErr *internal.DictionaryError
+	assert.True(t, errors.As(err, &dictErr))
+	assert.Equal(t, 5, dictErr.Line)
+	assert.True(t, errors.Is(err, internal.EmptyWordError))
+}
+
+func TestDictionaryRoundTrip(t *testing.T) {
+	dictionary := internal.Dictionary{
+		Name: "swears",
+		Entries: []internal.DictionaryEntry{
+			{Word: "aaa", Weight: 2, Category: "drugs", Match: internal.MatchExact},
+			{Word: "bbb", Weight: 1, Match: internal.MatchContains},
+		},
+	}
+
+	for _, format := range []internal.DictionaryFormat{internal.DictionaryCSV, internal.DictionaryJSON, internal.DictionaryYAML} {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, internal.WriteDictionary(buf, dictionary, format))
+
+		read, err := internal.ReadDictionary(buf, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, dictionary.Entries, read.Entries, format)
+	}
+}
+
+func TestLoadDictionaryFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	_, err := internal.LoadDictionaryFile(path)
+
+	var dictErr *internal.DictionaryError
+	assert.True(t, errors.As(err, &dictErr))
+	assert.Equal(t, path, dictErr.Path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestDictionaryMatchModes(t *testing.T) {
+	dictionary := internal.Dictionary{Entries: []internal.DictionaryEntry{
+		{Word: "kurw", Match: internal.MatchPrefix},
+		{Word: "ing", Match: internal.MatchSuffix},
+	}}
+
+	assert.True(t, dictionary.MatchesAny(internal.WordsOccurrences{"kurwa": 1}))
+	assert.True(t, dictionary.MatchesAny(internal.WordsOccurrences{"singing": 1}))
+	assert.False(t, dictionary.MatchesAny(internal.WordsOccurrences{"ingot": 1}))
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const originalLyrics = `[Verse 1]
+I been counting money since the early days
+Every corner of the city knows my name
+Mama said be careful how you play the game
+
+[Chorus]
+We keep it moving never looking back
+We keep it moving stacking up the stack`
+
+func TestTitleVariant(t *testing.T) {
+	assert.Equal(t, "", internal.TitleVariant("Lose Yourself"))
+	assert.Equal(t, internal.VariantRemix, internal.TitleVariant("Lose Yourself (DJ Shadow Remix)"))
+	assert.Equal(t, internal.VariantLive, internal.TitleVariant("Lose Yourself (Live at Detroit)"))
+	assert.Equal(t, internal.VariantTranslation, internal.TitleVariant("Lose Yourself [Tłumaczenie PL]"))
+	assert.Equal(t, internal.VariantSnippet, internal.TitleVariant("Lose Yourself [Snippet]"))
+	assert.Equal(t, internal.VariantSkit, internal.TitleVariant("Paul (Skit)"))
+	assert.Equal(t, "", internal.TitleVariant("Lose Yourself (feat. Someone)"))
+
+	assert.Equal(t, "lose yourself", internal.BaseTitle("Lose Yourself (DJ Shadow Remix) feat. Someone"))
+}
+
+func TestLyricsSimilarity(t *testing.T) {
+	assert.Equal(t, float64(1), internal.LyricsSimilarity(originalLyrics, originalLyrics+"\n"))
+	assert.True(t, internal.LyricsSimilarity(originalLyrics, "something completely different that has nothing in common") < 0.1)
+	assert.Equal(t, float64(0), internal.LyricsSimilarity("too short", "too short"))
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	songs := []internal.Song{
+		song("Money (Live)", originalLyrics),
+		song("Money", originalLyrics+"\nOne more line at the end of the original"),
+		song("Money [Translation]", "Liczę hajs od najmłodszych lat"),
+		song("Renamed Money", originalLyrics),
+		song("Another Song", "nothing like the other lyrics at all really"),
+		song("Intro (Skit)", "yo yo yo"),
+	}
+
+	groups := internal.GroupDuplicates(songs)
+	assert.Equal(t, 3, len(groups))
+	assert.Equal(t, "Money", groups[0].Song.Info.Title)
+	assert.Equal(t, 3, len(groups[0].Duplicates))
+	assert.Equal(t, internal.VariantLive, groups[0].Duplicates[1].Variant)
+	assert.Equal(t, "", groups[0].Duplicates[0].Variant)
+	assert.Equal(t, "Renamed Money", groups[0].Duplicates[0].Song.Info.Title)
+	assert.Equal(t, internal.VariantTranslation, groups[0].Duplicates[2].Variant)
+	assert.True(t, groups[0].Duplicates[0].Similarity > 0.7)
+
+	var titles []string
+	for _, song := range internal.Deduplicate(songs) {
+		titles = append(titles, song.Info.Title)
+	}
+	assert.Equal(t, []string{"Money", "Another Song"}, titles)
+}
+
+func TestGroupDuplicatesKeepsDifferentSongsWithTheSameTitle(t *testing.T) {
+	songs := []internal.Song{
+		song("Intro", "welcome to the first album, this is where it all begins"),
+		song("Intro", "ten years later and i'm still standing on the same stage"),
+		song("Intro (Live)", "welcome to the first album, this is where it all begins"),
+	}
+
+	groups := internal.GroupDuplicates(songs)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, 1, len(groups[0].Duplicates))
+	assert.Equal(t, internal.VariantLive, groups[0].Duplicates[0].Variant)
+	assert.Equal(t, 0, len(groups[1].Duplicates))
+	assert.Equal(t, 2, len(internal.Deduplicate(songs)))
+}
+
+func TestValidateDuplicatesMode(t *testing.T) {
+	assert.NoError(t, internal.ValidateDuplicatesMode(""))
+	assert.NoError(t, internal.ValidateDuplicatesMode(internal.DuplicatesGroup))
+	assert.True(t, errors.Is(internal.ValidateDuplicatesMode("merge"), internal.UnknownDuplicatesModeError))
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func exportedSongs() []internal.Song {
+	return []internal.Song{
+		{Info: internal.SongInfo{AuthorName: "Eminem", Title: "Stan", PageEndpoint: "/Eminem-stan-lyrics", GeniusID: 1}, Lyrics: "My tea's gone cold, I'm wondering why"},
+		{Info: internal.SongInfo{AuthorName: "Eminem", Title: "Stan (Live)", PageEndpoint: "/Eminem-stan-live-lyrics", GeniusID: 2}, Lyrics: "My tea's gone cold, I'm wondering why"},
+		{Info: internal.SongInfo{AuthorName: "Eminem", Title: "Who/What?", PageEndpoint: "/Eminem-who-what-lyrics", GeniusID: 3}, Lyrics: "Money money money"},
+	}
+}
+
+func getExportService(t *testing.T) (*internal.InternalExportService, internal.LyricsRepository) {
+	lyricsService := &mocks.LyricsService{}
+	lyricsService.On("GetArtist", "eminem").Return(internal.Artist{GeniusID: 45, Name: "Eminem"}, nil)
+	lyricsService.On("GetArtist", "nobody").Return(internal.Artist{}, nil)
+	lyricsService.On("GetSongsByArtist", "eminem").Return(exportedSongs(), nil)
+
+	repository := getSQLiteRepository(t)
+	return internal.NewExportService(GetConfig(), lyricsService, repository, log.NewEntry(log.New())), repository
+}
+
+func readManifest(t *testing.T, path string) internal.ExportManifest {
+	var manifest internal.ExportManifest
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &manifest))
+	return manifest
+}
+
+func TestExportJSONL(t *testing.T) {
+	service, repository := getExportService(t)
+	assert.NoError(t, repository.SaveLyrics(1, "My tea's gone cold, I'm wondering why"))
+	dir := t.TempDir()
+
+	manifest, err := service.ExportArtist("eminem", internal.ExportOptions{Format: internal.ExportJSONL, WordCounts: true, Deduplicate: true}, internal.NewDirExportTarget(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, manifest.SongsCount)
+	assert.Equal(t, []string{"songs.jsonl"}, manifest.Files)
+
+	written := readManifest(t, filepath.Join(dir, internal.ExportManifestFile))
+	assert.Equal(t, "Eminem", written.Artist)
+	assert.Equal(t, 45, written.ArtistGeniusID)
+	if assert.Len(t, written.Songs, 2) {
+		// only stored lyrics have fetch date
+		assert.NotNil(t, written.Songs[0].FetchedAt)
+		assert.Nil(t, written.Songs[1].FetchedAt)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "songs.jsonl"))
+	assert.NoError(t, err)
+	defer f.Close()
+	var songs []internal.ExportedSong
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var song internal.ExportedSong
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &song))
+		songs = append(songs, song)
+	}
+	if assert.Len(t, songs, 2) {
+		assert.Equal(t, "Stan", songs[0].Title)
+		assert.Equal(t, "/Eminem-stan-lyrics", songs[0].PagePath)
+		assert.Equal(t, "My tea's gone cold I'm wondering why", songs[0].NormalisedLyrics)
+		assert.Equal(t, 3, songs[1].Words["money"])
+	}
+}
+
+func TestExportCSVToZip(t *testing.T) {
+	service, _ := getExportService(t)
+	var archive bytes.Buffer
+	target := internal.NewZipExportTarget(&archive)
+
+	_, err := service.ExportArtist("eminem", internal.ExportOptions{Format: internal.ExportCSV}, target)
+	assert.NoError(t, err)
+	assert.NoError(t, target.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	assert.NoError(t, err)
+	if assert.Len(t, reader.File, 2) {
+		assert.Equal(t, "songs.csv", reader.File[0].Name)
+		assert.Equal(t, internal.ExportManifestFile, reader.File[1].Name)
+
+		f, err := reader.File[0].Open()
+		assert.NoError(t, err)
+		rows, err := csv.NewReader(f).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, rows, 4)
+		assert.Equal(t, internal.ExportCSVHeader, rows[0])
+		assert.Equal(t, []string{"3", "Eminem", "45", "Who/What?"}, rows[3][:4])
+		// words are exported only on request
+		assert.Equal(t, "", rows[3][9])
+	}
+}
+
+func TestExportTextCanBeLoadedAsLyricsDir(t *testing.T) {
+	service, _ := getExportService(t)
+	dir := t.TempDir()
+
+	manifest, err := service.ExportArtist("eminem", internal.ExportOptions{Format: internal.ExportText, WordCounts: true}, internal.NewDirExportTarget(dir))
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Files, 9)
+	assert.Equal(t, []string{"lyrics/Eminem/Who_What_.txt", "normalised/Eminem/Who_What_.txt", "words/Eminem/Who_What_.csv"}, manifest.Songs[2].Files)
+
+	songs, err := internal.LoadLyricsDir(filepath.Join(dir, "lyrics"))
+	assert.NoError(t, err)
+	if assert.Len(t, songs, 3) {
+		// files are sorted by name, "Stan (Live).txt" goes first
+		assert.Equal(t, "Stan", songs[1].Info.Title)
+		assert.Equal(t, "Eminem", songs[1].Info.AuthorName)
+		assert.Equal(t, internal.Lyrics("My tea's gone cold, I'm wondering why"), songs[1].Lyrics)
+		assert.Equal(t, "Who/What?", songs[2].Info.Title)
+	}
+}
+
+func TestExportTextTruncatesLongNames(t *testing.T) {
+	// two songs with the same name need the genius id on top of the cut name
+	title := strings.Repeat("Łódź ", 100)
+	lyricsService := &mocks.LyricsService{}
+	lyricsService.On("GetArtist", "eminem").Return(internal.Artist{GeniusID: 45, Name: "Eminem"}, nil)
+	lyricsService.On("GetSongsByArtist", "eminem").Return([]internal.Song{
+		{Info: internal.SongInfo{AuthorName: "Eminem", Title: title, PageEndpoint: "/long", GeniusID: 1}, Lyrics: "Money"},
+		{Info: internal.SongInfo{AuthorName: "Eminem", Title: title + "(Live)", PageEndpoint: "/long-live", GeniusID: 1234567890}, Lyrics: "Money"},
+	}, nil)
+	service := internal.NewExportService(GetConfig(), lyricsService, getSQLiteRepository(t), log.NewEntry(log.New()))
+	dir := t.TempDir()
+
+	manifest, err := service.ExportArtist("eminem", internal.ExportOptions{Format: internal.ExportText, WordCounts: true}, internal.NewDirExportTarget(dir))
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Files, 6)
+	for _, name := range manifest.Files {
+		base := filepath.Base(name)
+		assert.True(t, len(base) <= 255, base)
+		assert.True(t, utf8.ValidString(base), base)
+		assert.True(t, strings.HasSuffix(base, ".txt") || strings.HasSuffix(base, ".csv"), base)
+	}
+	assert.True(t, strings.HasSuffix(manifest.Songs[1].Files[0], " (1234567890).txt"), manifest.Songs[1].Files[0])
+
+	songs, err := internal.LoadLyricsDir(filepath.Join(dir, "lyrics"))
+	assert.NoError(t, err)
+	assert.Len(t, songs, 2)
+}
+
+func TestExportUnknownArtist(t *testing.T) {
+	service, _ := getExportService(t)
+
+	_, err := service.ExportArtist("nobody", internal.ExportOptions{Format: internal.ExportJSONL}, internal.NewDirExportTarget(t.TempDir()))
+	assert.True(t, errors.Is(err, internal.ArtistNotFoundError))
+}
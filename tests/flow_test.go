@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLineSyllables(t *testing.T) {
+	assert.Equal(t, 6, internal.LineSyllables("I got money, baby", "en"))
+	assert.Equal(t, 6, internal.LineSyllables("Siedzę w domu, nie wiem", "pl"))
+	assert.Equal(t, 0, internal.LineSyllables("...", "en"))
+}
+
+func TestFlowStats(t *testing.T) {
+	flow := internal.AnalyseFlow("[Verse 1]\nI got money\nI got money, baby\n\n[Chorus]\nYeah", "en", "Eminem")
+
+	assert.Equal(t, []internal.StanzaFlow{
+		{Section: "Verse 1", Performers: []string{"Eminem"}, LineSyllables: []int{4, 6}, Syllables: 10},
+		{Section: "Chorus", Performers: []string{"Eminem"}, LineSyllables: []int{1}, Syllables: 1},
+	}, flow.Stanzas)
+
+	assert.Equal(t, 3, flow.Total.Lines)
+	assert.Equal(t, 11, flow.Total.Syllables)
+	assert.Equal(t, 3.67, flow.Total.SyllablesPerLine)
+	assert.Equal(t, 4.22, flow.Total.Variance)
+	assert.Equal(t, 2.05, flow.Total.StdDev)
+	assert.Equal(t, 1, flow.Total.MinLineSyllables)
+	assert.Equal(t, 6, flow.Total.MaxLineSyllables)
+	assert.Equal(t, 1, flow.Total.Verses)
+	assert.Equal(t, float64(10), flow.Total.SyllablesPerVerse)
+}
+
+func TestPerformersFlow(t *testing.T) {
+	analysis := internal.AnalyseArtist([]internal.Song{
+		{Info: internal.SongInfo{Title: "feat", AuthorName: "Eminem"}, Lyrics: "[Verse 1: Eminem]\nI got money\nYou got honey\n\n[Verse 2: Rihanna]\nYeah\n\n[Chorus: Eminem & Rihanna]\nYeah yeah"},
+		{Info: internal.SongInfo{Title: "solo", AuthorName: "Eminem"}, Lyrics: "I got money"},
+	}, nil)
+
+	assert.Equal(t, 2, len(analysis.Performers))
+	assert.Equal(t, "Eminem", analysis.Performers[0].Performer)
+	assert.Equal(t, 4, analysis.Performers[0].Flow.Lines)
+	assert.Equal(t, 2, analysis.Performers[0].Flow.Verses)
+	assert.Equal(t, "Rihanna", analysis.Performers[1].Performer)
+	assert.Equal(t, 2, analysis.Performers[1].Flow.Lines)
+	assert.Equal(t, float64(1), analysis.Performers[1].Flow.SyllablesPerVerse)
+	assert.Equal(t, 5, analysis.Flow.Lines)
+}
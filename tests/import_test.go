@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func getImportService(t *testing.T) (*internal.InternalImportService, internal.LyricsRepository) {
+	repository := getSQLiteRepository(t)
+	return internal.NewImportService(GetConfig(), repository, log.NewEntry(log.New())), repository
+}
+
+func TestImportExportedDumps(t *testing.T) {
+	exportService, _ := getExportService(t)
+
+	for _, format := range []internal.ExportFormat{internal.ExportJSONL, internal.ExportCSV, internal.ExportText} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := exportService.ExportArtist("eminem", internal.ExportOptions{Format: format, WordCounts: true}, internal.NewDirExportTarget(dir))
+			assert.NoError(t, err)
+
+			importService, repository := getImportService(t)
+			result, err := importService.Import(dir, internal.ImportOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, 3, result.Imported)
+			assert.Empty(t, result.Invalid)
+			assert.Empty(t, result.Conflicts)
+
+			artist, err := repository.GetArtist("eminem")
+			assert.NoError(t, err)
+			assert.Equal(t, 45, artist.Artist.ID)
+			stored, err := repository.GetArtistSongInfos(45)
+			assert.NoError(t, err)
+			// the dump isn't the whole listing of the artist, so it's not fresh
+			assert.True(t, stored.FetchedAt.IsZero())
+			if assert.Len(t, stored.Songs, 3) {
+				assert.Equal(t, "Who/What?", stored.Songs[2].FullTitle)
+				assert.Equal(t, "/Eminem-who-what-lyrics", stored.Songs[2].PagePath)
+			}
+			lyrics, err := repository.GetLyrics(3)
+			assert.NoError(t, err)
+			assert.Equal(t, internal.Lyrics("Money money money"), lyrics.Lyrics)
+
+			// importing the same dump again changes nothing
+			result, err = importService.Import(dir, internal.ImportOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, 0, result.Imported)
+			assert.Equal(t, 3, result.Unchanged)
+		})
+	}
+}
+
+func TestImportReportsDuplicatesConflictsAndInvalidSongs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "songs.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"genius_id": 1, "artist": "Eminem", "title": "Stan", "lyrics": "first"}
+{"genius_id": 1, "artist": "Eminem", "title": "Stan", "lyrics": "first"}
+{"genius_id": 1, "artist": "Eminem", "title": "Stan", "lyrics": "second"}
+{"genius_id": 2, "artist": "Eminem", "title": "Empty", "lyrics": ""}
+not json
+`), 0644))
+
+	importService, repository := getImportService(t)
+	result, err := importService.Import(path, internal.ImportOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Duplicates)
+	assert.Equal(t, []internal.ImportConflict{{GeniusID: 1, Title: "Stan", Source: "songs.jsonl:3", With: "songs.jsonl:1", Resolution: "skipped"}}, result.Conflicts)
+	if assert.Len(t, result.Invalid, 2) {
+		assert.Equal(t, "songs.jsonl:5", result.Invalid[0].Source)
+		assert.Equal(t, "songs.jsonl:4", result.Invalid[1].Source)
+	}
+
+	// dry run doesn't store anything
+	_, err = repository.GetLyrics(1)
+	assert.True(t, errors.Is(err, internal.NotStoredError))
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "songs.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("genius_id,title,fetched_at,lyrics\n1,Stan,2021-01-01T00:00:00Z,old\n2,Lose Yourself,2030-01-01T00:00:00Z,new\n"), 0644))
+
+	for _, c := range []struct {
+		policy   internal.ConflictPolicy
+		expected []internal.Lyrics
+	}{
+		{internal.ConflictSkip, []internal.Lyrics{"stored", "stored"}},
+		{internal.ConflictNewer, []internal.Lyrics{"stored", "new"}},
+		{internal.ConflictOverwrite, []internal.Lyrics{"old", "new"}},
+	} {
+		importService, repository := getImportService(t)
+		assert.NoError(t, repository.SaveStoredLyrics(1, internal.StoredLyrics{Lyrics: "stored", FetchedAt: time.Now()}))
+		assert.NoError(t, repository.SaveStoredLyrics(2, internal.StoredLyrics{Lyrics: "stored", FetchedAt: time.Now()}))
+
+		result, err := importService.Import(path, internal.ImportOptions{OnConflict: c.policy})
+		assert.NoError(t, err)
+		assert.Len(t, result.Conflicts, 2)
+		for i, expected := range c.expected {
+			lyrics, err := repository.GetLyrics(i + 1)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, lyrics.Lyrics, c.policy)
+		}
+	}
+}
+
+func TestImportConflictPoliciesInDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "songs.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("genius_id,title,fetched_at,lyrics\n1,Stan,2020-01-01T00:00:00Z,old\n1,Stan,2021-01-01T00:00:00Z,new\n1,Stan,,last\n"), 0644))
+
+	for _, c := range []struct {
+		policy      internal.ConflictPolicy
+		expected    internal.Lyrics
+		resolutions []string
+	}{
+		{internal.ConflictSkip, "old", []string{"skipped", "skipped"}},
+		{internal.ConflictNewer, "new", []string{"overwritten", "skipped"}},
+		{internal.ConflictOverwrite, "last", []string{"overwritten", "overwritten"}},
+	} {
+		importService, repository := getImportService(t)
+		result, err := importService.Import(path, internal.ImportOptions{OnConflict: c.policy})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Imported)
+		var resolutions []string
+		for _, conflict := range result.Conflicts {
+			resolutions = append(resolutions, conflict.Resolution)
+		}
+		assert.Equal(t, c.resolutions, resolutions, c.policy)
+
+		lyrics, err := repository.GetLyrics(1)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, lyrics.Lyrics, c.policy)
+	}
+}
+
+func TestImportKeepsStoredSongs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "songs.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("genius_id,artist,artist_genius_id,title,lyrics\n2,Eminem,45,Stan,imported\n4,Eminem,45,Removed,imported\n"), 0644))
+
+	importService, repository := getImportService(t)
+	stan := storedSongInfo(2, "Stan")
+	stan.LyricsState = "unreleased"
+	removed := storedSongInfo(4, "Removed")
+	assert.NoError(t, repository.SaveArtistSongInfos(storedArtist.ID, []internal.GeniusSongInfo{stan, removed}))
+	assert.NoError(t, repository.MarkSongsRemoved([]int{removed.ID}))
+
+	result, err := importService.Import(path, internal.ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+
+	// infos of genius are kept and the removed song isn't listed again
+	stored, err := repository.GetArtistSongInfos(storedArtist.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.GeniusSongInfo{stan}, stored.Songs)
+	lyrics, err := repository.GetLyrics(removed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.Lyrics("imported"), lyrics.Lyrics)
+}
+
+func TestImportRejectsTextFilesWithoutIDs(t *testing.T) {
+	dir := t.TempDir()
+	writeLyricsFile(t, dir, "Eminem/Stan.txt", "My tea's gone cold")
+
+	importService, _ := getImportService(t)
+	_, err := importService.Import(dir, internal.ImportOptions{})
+	assert.True(t, errors.Is(err, internal.TextDumpWithoutIDsError))
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := internal.ParseConflictPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.ConflictSkip, policy)
+
+	_, err = internal.ParseConflictPolicy("merge")
+	assert.True(t, errors.Is(err, internal.UnknownConflictPolicyError))
+}
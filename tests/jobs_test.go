@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+var jobSongInfos = []internal.SongInfo{
+	{GeniusID: 1, Title: "Stan", AuthorName: "Eminem", PageEndpoint: "/Eminem-stan-lyrics"},
+	{GeniusID: 2, Title: "Lose Yourself", AuthorName: "Eminem", PageEndpoint: "/Eminem-lose-yourself-lyrics"},
+	{GeniusID: 3, Title: "Without Me", AuthorName: "Eminem", PageEndpoint: "/Eminem-without-me-lyrics"},
+}
+
+func getJobService(t *testing.T, repository internal.JobRepository) (*mocks.LyricsService, *internal.InternalJobService) {
+	lyricsService := mocks.LyricsService{}
+	stopWords, err := internal.LoadStopWordsRegistry("")
+	assert.NoError(t, err)
+	dictionaryService := getDictionaryService(t, internal.NewMemoryDictionaryHistory())
+	service := internal.NewJobService(GetConfig(), &lyricsService, dictionaryService, stopWords, repository, log.NewEntry(log.New()))
+	return &lyricsService, service
+}
+
+func jobSong(info internal.SongInfo, lyrics string) internal.Song {
+	output := song(info.Title, lyrics)
+	output.Info = info
+	return output
+}
+
+// waitForJob polls the job until it has the status, the last state is returned anyway
+func waitForJob(t *testing.T, service internal.JobService, id string, status internal.JobStatus) internal.Job {
+	var job internal.Job
+	for i := 0; i < 500; i++ {
+		var err error
+		job, err = service.GetJob(id)
+		assert.NoError(t, err)
+		if job.Status == status {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, status, job.Status)
+	return job
+}
+
+func TestJobRunsAndReportsProgress(t *testing.T) {
+	lyricsService, service := getJobService(t, internal.NewMemoryJobRepository())
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos, nil)
+	lyricsService.On("GetSongFromInfo", jobSongInfos[0]).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil)
+	lyricsService.On("GetSongFromInfo", jobSongInfos[1]).Return(internal.Song{}, errors.New("timeout"))
+	lyricsService.On("GetSongFromInfo", jobSongInfos[2]).Return(jobSong(jobSongInfos[2], "guess who's back, money"), nil)
+	assert.NoError(t, service.Start())
+	defer service.Close()
+
+	job, err := service.CreateJob(internal.JobSpec{Artist: "eminem", Query: "NOT money"})
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobWords, job.Spec.Kind)
+	assert.Equal(t, internal.JobQueued, job.Status)
+
+	job = waitForJob(t, service, job.ID, internal.JobDone)
+	assert.Equal(t, 3, job.Progress.SongsListed)
+	assert.Equal(t, 3, job.Progress.PagesFetched)
+	assert.Equal(t, 2, job.Progress.SongsScraped)
+	assert.Equal(t, 1, job.Progress.Failures)
+	assert.Equal(t, []string{"Lose Yourself: timeout"}, job.Progress.Errors)
+	assert.NotNil(t, job.FinishedAt)
+
+	result, err := service.GetJobResult(job.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Songs, 1) {
+		assert.Equal(t, "Stan", result.Songs[0].Title)
+		assert.Equal(t, 1, result.Songs[0].WordsCount["cold"])
+	}
+}
+
+func TestCreateJobValidatesSpec(t *testing.T) {
+	_, service := getJobService(t, internal.NewMemoryJobRepository())
+
+	_, err := service.CreateJob(internal.JobSpec{Kind: "lyrics", Artist: "eminem"})
+	assert.True(t, errors.Is(err, internal.UnknownJobKindError))
+	_, err = service.CreateJob(internal.JobSpec{Kind: internal.JobSongs})
+	assert.True(t, errors.Is(err, internal.InvalidJobSpecError))
+	_, err = service.CreateJob(internal.JobSpec{Artist: "eminem", Duplicates: internal.DuplicatesGroup})
+	assert.True(t, errors.Is(err, internal.InvalidJobSpecError))
+	_, err = service.CreateJob(internal.JobSpec{Artist: "eminem", Query: "(money"})
+	assert.True(t, errors.Is(err, internal.InvalidQueryError))
+
+	_, err = service.GetJob("unknown")
+	assert.True(t, errors.Is(err, internal.JobNotFoundError))
+}
+
+func TestCancelJob(t *testing.T) {
+	repository := internal.NewMemoryJobRepository()
+	lyricsService, service := getJobService(t, repository)
+	started, release := make(chan struct{}, len(jobSongInfos)), make(chan struct{})
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos, nil)
+	lyricsService.On("GetSongFromInfo", mock.Anything).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil).Run(func(args mock.Arguments) {
+		started <- struct{}{}
+		<-release
+	})
+	assert.NoError(t, service.Start())
+
+	running, err := service.CreateJob(internal.JobSpec{Kind: internal.JobSongs, Artist: "eminem"})
+	assert.NoError(t, err)
+	<-started
+	// the only worker is busy, so the second job waits in the queue
+	queued, err := service.CreateJob(internal.JobSpec{Kind: internal.JobSongs, Artist: "eminem"})
+	assert.NoError(t, err)
+
+	job, err := service.CancelJob(queued.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobCancelled, job.Status)
+
+	job, err = service.CancelJob(running.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobCancelled, job.Status)
+	// it's saved before the worker stops
+	stored, err := repository.GetJob(running.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobCancelled, stored.Status)
+	_, err = service.CancelJob(running.ID)
+	assert.True(t, errors.Is(err, internal.JobFinishedError))
+	close(release)
+
+	// Close waits for the worker, so the job is saved
+	service.Close()
+	job, err = service.GetJob(running.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobCancelled, job.Status)
+	assert.Equal(t, 1, job.Progress.SongsScraped, "only the song which was being downloaded is finished")
+	_, err = service.GetJobResult(running.ID)
+	assert.True(t, errors.Is(err, internal.JobNotDoneError))
+	_, err = service.CancelJob(running.ID)
+	assert.True(t, errors.Is(err, internal.JobFinishedError))
+	lyricsService.AssertNumberOfCalls(t, "GetSongsInfosByArtist", 1)
+}
+
+func TestJobsSurviveRestart(t *testing.T) {
+	repository := getSQLiteRepository(t)
+	lyricsService, service := getJobService(t, repository)
+	release := make(chan struct{})
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos[:1], nil)
+	lyricsService.On("GetSongFromInfo", jobSongInfos[0]).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil).Run(func(args mock.Arguments) {
+		<-release
+	})
+	assert.NoError(t, service.Start())
+
+	job, err := service.CreateJob(internal.JobSpec{Kind: internal.JobAnalysis, Artist: "eminem"})
+	assert.NoError(t, err)
+	waitForJob(t, service, job.ID, internal.JobRunning)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	// interrupted job is queued again
+	service.Close()
+	stored, err := repository.GetJob(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.JobQueued, stored.Status)
+	assert.Equal(t, internal.ScrapeProgress{}, stored.Progress)
+
+	lyricsService, service = getJobService(t, repository)
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos[:1], nil)
+	lyricsService.On("GetSongFromInfo", jobSongInfos[0]).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil)
+	assert.NoError(t, service.Start())
+	defer service.Close()
+	waitForJob(t, service, job.ID, internal.JobDone)
+
+	// the result is read from the database
+	_, restarted := getJobService(t, repository)
+	result, err := restarted.GetJobResult(job.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Songs, 1) && assert.NotNil(t, result.Analysis) {
+		assert.Equal(t, "/Eminem-stan-lyrics", result.Songs[0].PagePath)
+		assert.NotEmpty(t, result.Songs[0].Mood)
+		assert.Equal(t, 1, result.Analysis.Moods[result.Songs[0].Mood])
+	}
+}
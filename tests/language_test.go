@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const (
+	englishLyrics = "I was walking down the street with my friends, we were talking about the money and the fame\n" +
+		"Nobody told me that the life would be so hard, but I keep on fighting every single day"
+	polishLyrics = "Szedłem ulicą razem z ziomkami, gadaliśmy o pieniądzach i o sławie\n" +
+		"Nikt mi nie mówił, że życie będzie takie trudne, ale walczę dalej każdego dnia"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	detector := internal.DefaultLanguageDetector()
+	assert.Equal(t, []string{"en", "pl"}, detector.Languages())
+
+	languages := detector.Detect(englishLyrics)
+	assert.Equal(t, []string{"en"}, internal.LanguagesOf(languages))
+	assert.True(t, languages[0].Confidence > 0.9)
+
+	languages = detector.Detect("[Refren: Ziomek]\n" + polishLyrics)
+	assert.Equal(t, []string{"pl"}, internal.LanguagesOf(languages))
+
+	assert.Equal(t, 0, len(detector.Detect("[Intro] 123")))
+}
+
+func TestDetectMixedLanguages(t *testing.T) {
+	languages := internal.DefaultLanguageDetector().Detect(internal.Lyrics(polishLyrics + "\n" + polishLyrics + "\n" + englishLyrics))
+	assert.Equal(t, []string{"pl", "en"}, internal.LanguagesOf(languages))
+	assert.True(t, languages[0].Confidence > languages[1].Confidence)
+}
+
+func TestStem(t *testing.T) {
+	assert.Equal(t, internal.Word("talk"), internal.Stem("talking", "en"))
+	assert.Equal(t, internal.Stem("moneys", "en"), internal.Stem("money", "en"))
+	assert.Equal(t, internal.Stem("kasy", "pl"), internal.Stem("kasa", "pl"))
+	assert.Equal(t, internal.Word("ale"), internal.Stem("ale", "pl"))
+	assert.Equal(t, internal.Word("kasy"), internal.Stem("kasy", "de"))
+}
+
+func TestStemMatchUsesSongLanguage(t *testing.T) {
+	entry := internal.DictionaryEntry{Word: "kasa", Match: internal.MatchStem}
+	assert.True(t, entry.MatchesIn("kasy", []string{"pl"}))
+	assert.False(t, entry.MatchesIn("kasy", []string{"en"}))
+	assert.True(t, entry.Matches("kasę"))
+
+	dictionary, err := internal.ReadDictionary(strings.NewReader("~kasa\n"), internal.DictionaryText)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.MatchStem, dictionary.Entries[0].Match)
+}
+
+func TestSongDictionaryPicksLanguage(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(),
+		dictionary("en/profanity", "money"),
+		dictionary("pl/profanity", "hajs"),
+	)
+
+	songDictionary, err := internal.GetSongDictionary(service, "profanity", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(songDictionary.Versions))
+
+	polish := internal.Song{Languages: []internal.LanguageScore{{Language: "pl", Confidence: 1}}}
+	assert.True(t, songDictionary.MatchesSong(polish, internal.WordsOccurrences{"hajs": 1}))
+	assert.False(t, songDictionary.MatchesSong(polish, internal.WordsOccurrences{"money": 1}))
+
+	unknown := internal.Song{}
+	assert.True(t, songDictionary.MatchesSong(unknown, internal.WordsOccurrences{"money": 1}))
+
+	_, err = internal.GetSongDictionary(service, "profanity", 2)
+	assert.Error(t, err)
+}
+
+func TestFilterSongsByLanguage(t *testing.T) {
+	songs := []internal.Song{
+		{Info: internal.SongInfo{Title: "en"}, Languages: []internal.LanguageScore{{Language: "en", Confidence: 1}}},
+		{Info: internal.SongInfo{Title: "mixed"}, Languages: []internal.LanguageScore{{Language: "en", Confidence: 0.6}, {Language: "pl", Confidence: 0.4}}},
+	}
+
+	assert.Equal(t, 1, len(internal.FilterSongsByLanguage(songs, "pl")))
+	assert.Equal(t, 2, len(internal.FilterSongsByLanguage(songs, "")))
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/config"
+	"github.com/marosiak/WordFinder/internal"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeLyricsFile(t *testing.T, dir string, path string, content string) {
+	path = filepath.Join(dir, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func getLocalLyricsService(t *testing.T) *internal.LocalLyricsService {
+	dir := t.TempDir()
+	writeLyricsFile(t, dir, "Eminem/The Marshall Mathers LP/Stan.txt", "My tea's gone cold\nI'm wondering why")
+	writeLyricsFile(t, dir, "Eminem/lose_yourself.lrc", "[ar:Eminem]\n[ti:Lose Yourself]\n[00:01.00]Look, if you had\n[00:03.50]One shot")
+	writeLyricsFile(t, dir, "misc/song.md", "---\nartist: Dido\ntitle: Thank You\n---\nMy tea's gone cold")
+	writeLyricsFile(t, dir, "Eminem/cover.jpg", "not lyrics")
+
+	songs, err := internal.LoadLyricsDir(dir)
+	assert.NoError(t, err)
+	return internal.NewLocalLyricsService(&config.Config{LyricsDir: dir}, songs, log.NewEntry(log.New()))
+}
+
+func TestParseLyricsFile(t *testing.T) {
+	artist, title, lyrics := internal.ParseLyricsFile("song.lrc", []byte("[ar: Eminem]\r\n[ti:Stan]\r\n[00:12.34]My tea's <00:13.00>gone cold\r\n"))
+	assert.Equal(t, "Eminem", artist)
+	assert.Equal(t, "Stan", title)
+	assert.Equal(t, internal.Lyrics("My tea's gone cold"), lyrics)
+
+	artist, title, lyrics = internal.ParseLyricsFile("song.txt", []byte("[Chorus]\nMy tea's gone cold"))
+	assert.Equal(t, "", artist)
+	assert.Equal(t, "", title)
+	assert.Equal(t, internal.Lyrics("[Chorus]\nMy tea's gone cold"), lyrics)
+}
+
+func TestLocalLyricsService(t *testing.T) {
+	service := getLocalLyricsService(t)
+
+	songs, err := service.GetSongsByArtist("eminem")
+	assert.NoError(t, err)
+	if assert.Len(t, songs, 2) {
+		assert.Equal(t, "Stan", songs[0].Info.Title)
+		assert.Equal(t, internal.Lyrics("My tea's gone cold\nI'm wondering why"), songs[0].Lyrics)
+		assert.Equal(t, "Lose Yourself", songs[1].Info.Title)
+		assert.Equal(t, internal.Lyrics("Look, if you had\nOne shot"), songs[1].Lyrics)
+	}
+
+	song, err := service.GetSongByName("thank")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dido", song.Info.AuthorName)
+
+	found, err := service.GetSongsFromSongInfos([]internal.SongInfo{song.Info, {GeniusID: 100}})
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Song{song}, found)
+
+	_, err = service.GetSongsByArtist("nobody")
+	assert.True(t, errors.Is(err, internal.ArtistNotFoundError))
+	_, err = service.GetSongByName("nothing")
+	assert.True(t, errors.Is(err, internal.SongNotFoundError))
+}
+
+func TestLoadLyricsDirKeepsIDsOfFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeLyricsFile(t, dir, "Eminem/Stan.txt", "My tea's gone cold")
+	songs, err := internal.LoadLyricsDir(dir)
+	assert.NoError(t, err)
+
+	// a file which is sorted before doesn't change ID of the other one
+	writeLyricsFile(t, dir, "Dido/Thank You.txt", "My tea's gone cold")
+	updated, err := internal.LoadLyricsDir(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, songs, 1) && assert.Len(t, updated, 2) {
+		assert.NotZero(t, songs[0].Info.GeniusID)
+		assert.Equal(t, "/Eminem/Stan.txt", updated[1].Info.PageEndpoint)
+		assert.Equal(t, songs[0].Info.GeniusID, updated[1].Info.GeniusID)
+		assert.NotEqual(t, updated[0].Info.GeniusID, updated[1].Info.GeniusID)
+	}
+}
+
+func TestConfigWithoutGenius(t *testing.T) {
+	for _, name := range []string{"GENIUS_RAPID_API_HOST", "GENIUS_HOST", "GENIUS_RAPID_API_KEY", "GENIUS_API_HOST", "OFFLINE", "LYRICS_DIR"} {
+		// Setenv restores the variable after the test
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	_, err := config.NewConfig()
+	assert.True(t, errors.Is(err, config.MissingGeniusConfigError))
+
+	t.Setenv("OFFLINE", "true")
+	cfg, err := config.NewConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.IsOffline())
+}
+
+func TestOfflineLyricsServiceUsesStoredLyrics(t *testing.T) {
+	repository := getSQLiteRepository(t)
+	song := storedSongInfo(1, "Stan")
+	assert.NoError(t, repository.SaveArtist("eminem", storedArtist))
+	assert.NoError(t, repository.SaveArtistSongInfos(storedArtist.ID, []internal.GeniusSongInfo{song}))
+	assert.NoError(t, repository.SaveLyrics(song.ID, "stored lyrics"))
+
+	cfg := &config.Config{Offline: true}
+	logger := log.NewEntry(log.New())
+	provider := internal.NewStoredGeniusProvider(cfg, &internal.OfflineGeniusProvider{}, repository, logger)
+	service := internal.NewLyricsService(cfg, provider, logger)
+
+	songs, err := service.GetSongsByArtist("eminem")
+	assert.NoError(t, err)
+	if assert.Len(t, songs, 1) {
+		assert.Equal(t, internal.Lyrics("stored lyrics"), songs[0].Lyrics)
+	}
+
+	_, err = service.GetSongsByArtist("dido")
+	assert.True(t, errors.Is(err, internal.OfflineError))
+}
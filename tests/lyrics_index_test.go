@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getInvertedIndex() *internal.InvertedIndex {
+	index := internal.NewInvertedIndex()
+	index.Add(internal.IndexedSong{GeniusID: 1, Title: "Lose Yourself", Artist: "Eminem"},
+		"[Verse 1]\nHis palms are sweaty, knees weak, arms are heavy\nThere's vomit on his sweater already, mom's spaghetti")
+	index.Add(internal.IndexedSong{GeniusID: 2, Title: "Spaghetti", Artist: "Somebody"},
+		"Spaghetti, spaghetti\nMom's\nspaghetti again")
+	return index
+}
+
+func TestSearchLyricsPhrase(t *testing.T) {
+	index := getInvertedIndex()
+
+	result, err := index.SearchLyrics("MOM'S   Spaghetti!", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	if assert.Len(t, result.Hits, 2) {
+		assert.Equal(t, 1, result.Hits[0].Song.GeniusID)
+		assert.Equal(t, []string{"There's vomit on his sweater already, mom's spaghetti"}, result.Hits[0].Snippets)
+		// phrase over two lines shows both of them
+		assert.Equal(t, []string{"Mom's / spaghetti again"}, result.Hits[1].Snippets)
+	}
+
+	// songs with more matches go first, section headers aren't lyrics
+	result, err = index.SearchLyrics("spaghetti", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 2, result.Hits[0].Song.GeniusID)
+	assert.Equal(t, 3, result.Hits[0].Matches)
+
+	result, err = index.SearchLyrics("verse", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+
+	_, err = index.SearchLyrics(" ?! ", 0)
+	assert.True(t, errors.Is(err, internal.EmptyQueryError))
+}
+
+func TestInvertedIndexReplacesAndRemovesSongs(t *testing.T) {
+	index := getInvertedIndex()
+	index.Add(internal.IndexedSong{GeniusID: 2, Title: "Spaghetti"}, "nothing here")
+
+	result, _ := index.SearchLyrics("spaghetti", 0)
+	assert.Equal(t, 1, result.Total)
+
+	index.Remove([]int{1})
+	result, _ = index.SearchLyrics("spaghetti", 0)
+	assert.Equal(t, 0, result.Total)
+	assert.Equal(t, 1, index.Len())
+}
+
+func TestIndexedLyricsRepositoryFollowsSync(t *testing.T) {
+	sqlRepository := getSQLiteRepository(t)
+	assert.NoError(t, sqlRepository.SaveLyrics(1, "stored before start"))
+
+	repository := internal.NewIndexedLyricsRepository(sqlRepository, log.NewEntry(log.New()))
+	result, err := repository.SearchLyrics("stored", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+
+	geniusProvider := mocks.GeniusProvider{}
+	syncService := internal.NewSyncService(GetConfig(), &geniusProvider, repository, log.NewEntry(log.New()))
+	song := storedSongInfo(2, "Lose Yourself")
+	geniusProvider.On("GetArtist", "eminem").Return(storedArtist, nil)
+	geniusProvider.On("GetSongInfosByArtistID", storedArtist.ID).Return([]internal.GeniusSongInfo{song}, nil).Once()
+	geniusProvider.On("GetLyrics", song).Return(internal.Lyrics("mom's spaghetti"), nil).Once()
+
+	_, err = syncService.SyncArtist("eminem")
+	assert.NoError(t, err)
+	result, _ = repository.SearchLyrics("spaghetti", 0)
+	if assert.Equal(t, 1, result.Total) {
+		// the title is known after the listing has been saved
+		assert.Equal(t, "Lose Yourself", result.Hits[0].Song.Title)
+		assert.Equal(t, storedArtist.Name, result.Hits[0].Song.Artist)
+	}
+
+	// song which disappears from genius disappears from the search too, and comes back with the listing
+	geniusProvider.On("GetSongInfosByArtistID", storedArtist.ID).Return([]internal.GeniusSongInfo{}, nil).Once()
+	_, err = syncService.SyncArtist("eminem")
+	assert.NoError(t, err)
+	result, _ = repository.SearchLyrics("spaghetti", 0)
+	assert.Equal(t, 0, result.Total)
+
+	assert.NoError(t, repository.SaveArtistSongInfos(storedArtist.ID, []internal.GeniusSongInfo{song}))
+	result, _ = repository.SearchLyrics("spaghetti", 0)
+	assert.Equal(t, 1, result.Total)
+}
+
+func TestIndexedLyricsRepositoryIndexesOnFirstSearch(t *testing.T) {
+	sqlRepository := getSQLiteRepository(t)
+	repository := internal.NewIndexedLyricsRepository(sqlRepository, log.NewEntry(log.New()))
+	// lyrics saved before the first search are read from the repository then
+	assert.NoError(t, repository.SaveLyrics(1, "mom's spaghetti"))
+	assert.NoError(t, sqlRepository.SaveLyrics(2, "spaghetti again"))
+
+	result, err := repository.SearchLyrics("spaghetti", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+
+	// the index isn't loaded again, only changes saved through the repository get there
+	assert.NoError(t, sqlRepository.SaveLyrics(3, "more spaghetti"))
+	assert.NoError(t, repository.SaveLyrics(4, "the last spaghetti"))
+	result, err = repository.SearchLyrics("spaghetti", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Total)
+}
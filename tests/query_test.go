@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func queriedSongs() []internal.Song {
+	stan := song("Stan", "[Verse 1: Eminem]\nDear Slim, I wrote you but you still ain't callin'\n\n[Chorus: Dido]\nMy tea's gone cold, I'm wondering why")
+	stan.Info.AuthorName = "Eminem"
+	loseYourself := song("Lose Yourself", "[Verse 1]\nHis palms are sweaty, knees weak, arms are heavy\nThere's vomit on his sweater already, mom's spaghetti\n\n[Chorus]\nYou better lose yourself in the music")
+	loseYourself.Info.AuthorName = "Eminem"
+	return []internal.Song{stan, loseYourself, song("Money", "money money money, damn")}
+}
+
+func queriedTitles(t *testing.T, text string, dictionaryService internal.DictionaryService) []string {
+	query, err := internal.ParseQuery(text, dictionaryService)
+	if assert.NoError(t, err, text) == false {
+		return nil
+	}
+	var titles []string
+	for _, song := range internal.FilterSongsByQuery(queriedSongs(), query) {
+		titles = append(titles, song.Info.Title)
+	}
+	return titles
+}
+
+func TestParseQueryPrecedence(t *testing.T) {
+	for text, expected := range map[string]string{
+		"a b OR c":                                  `((a AND b) OR c)`,
+		"a OR b AND NOT c":                          `(a OR (b AND NOT c))`,
+		"NOT (a OR b) \"Mom's Spaghetti\"":          `(NOT (a OR b) AND "mom's spaghetti")`,
+		"a NEAR/3 b OR hip-hop":                     `((a NEAR/3 b) OR "hip hop")`,
+		`section:chorus(lose*) performer:"Dr. Dre"`: `(section:"chorus"(lose*) AND performer:"Dr. Dre")`,
+		"section:chorus (lose)":                     `(section:"chorus" AND lose)`,
+		"NEARBY NEARLY":                             `(nearby AND nearly)`,
+	} {
+		query, err := internal.ParseQuery(text, nil)
+		if assert.NoError(t, err, text) {
+			assert.Equal(t, expected, query.String(), text)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for text, expected := range map[string]string{
+		"(money OR cash":       `column 15: expected ) to close ( from column 1, found end of query`,
+		"money)":               `column 6: ")" doesn't close anything, remove it or add ( before`,
+		"money OR":             `column 9: query ends after "OR", expected a word, phrase, field or @dictionary`,
+		"AND money":            `column 1: unexpected "AND", expected a word, phrase, field, @dictionary or (`,
+		`"mom's spaghetti`:     `column 1: phrase isn't closed, add " at the end of it`,
+		"money NEAR cash":      `column 7: "NEAR" needs the distance in words, ex. NEAR/5`,
+		"(a OR b) NEAR/2 cash": `column 10: "NEAR/2" works only between two words or phrases`,
+		"verse:1 money":        `column 1: unknown field "verse", use one of: section, performer`,
+		"money @en/profanity":  `column 7: dictionaries aren't available here, remove "@en/profanity"`,
+		"()":                   `column 2: () is empty, put words inside`,
+	} {
+		_, err := internal.ParseQuery(text, nil)
+		assert.True(t, errors.Is(err, internal.InvalidQueryError), text)
+		if assert.Error(t, err, text) {
+			assert.Equal(t, expected, err.Error(), text)
+		}
+	}
+
+	_, err := internal.ParseQuery("money OR", nil)
+	var queryError *internal.QueryError
+	if assert.True(t, errors.As(err, &queryError)) {
+		assert.Equal(t, "money OR\n        ^", queryError.Pointer())
+	}
+
+	_, err = internal.ParseQuery("  ", nil)
+	assert.True(t, errors.Is(err, internal.EmptyQueryError))
+}
+
+func TestQueryMatchesSongs(t *testing.T) {
+	assert.Equal(t, []string{"Stan", "Lose Yourself"}, queriedTitles(t, "NOT money", nil))
+	assert.Equal(t, []string{"Lose Yourself", "Money"}, queriedTitles(t, `"MOM'S spaghetti" OR mon*`, nil))
+	// section headers aren't lyrics
+	assert.Empty(t, queriedTitles(t, "chorus", nil))
+
+	assert.Equal(t, []string{"Lose Yourself"}, queriedTitles(t, "palms NEAR/5 weak", nil))
+	assert.Empty(t, queriedTitles(t, "palms NEAR/2 arms", nil))
+	assert.Equal(t, []string{"Lose Yourself"}, queriedTitles(t, "spaghetti NEAR/0 \"mom's\"", nil))
+
+	assert.Equal(t, []string{"Stan", "Lose Yourself"}, queriedTitles(t, "section:chorus", nil))
+	assert.Equal(t, []string{"Lose Yourself"}, queriedTitles(t, "section:chorus(music)", nil))
+	assert.Empty(t, queriedTitles(t, "section:chorus(sweaty)", nil))
+	// stanza without performers in the header is performed by the author of song
+	assert.Equal(t, []string{"Stan", "Lose Yourself"}, queriedTitles(t, "performer:eminem", nil))
+	assert.Equal(t, []string{"Stan"}, queriedTitles(t, `performer:"Dido"(cold) NOT performer:"Dido"(slim)`, nil))
+}
+
+func TestQueryWithDictionaries(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(), dictionary("en/profanity", "damn"), dictionary("food", "spaghetti"))
+
+	assert.Equal(t, []string{"Stan", "Lose Yourself"}, queriedTitles(t, "NOT @profanity", service))
+	assert.Equal(t, []string{"Lose Yourself"}, queriedTitles(t, "@food:1", service))
+
+	query, err := internal.ParseQuery("NOT @en/profanity OR @food", service)
+	assert.NoError(t, err)
+	versions := query.DictionaryVersions()
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, "en/profanity", versions[0].Name)
+		assert.Equal(t, 1, versions[0].Version)
+	}
+
+	_, err = internal.ParseQuery("@drugs", service)
+	assert.True(t, errors.Is(err, internal.DictionaryNotFoundError))
+	_, err = internal.ParseQuery("@food:7", service)
+	assert.True(t, errors.Is(err, internal.DictionaryVersionNotFoundError))
+}
+
+func TestBannedWordsAreQuery(t *testing.T) {
+	keywords := internal.NewSongDictionary(dictionary("keywords", "Money", "sweater"))
+	where, err := internal.ParseQuery("section:chorus", nil)
+	assert.NoError(t, err)
+
+	filter := internal.AllOf(internal.WithoutDictionaries(keywords), nil, where)
+	assert.Equal(t, `(NOT @keywords AND section:"chorus")`, filter.String())
+	assert.Empty(t, filter.DictionaryVersions())
+	songs := internal.FilterSongsByQuery(queriedSongs(), filter)
+	if assert.Len(t, songs, 1) {
+		assert.Equal(t, "Stan", songs[0].Info.Title)
+	}
+
+	assert.Nil(t, internal.AllOf(internal.WithoutDictionaries()))
+	assert.Len(t, internal.FilterSongsByQuery(queriedSongs(), nil), 3)
+}
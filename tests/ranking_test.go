@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func song(title string, lyrics string) internal.Song {
+	return internal.Song{Info: internal.SongInfo{Title: title}, Lyrics: internal.Lyrics(lyrics)}
+}
+
+func TestRankSongsByWords(t *testing.T) {
+	songs := []internal.Song{
+		song("first", "money money cash and some other words here"),
+		song("second", "nothing interesting"),
+		song("third", "money cash cash cash"),
+	}
+
+	ranking := internal.RankSongsByWords(songs, internal.NormaliseWords([]string{"Money", " cash"}))
+
+	assert.Equal(t, 3, ranking.SongsCount)
+	assert.Equal(t, 2, len(ranking.Songs))
+	assert.Equal(t, "third", ranking.Songs[0].Song.Info.Title)
+	assert.Equal(t, 4, ranking.Songs[0].Count)
+	assert.Equal(t, float64(1000), ranking.Songs[0].PerThousand)
+	assert.Equal(t, "first", ranking.Songs[1].Song.Info.Title)
+	assert.Equal(t, 3, ranking.Songs[1].Count)
+
+	assert.Equal(t, 3, ranking.Totals["money"])
+	assert.Equal(t, 4, ranking.Totals["cash"])
+	assert.Equal(t, 7, ranking.TotalCount)
+	assert.Equal(t, 14, ranking.TotalWords)
+}
+
+func TestRankSongsByWordsNotUsed(t *testing.T) {
+	ranking := internal.RankSongsByWords([]internal.Song{song("first", "aaa bbb")}, []internal.Word{"ccc"})
+
+	assert.Equal(t, 0, len(ranking.Songs))
+	assert.Equal(t, 0, ranking.Totals["ccc"])
+	assert.Equal(t, float64(0), ranking.PerThousand)
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnalyseRepetition(t *testing.T) {
+	repetition := internal.AnalyseRepetition("[Verse 1]\nFirst line here\nSecond line here\n\n[Chorus]\nMoney money\nMoney, money!\n\n[Verse 2]\nThird line here\n\n[Chorus]\nMoney money\nMoney money")
+
+	assert.Equal(t, 7, repetition.Lines)
+	assert.Equal(t, 4, repetition.DistinctLines)
+	assert.Equal(t, 3, repetition.DuplicateLines)
+	assert.Equal(t, 0.429, repetition.DuplicateShare)
+	assert.Equal(t, []internal.RepeatedLine{{Line: "Money money", Count: 4}}, repetition.MostRepeated)
+	assert.Equal(t, 8, repetition.ChorusWords)
+	assert.Equal(t, 0.471, repetition.ChorusShare)
+	assert.True(t, repetition.Repetitiveness > 0)
+}
+
+func TestChorusWithoutHeaders(t *testing.T) {
+	repetition := internal.AnalyseRepetition("verse one\n\nchorus line\n\nverse two\n\nchorus line")
+
+	assert.Equal(t, 4, repetition.ChorusWords)
+	assert.Equal(t, 0.5, repetition.ChorusShare)
+}
+
+func TestRepetitivenessOfRepeatedLyrics(t *testing.T) {
+	repeated := ""
+	for i := 0; i < 20; i++ {
+		repeated += "we will rock you\n"
+	}
+	varied := internal.AnalyseRepetition("look if you had one shot or one opportunity\nto seize everything you ever wanted in one moment\nwould you capture it or just let it slip")
+
+	assert.True(t, internal.AnalyseRepetition(internal.Lyrics(repeated)).Repetitiveness > varied.Repetitiveness)
+	assert.Equal(t, internal.Repetition{MostRepeated: []internal.RepeatedLine{}}, internal.AnalyseRepetition(""))
+}
+
+func TestDistinctLines(t *testing.T) {
+	lyrics := internal.Lyrics("[Chorus]\nMoney money\nCash\n\n[Verse]\nSomething else\n\n[Chorus]\nMoney, money\nCash")
+
+	assert.Equal(t, internal.Lyrics("Money money\nCash\n\nSomething else"), lyrics.DistinctLines())
+	assert.Equal(t, 2, lyrics.DistinctLines().FindWords()["money"])
+
+	songs := internal.WithDistinctLines([]internal.Song{song("first", string(lyrics))})
+	assert.Equal(t, lyrics.DistinctLines(), songs[0].Lyrics)
+	assert.Equal(t, 4, lyrics.FindWords()["money"])
+}
+
+func TestAverageRepetition(t *testing.T) {
+	average := internal.AverageRepetition([]internal.Repetition{
+		{Lines: 4, DistinctLines: 2, DuplicateLines: 2, DuplicateShare: 0.5, ChorusShare: 0.4, CompressionRatio: 0.6},
+		{Lines: 2, DistinctLines: 2, DuplicateShare: 0, ChorusShare: 0, CompressionRatio: 1},
+	})
+
+	assert.Equal(t, 6, average.Lines)
+	assert.Equal(t, 2, average.DuplicateLines)
+	assert.Equal(t, 0.25, average.DuplicateShare)
+	assert.Equal(t, 0.2, average.ChorusShare)
+	assert.Equal(t, 0.8, average.CompressionRatio)
+	assert.Equal(t, 0.2, average.Repetitiveness)
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLyricsStanzas(t *testing.T) {
+	lyrics := internal.Lyrics("[Verse 1: Eminem & Rihanna]\nFirst line\nSecond line\n\nThird line\n[Chorus]\nFourth line")
+
+	assert.Equal(t, []internal.Stanza{
+		{Section: "Verse 1", Performers: []string{"Eminem", "Rihanna"}, Lines: []string{"First line", "Second line"}},
+		{Section: "Verse 1", Performers: []string{"Eminem", "Rihanna"}, Lines: []string{"Third line"}},
+		{Section: "Chorus", Lines: []string{"Fourth line"}},
+	}, lyrics.Stanzas())
+}
+
+func TestSyllables(t *testing.T) {
+	assert.Equal(t, 2, internal.Syllables("money", "en"))
+	assert.Equal(t, 1, internal.Syllables("time", "en"))
+	assert.Equal(t, 3, internal.Syllables("spaghetti", "en"))
+	assert.Equal(t, 1, internal.Syllables("nie", "pl"))
+	assert.Equal(t, 3, internal.Syllables("wszystkiego", "pl"))
+	assert.Equal(t, 1, internal.Syllables("chrząszcz", "pl"))
+}
+
+func TestRhymeSchemes(t *testing.T) {
+	analysis := internal.AnalyseRhymes("I got money in the bank\nYou can call me late at night\nLook at honey in the tank\nEverything will be alright", "en")
+
+	assert.Equal(t, "en", analysis.Language)
+	assert.Equal(t, 1, len(analysis.Stanzas))
+	assert.Equal(t, "ABAB", analysis.Stanzas[0].Scheme)
+	assert.Equal(t, 2, analysis.EndRhymes)
+	assert.True(t, analysis.Density > 0)
+}
+
+func TestMultisyllabicAndInternalRhymes(t *testing.T) {
+	analysis := internal.AnalyseRhymes("Siedzę w domu, nie mówię nikomu\nNic nie mówię, tylko siedzę w domu\nMam w głowie plan\nJestem tu sam", "pl")
+
+	assert.Equal(t, "pl", analysis.Language)
+	assert.Equal(t, "AABB", analysis.Stanzas[0].Scheme)
+	// "domu" with "nikomu" and "mam" with "plan"
+	assert.Equal(t, 2, analysis.InternalRhymes)
+	assert.Equal(t, 1, analysis.MultisyllabicRhymes)
+	assert.Equal(t, "nikomu", analysis.LongestRhymes[0].Lines[0])
+}
+
+func TestRepeatedWordIsNotRhyme(t *testing.T) {
+	analysis := internal.AnalyseRhymes("Money money\nMoney money", "en")
+
+	assert.Equal(t, "AB", analysis.Stanzas[0].Scheme)
+	assert.Equal(t, 0, analysis.EndRhymes)
+	assert.Equal(t, float64(0), analysis.Density)
+}
+
+func TestRhymeSummary(t *testing.T) {
+	analysis := internal.AnalyseArtist([]internal.Song{
+		song("first", "Mam w głowie plan\nJestem tu sam"),
+		song("second", "Money money\nMoney money"),
+	}, nil)
+
+	assert.Equal(t, 2, analysis.Rhymes.Songs)
+	assert.Equal(t, 1, analysis.Rhymes.EndRhymes)
+	assert.Equal(t, []internal.SchemeCount{{Scheme: "AA", Count: 1}, {Scheme: "AB", Count: 1}}, analysis.Rhymes.CommonSchemes)
+	assert.True(t, analysis.Rhymes.AverageDensity > analysis.Songs[1].Rhymes.Density)
+}
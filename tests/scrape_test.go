@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sort"
+	"testing"
+)
+
+func TestScrapeSongs(t *testing.T) {
+	lyricsService := mocks.LyricsService{}
+	lyricsService.On("GetSongFromInfo", jobSongInfos[0]).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil)
+	lyricsService.On("GetSongFromInfo", jobSongInfos[1]).Return(internal.Song{}, errors.New("timeout"))
+	lyricsService.On("GetSongFromInfo", jobSongInfos[2]).Return(jobSong(jobSongInfos[2], "guess who's back"), nil)
+
+	progress := internal.ScrapeProgress{SongsListed: len(jobSongInfos)}
+	var titles []string
+	internal.ScrapeSongs(context.Background(), &lyricsService, jobSongInfos, 2, func(index int, song internal.Song, err error) {
+		progress.Add(jobSongInfos[index], err)
+		if err == nil {
+			titles = append(titles, song.Info.Title)
+		}
+	})
+
+	sort.Strings(titles)
+	assert.Equal(t, []string{"Stan", "Without Me"}, titles)
+	assert.Equal(t, internal.ScrapeProgress{
+		SongsListed:  3,
+		PagesFetched: 3,
+		SongsScraped: 2,
+		Failures:     1,
+		Errors:       []string{"Lose Yourself: timeout"},
+	}, progress)
+}
+
+func TestScrapeSongsStopsWhenCancelled(t *testing.T) {
+	lyricsService := mocks.LyricsService{}
+	ctx, cancel := context.WithCancel(context.Background())
+	lyricsService.On("GetSongFromInfo", mock.Anything).Return(jobSong(jobSongInfos[0], "my tea's gone cold"), nil)
+
+	calls := 0
+	internal.ScrapeSongs(ctx, &lyricsService, jobSongInfos, 1, func(index int, song internal.Song, err error) {
+		calls++
+		cancel()
+	})
+	assert.Equal(t, 1, calls, "the song which was being downloaded is finished, the rest isn't started")
+}
+
+func TestScrapeProgressKeepsFirstErrors(t *testing.T) {
+	progress := internal.ScrapeProgress{}
+	for i := 0; i < 15; i++ {
+		progress.Add(internal.SongInfo{Title: fmt.Sprintf("song %d", i)}, errors.New("timeout"))
+	}
+	assert.Equal(t, 15, progress.Failures)
+	assert.Len(t, progress.Errors, 10)
+	assert.Equal(t, "song 0: timeout", progress.Errors[0])
+}
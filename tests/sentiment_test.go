@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSentimentScore(t *testing.T) {
+	lexicon := internal.SentimentLexiconFor("en")
+
+	happy := lexicon.Score([]string{"i", "love", "my", "family"})
+	assert.Equal(t, 4, happy.Words)
+	assert.Equal(t, 2, happy.Matched)
+	assert.Equal(t, 2, happy.Positive)
+	assert.Equal(t, 2.5, happy.Valence)
+	assert.Equal(t, 1.25, happy.Comparative)
+	assert.Equal(t, 2, happy.Emotions["trust"])
+	assert.Equal(t, "trust", happy.DominantEmotion)
+	assert.Equal(t, internal.MoodUpbeat, happy.Mood)
+
+	// stems and negations
+	negated := lexicon.Score([]string{"i'm", "not", "really", "lovin'", "it"})
+	assert.Equal(t, 1, negated.Negative)
+	assert.Equal(t, float64(-3), negated.Valence)
+	assert.Equal(t, 0, negated.Emotions["joy"])
+	assert.Equal(t, internal.MoodDark, negated.Mood)
+
+	neutral := lexicon.Score([]string{"table"})
+	assert.Equal(t, internal.MoodNeutral, neutral.Mood)
+	assert.Equal(t, "", neutral.DominantEmotion)
+}
+
+func TestSentimentPolish(t *testing.T) {
+	sentiment := internal.SentimentLexiconFor("pl").Score([]string{"samotność", "i", "łzy"})
+
+	assert.Equal(t, 2, sentiment.Negative)
+	assert.Equal(t, "sadness", sentiment.DominantEmotion)
+}
+
+func TestAnalyseSentiment(t *testing.T) {
+	analysis := internal.AnalyseSentiment("[Verse 1]\nI love you\nI hate you\n\n[Chorus]\nParty party", "en")
+
+	assert.Equal(t, 2, len(analysis.Stanzas))
+	assert.Equal(t, 2, len(analysis.Stanzas[0].Lines))
+	assert.Equal(t, internal.MoodUpbeat, analysis.Stanzas[0].Lines[0].Sentiment.Mood)
+	assert.Equal(t, internal.MoodDark, analysis.Stanzas[0].Lines[1].Sentiment.Mood)
+	assert.Equal(t, float64(0), analysis.Stanzas[0].Sentiment.Valence)
+	assert.Equal(t, 8, analysis.Total.Words)
+	assert.Equal(t, 4, analysis.Total.Matched)
+	assert.Equal(t, float64(1), analysis.Total.Valence)
+}
+
+func TestReadSentimentLexiconErrors(t *testing.T) {
+	_, err := internal.ReadSentimentLexicon("en", strings.NewReader("love 3 joy\nhate -9"))
+	assert.True(t, errors.Is(err, internal.InvalidLexiconEntryError))
+
+	_, err = internal.ReadSentimentLexicon("en", strings.NewReader("love 3 euphoria"))
+	assert.True(t, errors.Is(err, internal.UnknownEmotionError))
+
+	assert.True(t, errors.Is(internal.ValidateMood("sleepy"), internal.UnknownMoodError))
+	assert.NoError(t, internal.ValidateMood(""))
+}
+
+func TestThemes(t *testing.T) {
+	service := getDictionaryService(t, internal.NewMemoryDictionaryHistory(),
+		dictionary("en/themes/money", "cash", "money"),
+		dictionary("pl/themes/money", "hajs"),
+		dictionary("themes/love", "love"),
+		dictionary("en/profanity", "damn"),
+	)
+	assert.Equal(t, []string{"love", "money"}, internal.ThemesNames(service))
+
+	themes, err := internal.GetThemes(service, nil)
+	assert.NoError(t, err)
+
+	english := song("english", "cash cash money love and some other words")
+	english.Languages = []internal.LanguageScore{{Language: "en", Confidence: 1}}
+	polish := song("polish", "hajs się zgadza ziomek")
+	polish.Languages = []internal.LanguageScore{{Language: "pl", Confidence: 1}}
+
+	scores := internal.SongsThemes([]internal.Song{english, polish}, themes)
+	assert.Equal(t, []internal.ThemeScore{
+		{Theme: "money", Matches: 4, Share: 333.33, Proportion: 0.8},
+		{Theme: "love", Matches: 1, Share: 83.33, Proportion: 0.2},
+	}, scores)
+
+	_, err = internal.GetThemes(service, []string{"drugs"})
+	assert.True(t, errors.Is(err, internal.DictionaryNotFoundError))
+}
+
+func TestBundledThemes(t *testing.T) {
+	registry, err := internal.LoadDictionaryRegistry("../dictionaries")
+	assert.NoError(t, err)
+	for _, name := range []string{"money", "violence", "love", "drugs"} {
+		for _, language := range []string{"en", "pl"} {
+			theme, err := registry.Get(language + "/themes/" + name)
+			assert.NoError(t, err)
+			assert.False(t, theme.IsEmpty())
+		}
+	}
+}
+
+func TestArtistMoods(t *testing.T) {
+	analysis := internal.AnalyseArtist([]internal.Song{
+		song("happy", "I love this party"),
+		song("sad", "I am lonely and lost"),
+		song("plain", "The table is brown"),
+	}, nil)
+
+	assert.Equal(t, map[string]int{internal.MoodUpbeat: 1, internal.MoodNeutral: 1, internal.MoodDark: 1}, analysis.Moods)
+	assert.Equal(t, "happy", internal.FilterByMood(analysis.Songs, internal.MoodUpbeat)[0].Song.Info.Title)
+	assert.Equal(t, 3, len(internal.FilterByMood(analysis.Songs, "")))
+	assert.Equal(t, []internal.ThemeScore{}, analysis.Themes)
+}
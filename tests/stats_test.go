@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLyricsLinesAndTokens(t *testing.T) {
+	lyrics := internal.Lyrics("[Verse 1: Eminem]\nI don't care, I'm here\nSecond line[Chorus]\n\nThird lineFourth line")
+
+	assert.Equal(t, []string{"I don't care, I'm here", "Second line", "Third line", "Fourth line"}, lyrics.Lines())
+	assert.Equal(t, []string{"i", "don't", "care", "i'm", "here", "second", "line", "third", "line", "fourth", "line"}, lyrics.Tokens())
+}
+
+func TestLexicalStats(t *testing.T) {
+	stats := internal.NewLexicalStats([]string{"a", "b", "a", "cc"}, 2)
+
+	assert.Equal(t, 4, stats.Words)
+	assert.Equal(t, 3, stats.UniqueWords)
+	assert.Equal(t, 0.75, stats.TypeTokenRatio)
+	assert.Equal(t, 0.75, stats.MATTR)
+	assert.Equal(t, 1.25, stats.AverageWordLength)
+	assert.Equal(t, float64(2), stats.WordsPerLine)
+	assert.Equal(t, 2, stats.HapaxLegomena)
+
+	empty := internal.NewLexicalStats(nil, 0)
+	assert.Equal(t, 0, empty.Words)
+	assert.Equal(t, float64(0), empty.MTLD)
+}
+
+func TestMTLDPrefersVariedVocabulary(t *testing.T) {
+	var repetitive, varied []string
+	for i := 0; i < 100; i++ {
+		repetitive = append(repetitive, []string{"yeah", "money", "yeah"}[i%3])
+		varied = append(varied, string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+
+	assert.True(t, internal.NewLexicalStats(varied, 1).MTLD > internal.NewLexicalStats(repetitive, 1).MTLD)
+	assert.Equal(t, float64(100), internal.NewLexicalStats(varied, 1).MTLD)
+	assert.Equal(t, float64(1), internal.NewLexicalStats(varied, 1).MATTR)
+}
+
+func TestArtistStats(t *testing.T) {
+	stats := internal.NewArtistStats([]internal.Song{
+		song("first", "one two\nthree"),
+		song("second", "one one"),
+	})
+
+	assert.Equal(t, 2, len(stats.Songs))
+	assert.Equal(t, 5, stats.Total.Words)
+	assert.Equal(t, 3, stats.Total.UniqueWords)
+	assert.Equal(t, 3, stats.Total.Lines)
+	assert.Equal(t, 0.75, stats.Average.TypeTokenRatio)
+}
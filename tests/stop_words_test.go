@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStopWordsRegistryEmbeddedLanguages(t *testing.T) {
+	registry, err := internal.LoadStopWordsRegistry("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en", "pl"}, registry.Languages())
+
+	pl, err := registry.Get("pl")
+	assert.NoError(t, err)
+	assert.True(t, pl.Contains("się"))
+	assert.False(t, pl.Contains("the"))
+
+	_, err = registry.Get("de")
+	assert.True(t, errors.Is(err, internal.UnknownLanguageError))
+}
+
+func TestStopWordsRegistryCustomDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "en.txt"), []byte("# slang\nyeah yo\n"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "de.txt"), []byte("und der die das"), os.ModePerm))
+
+	registry, err := internal.LoadStopWordsRegistry(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"de", "en", "pl"}, registry.Languages())
+
+	en, err := registry.Get("en")
+	assert.NoError(t, err)
+	assert.True(t, en.Contains("yo"))
+	assert.True(t, en.Contains("the"))
+
+	_, err = internal.LoadStopWordsRegistry(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+}
+
+func TestStopWordsResolveAuto(t *testing.T) {
+	registry, err := internal.LoadStopWordsRegistry("")
+	assert.NoError(t, err)
+
+	words := internal.Lyrics("Nie wiem jak ale się uda, hajs się zgadza").FindWords()
+	stopWords, language, err := registry.Resolve(internal.StopWordsAuto, nil, words)
+	assert.NoError(t, err)
+	assert.Equal(t, "pl", language)
+	assert.Equal(t, internal.WordsOccurrences{"wiem": 1, "uda": 1, "hajs": 1, "zgadza": 1}, words.WithoutStopWords(stopWords))
+
+	_, language, err = registry.Resolve(internal.StopWordsAuto, nil, internal.WordsOccurrences{"hajs": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, internal.StopWordsNone, language)
+
+	stopWords, language, err = registry.Resolve(internal.StopWordsAuto, []string{"pl", "en", "xx"}, words)
+	assert.NoError(t, err)
+	assert.Equal(t, "pl,en", language)
+	assert.True(t, stopWords.Contains("the"))
+
+	_, _, err = registry.Resolve("xx", nil, words)
+	assert.True(t, errors.Is(err, internal.UnknownLanguageError))
+}
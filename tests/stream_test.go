@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/buaazp/fasthttprouter"
+	"github.com/marosiak/WordFinder/api"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveInMemory runs the handler on in-memory listener, the returned client is connected to it
+func serveInMemory(t *testing.T, handler fasthttp.RequestHandler) (*fasthttp.Client, *fasthttputil.InmemoryListener) {
+	listener := fasthttputil.NewInmemoryListener()
+	go fasthttp.Serve(listener, handler)
+	t.Cleanup(func() { listener.Close() })
+	return &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return listener.Dial() }}, listener
+}
+
+func getStreamedGeniusAPI(t *testing.T) (*mocks.LyricsService, *fasthttp.Client) {
+	lyricsService := &mocks.LyricsService{}
+	stopWords, err := internal.LoadStopWordsRegistry("")
+	assert.NoError(t, err)
+	geniusAPI := api.NewGeniusAPI(GetConfig(), lyricsService, getDictionaryService(t, internal.NewMemoryDictionaryHistory()), stopWords, log.NewEntry(log.New()))
+	router := fasthttprouter.New()
+	assert.NoError(t, geniusAPI.Register(router))
+	client, _ := serveInMemory(t, router.Handler)
+	return lyricsService, client
+}
+
+func get(t *testing.T, client *fasthttp.Client, uri string, accept string) (string, string) {
+	request, response := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(request)
+	defer fasthttp.ReleaseResponse(response)
+	request.SetRequestURI("http://api" + uri)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	assert.NoError(t, client.Do(request, response))
+	return string(response.Header.ContentType()), string(response.Body())
+}
+
+type ndjsonEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func readNDJSON(t *testing.T, body string) []ndjsonEvent {
+	var events []ndjsonEvent
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		var event ndjsonEvent
+		assert.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestStreamFormatIsPickedByAccept(t *testing.T) {
+	lyricsService, client := getStreamedGeniusAPI(t)
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos[:1], nil)
+
+	for _, c := range []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json"},
+		{"text/event-stream", api.StreamSSE},
+		{"application/json, Application/X-NDJSON;q=0.9", api.StreamNDJSON},
+		{"text/event-stream;q=0, application/json", "application/json"},
+		{"text/event-stream; q=0.5, application/x-ndjson", api.StreamNDJSON},
+		{"application/x-ndjson;q=0.0", "application/json"},
+	} {
+		contentType, _ := get(t, client, "/artists/eminem/songs/", c.accept)
+		assert.Equal(t, c.contentType, contentType, c.accept)
+	}
+}
+
+func TestStreamSongs(t *testing.T) {
+	lyricsService, client := getStreamedGeniusAPI(t)
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(jobSongInfos[:2], nil)
+
+	_, body := get(t, client, "/artists/eminem/songs/", api.StreamSSE)
+	assert.Equal(t, `event: progress
+data: {"songs_listed":2,"pages_fetched":0,"songs_scraped":0,"failures":0}
+
+event: song
+data: {"title":"Stan","url":"https://example.com/Eminem-stan-lyrics"}
+
+event: song
+data: {"title":"Lose Yourself","url":"https://example.com/Eminem-lose-yourself-lyrics"}
+
+event: summary
+data: {"songs":2,"progress":{"songs_listed":2,"pages_fetched":0,"songs_scraped":0,"failures":0}}
+
+`, body)
+
+	_, body = get(t, client, "/artists/eminem/songs/", api.StreamNDJSON)
+	events := readNDJSON(t, body)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, "song", events[1].Event)
+		assert.JSONEq(t, `{"title":"Stan","url":"https://example.com/Eminem-stan-lyrics"}`, string(events[1].Data))
+		assert.Equal(t, "summary", events[3].Event)
+	}
+}
+
+func TestStreamedSongsAreDedupedLikePlainResponse(t *testing.T) {
+	lyricsService, client := getStreamedGeniusAPI(t)
+	infos := []internal.SongInfo{
+		{GeniusID: 1, Title: "Stan", AuthorName: "Eminem", PageEndpoint: "/stan"},
+		{GeniusID: 2, Title: "Stan (Live)", AuthorName: "Eminem", PageEndpoint: "/stan-live"},
+		{GeniusID: 3, Title: "Without Me", AuthorName: "Eminem", PageEndpoint: "/without-me"},
+	}
+	songs := []internal.Song{
+		jobSong(infos[0], "my tea's gone cold I'm wondering why"),
+		// the live version is the only one with the word, but it's a duplicate of the original
+		jobSong(infos[1], "my tea's gone cold I'm wondering why\nmoney encore"),
+		jobSong(infos[2], "guess who's back money"),
+	}
+	lyricsService.On("GetSongsByArtist", "eminem").Return(songs, nil)
+	lyricsService.On("GetSongsInfosByArtist", "eminem").Return(infos, nil)
+	for i, info := range infos {
+		lyricsService.On("GetSongFromInfo", info).Return(songs[i], nil)
+	}
+
+	for uri, titles := range map[string][]string{
+		"/artists/eminem/songs/words?duplicates=dedupe&q=money": {"Without Me"},
+		"/artists/eminem/songs/words?duplicates=group&q=money":  {"Without Me"},
+		"/artists/eminem/songs/?duplicates=dedupe&q=money":      {"Without Me"},
+		"/artists/eminem/songs/?duplicates=group&q=money":       {"Without Me"},
+		"/artists/eminem/songs/?duplicates=group":               {"Stan", "Without Me"},
+	} {
+		_, body := get(t, client, uri, "")
+		var plain struct {
+			Data struct {
+				Songs []struct {
+					Title string `json:"title"`
+				}
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(body), &plain), uri)
+		var plainTitles []string
+		for _, song := range plain.Data.Songs {
+			plainTitles = append(plainTitles, song.Title)
+		}
+		assert.Equal(t, titles, plainTitles, uri)
+
+		_, body = get(t, client, uri, api.StreamNDJSON)
+		var streamedTitles []string
+		for _, event := range readNDJSON(t, body) {
+			if event.Event != "song" {
+				continue
+			}
+			var song struct {
+				Title string `json:"title"`
+			}
+			assert.NoError(t, json.Unmarshal(event.Data, &song), uri)
+			streamedTitles = append(streamedTitles, song.Title)
+		}
+		assert.Equal(t, plainTitles, streamedTitles, uri)
+	}
+}
+
+func TestWriteStreamStopsWhenClientDisconnects(t *testing.T) {
+	done := make(chan error, 1)
+	_, listener := serveInMemory(t, func(ctx *fasthttp.RequestCtx) {
+		api.WriteStream(ctx, api.StreamNDJSON, func(ctx context.Context, stream *api.StreamWriter) {
+			for stream.Event("tick", 1) {
+			}
+			done <- ctx.Err()
+		})
+	})
+
+	conn, err := listener.Dial()
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: api\r\n\r\n"))
+	assert.NoError(t, err)
+	// the first event is flushed right away, before the handler is done
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", line)
+	assert.NoError(t, conn.Close())
+
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream hasn't been stopped")
+	}
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/marosiak/WordFinder/mocks"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getSyncService(t *testing.T) (*mocks.GeniusProvider, internal.LyricsRepository, *internal.InternalSyncService) {
+	geniusProvider := &mocks.GeniusProvider{}
+	repository := getSQLiteRepository(t)
+	return geniusProvider, repository, internal.NewSyncService(GetConfig(), geniusProvider, repository, log.NewEntry(log.New()))
+}
+
+func TestSyncArtist(t *testing.T) {
+	geniusProvider, repository, syncService := getSyncService(t)
+	stan := storedSongInfo(2, "Stan")
+	unreleased := storedSongInfo(3, "Unreleased")
+	unreleased.LyricsState = "unreleased"
+	removed := storedSongInfo(4, "Removed")
+	assert.NoError(t, repository.SaveArtistSongInfos(storedArtist.ID, []internal.GeniusSongInfo{stan, unreleased, removed}))
+	assert.NoError(t, repository.SaveLyrics(stan.ID, "stored lyrics"))
+
+	completed := unreleased
+	completed.LyricsState = internal.LyricsComplete
+	added := storedSongInfo(5, "New Release")
+	failing := storedSongInfo(6, "Failing")
+	geniusProvider.On("GetArtist", "eminem").Return(storedArtist, nil)
+	geniusProvider.On("GetSongInfosByArtistID", storedArtist.ID).Return([]internal.GeniusSongInfo{stan, completed, added, failing}, nil)
+	geniusProvider.On("GetLyrics", completed).Return(internal.Lyrics("completed lyrics"), nil).Once()
+	geniusProvider.On("GetLyrics", added).Return(internal.Lyrics("new lyrics"), nil).Once()
+	geniusProvider.On("GetLyrics", failing).Return(internal.Lyrics(""), anyError).Once()
+
+	result, err := syncService.SyncArtist("eminem")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 5, Title: "New Release"}}, result.Added)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 3, Title: "Unreleased"}}, result.Updated)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 4, Title: "Removed"}}, result.Removed)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 6, Title: "Failing"}}, result.Failed)
+	assert.Equal(t, 1, result.Unchanged)
+	geniusProvider.AssertExpectations(t)
+
+	stored, err := repository.GetArtistSongInfos(storedArtist.ID)
+	assert.NoError(t, err)
+	// the failed new song isn't listed, so the next sync still reports it as added
+	assert.Equal(t, []internal.GeniusSongInfo{stan, completed, added}, stored.Songs)
+	lyrics, err := repository.GetLyrics(added.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.Lyrics("new lyrics"), lyrics.Lyrics)
+
+	// the next sync downloads only lyrics which have failed
+	geniusProvider.On("GetLyrics", failing).Return(internal.Lyrics("fixed"), nil).Once()
+	result, err = syncService.SyncArtist("eminem")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 6, Title: "Failing"}}, result.Added)
+	assert.Equal(t, []internal.SyncedSong{}, result.Updated)
+	assert.Equal(t, []internal.SyncedSong{}, result.Removed)
+	assert.Equal(t, []internal.SyncedSong{}, result.Failed)
+	assert.Equal(t, 3, result.Unchanged)
+	geniusProvider.AssertExpectations(t)
+
+	stored, err = repository.GetArtistSongInfos(storedArtist.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.GeniusSongInfo{stan, completed, added, failing}, stored.Songs)
+}
+
+func TestSyncRestoresRemovedSong(t *testing.T) {
+	geniusProvider, repository, syncService := getSyncService(t)
+	stan := storedSongInfo(2, "Stan")
+	removed := storedSongInfo(4, "Removed")
+	assert.NoError(t, repository.SaveArtistSongInfos(storedArtist.ID, []internal.GeniusSongInfo{stan, removed}))
+	assert.NoError(t, repository.SaveLyrics(removed.ID, "stored lyrics"))
+	assert.NoError(t, repository.MarkSongsRemoved([]int{removed.ID}))
+
+	geniusProvider.On("GetArtist", "eminem").Return(storedArtist, nil)
+	geniusProvider.On("GetSongInfosByArtistID", storedArtist.ID).Return([]internal.GeniusSongInfo{stan, removed}, nil)
+	geniusProvider.On("GetLyrics", stan).Return(internal.Lyrics("stan lyrics"), nil).Once()
+
+	// lyrics of the restored song are stored already, so only Stan is downloaded
+	result, err := syncService.SyncArtist("eminem")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.SyncedSong{}, result.Added)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 2, Title: "Stan"}}, result.Updated)
+	assert.Equal(t, []internal.SyncedSong{{GeniusID: 4, Title: "Removed"}}, result.Restored)
+	assert.Equal(t, []internal.SyncedSong{}, result.Failed)
+	geniusProvider.AssertExpectations(t)
+
+	stored, err := repository.GetArtistSongInfos(storedArtist.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.GeniusSongInfo{stan, removed}, stored.Songs)
+}
+
+func TestSyncUnknownArtist(t *testing.T) {
+	geniusProvider, _, syncService := getSyncService(t)
+	geniusProvider.On("GetArtist", "nobody").Return(internal.GeniusArtist{}, nil)
+
+	_, err := syncService.SyncArtist("nobody")
+	assert.True(t, errors.Is(err, internal.ArtistNotFoundError))
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCorpusTFIDF(t *testing.T) {
+	corpus := internal.NewCorpus([]internal.WordsOccurrences{
+		{"money": 2, "the": 2},
+		{"love": 1, "the": 3},
+	})
+
+	assert.Equal(t, 2, corpus.Documents())
+	assert.Equal(t, float64(1), corpus.IDF("the"))
+	assert.True(t, corpus.IDF("money") > corpus.IDF("the"))
+
+	scores := corpus.TopWords(internal.WordsOccurrences{"money": 2, "the": 2}, 1)
+	assert.Equal(t, []internal.Word{"money"}, []internal.Word{scores[0].Word})
+	assert.Equal(t, 2, scores[0].Count)
+}
+
+func TestSongsDistinctiveWords(t *testing.T) {
+	songs := []internal.Song{
+		song("first", "money money the the the"),
+		song("second", "love the the the"),
+	}
+
+	distinctive := internal.SongsDistinctiveWords(songs, songs, internal.NewStopWords([]internal.Word{"the"}), 5)
+	assert.Equal(t, 2, len(distinctive))
+	assert.Equal(t, []internal.WordScore{{Word: "money", Count: 2, Score: distinctive[0].Words[0].Score}}, distinctive[0].Words)
+	assert.Equal(t, internal.Word("love"), distinctive[1].Words[0].Word)
+}
+
+func TestArtistsDistinctiveWords(t *testing.T) {
+	scores := internal.ArtistsDistinctiveWords(map[string][]internal.Song{
+		"eminem": {song("a", "money slim the"), song("b", "slim the")},
+		"taco":   {song("c", "hajs the"), song("d", "hajs money the")},
+	}, nil, 1)
+
+	assert.Equal(t, internal.Word("slim"), scores["eminem"][0].Word)
+	assert.Equal(t, internal.Word("hajs"), scores["taco"][0].Word)
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"errors"
+	"github.com/marosiak/WordFinder/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestArtistVocabulary(t *testing.T) {
+	vocabulary := internal.ArtistVocabulary([]internal.Song{
+		song("first", "money money cash and the words"),
+		song("second", "money the cash"),
+	})
+
+	assert.Equal(t, 3, vocabulary["money"])
+	assert.Equal(t, 2, vocabulary["cash"])
+	assert.Equal(t, 2, vocabulary["the"])
+	assert.Equal(t, 9, vocabulary.Total())
+}
+
+func TestQueryVocabulary(t *testing.T) {
+	vocabulary := internal.WordsOccurrences{"money": 5, "cash": 3, "the": 7, "bank": 3, "gold": 1}
+
+	page := internal.QueryVocabulary(vocabulary, internal.VocabularyQuery{
+		Order:     internal.OrderCountDesc,
+		MinCount:  2,
+		StopWords: internal.NewStopWords([]internal.Word{"the"}),
+		Limit:     2,
+		Page:      1,
+	})
+	assert.Equal(t, []internal.WordCount{{Word: "money", Count: 5}, {Word: "bank", Count: 3}}, page.Words)
+	assert.Equal(t, 19, page.TotalWords)
+	assert.Equal(t, 5, page.UniqueWords)
+	assert.Equal(t, 3, page.MatchingWords)
+	assert.Equal(t, 2, page.Pages)
+
+	page = internal.QueryVocabulary(vocabulary, internal.VocabularyQuery{Order: internal.OrderWordAsc, Limit: 2, Page: 3})
+	assert.Equal(t, []internal.WordCount{{Word: "the", Count: 7}}, page.Words)
+
+	page = internal.QueryVocabulary(vocabulary, internal.VocabularyQuery{Order: internal.OrderCountAsc, Page: 4, Limit: 2})
+	assert.Equal(t, 0, len(page.Words))
+}
+
+func TestParseVocabularyOrder(t *testing.T) {
+	order, err := internal.ParseVocabularyOrder("")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.OrderCountDesc, order)
+
+	_, err = internal.ParseVocabularyOrder("random")
+	assert.True(t, errors.Is(err, internal.UnknownVocabularyOrderError))
+}
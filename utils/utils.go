@@ -3,9 +3,11 @@ package utils
 import (
 	"fmt"
 	"github.com/marosiak/WordFinder/config"
+	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -60,14 +62,56 @@ func CreatePathRequest(cfg *config.Config, path string, method string) (http.Req
 	return req, nil
 }
 
+// budgetTransport lets only limited number of requests run at the same time, the slot is released when the body
+// is closed, so all of the goroutines using one client share the same request budget
+type budgetTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+func NewBudgetTransport(base http.RoundTripper, maxConcurrentRequests int) http.RoundTripper {
+	if maxConcurrentRequests <= 0 {
+		return base
+	}
+	return &budgetTransport{base: base, slots: make(chan struct{}, maxConcurrentRequests)}
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	res.Body = &budgetBody{ReadCloser: res.Body, release: func() { <-t.slots }}
+	return res, nil
+}
+
+type budgetBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *budgetBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
 func CreateHttpClient(cfg *config.Config) *http.Client {
 	return &http.Client{
 		Timeout: cfg.RequestTimeout,
-		Transport: &http.Transport{
+		Transport: NewBudgetTransport(&http.Transport{
 			MaxIdleConns:        20,
 			MaxIdleConnsPerHost: 20,
 			MaxConnsPerHost:     20,
 			IdleConnTimeout:     cfg.RequestTimeout,
-		},
+		}, cfg.MaxConcurrentRequests),
 	}
 }